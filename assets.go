@@ -0,0 +1,52 @@
+// Package gobastion holds the application's templates/, static/ and locales/
+// directories.
+//
+// Built with the embed tag (go build -tags embed ./cmd/server) they are
+// compiled into the binary, so it runs from any working directory. Otherwise,
+// and in development, they are read from disk relative to the working
+// directory, so edits show up without rebuilding.
+package gobastion
+
+import (
+	"io/fs"
+	"os"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+// Templates returns the templates/ directory. dev reads it from disk even in
+// embedded builds.
+func Templates(dev bool) fs.FS {
+	return dir("templates", dev)
+}
+
+// Static returns the static/ directory. dev reads it from disk even in
+// embedded builds.
+func Static(dev bool) fs.FS {
+	return dir("static", dev)
+}
+
+// Locales returns the locales/ directory of message catalogs (see package
+// i18n). dev reads it from disk even in embedded builds.
+func Locales(dev bool) fs.FS {
+	return dir("locales", dev)
+}
+
+// NewViews creates the template engine for Templates(dev)
+func NewViews(dev bool) (*view.Engine, error) {
+	if dev || !Embedded {
+		return view.NewEngine("templates")
+	}
+	return view.NewEngineFS(Templates(false)), nil
+}
+
+func dir(name string, dev bool) fs.FS {
+	if dev || !Embedded {
+		return os.DirFS(name)
+	}
+	sub, err := fs.Sub(files, name)
+	if err != nil {
+		panic(err) // name is one of the embedded directories
+	}
+	return sub
+}
@@ -0,0 +1,11 @@
+//go:build !embed
+
+package gobastion
+
+import "embed"
+
+// Embedded reports whether templates/, static/ and locales/ are compiled into
+// the binary
+const Embedded = false
+
+var files embed.FS // Empty: files are read from disk
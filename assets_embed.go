@@ -0,0 +1,12 @@
+//go:build embed
+
+package gobastion
+
+import "embed"
+
+// Embedded reports whether templates/, static/ and locales/ are compiled into
+// the binary
+const Embedded = true
+
+//go:embed templates static locales
+var files embed.FS
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/static"
+)
+
+// runAssetsBuild precompresses static/ and writes its manifest of
+// fingerprinted names. Run it before go build -tags embed so the binary
+// carries the compressed copies.
+func runAssetsBuild() {
+	if _, err := os.Stat("static"); os.IsNotExist(err) {
+		log.Fatal("static/ not found (run this from the project root)")
+	}
+
+	manifest, err := static.Build("static")
+	if err != nil {
+		log.Fatalf("Failed to build static files: %v", err)
+	}
+
+	fmt.Printf("✓ %d files listed in static/%s\n", len(manifest), static.ManifestFile)
+	if _, err := exec.LookPath("brotli"); err != nil {
+		fmt.Println("⚠️  brotli is not installed, only gzip copies were written")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+)
+
+// runConfigPrint prints the configuration for env exactly as the server would
+// load it (files merged, environment overrides applied) with secrets masked.
+// The JSON goes to stdout and where it came from to stderr, so the output can
+// be redirected to a file.
+func runConfigPrint(env string) {
+	cfg, sources, err := config.Inspect("config/config.json", env)
+	var verr *config.ValidationError
+	if err != nil && !errors.As(err, &verr) {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	out, err := json.MarshalIndent(cfg.Masked(), "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode config: %v", err)
+	}
+	fmt.Println(string(out))
+
+	fmt.Fprintf(os.Stderr, "\nEnvironment: %s\n", sources.Environment)
+	fmt.Fprintln(os.Stderr, "Files:")
+	for _, file := range sources.Files {
+		fmt.Fprintln(os.Stderr, "  "+file)
+	}
+
+	// Values set by environment variables rather than files
+	var overrides []string
+	for path, source := range sources.Values {
+		if !slices.Contains(sources.Files, source) {
+			overrides = append(overrides, fmt.Sprintf("%s <- %s", path, source))
+		}
+	}
+	if len(overrides) > 0 {
+		slices.Sort(overrides)
+		fmt.Fprintln(os.Stderr, "Overrides:")
+		for _, o := range overrides {
+			fmt.Fprintln(os.Stderr, "  "+o)
+		}
+	}
+
+	if verr != nil {
+		fmt.Fprintln(os.Stderr, "\n"+verr.Error())
+		os.Exit(1)
+	}
+}
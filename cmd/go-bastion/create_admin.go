@@ -7,11 +7,11 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/AlejandroMBJS/goBastion/internal/app/models"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
 )
 
 type createAdminModel struct {
@@ -121,11 +121,6 @@ func runCreateAdminCmd(email, name string) func() tea.Msg {
 }
 
 func runCreateAdmin(email, password, name string) {
-	// Validate inputs
-	if len(password) < 8 {
-		log.Fatal("Password must be at least 8 characters long")
-	}
-
 	fmt.Println("Creating admin user...")
 
 	// Load config and initialize database
@@ -134,14 +129,14 @@ func runCreateAdmin(email, password, name string) {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	if err := db.Init(cfg.Database); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Validate and hash the password like sign-ups do
+	hashedPassword, err := hashNewPassword(cfg.Security, password, email, name)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		log.Fatalf("Failed to hash password: %v", err)
+	if err := db.Init(cfg.Database); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
 	// Create the user
@@ -152,7 +147,7 @@ func runCreateAdmin(email, password, name string) {
 		Role:     "admin",
 	}
 
-	_, err = db.CreateUser(context.Background(), input, string(hashedPassword))
+	_, err = db.CreateUser(context.Background(), input, hashedPassword)
 	if err != nil {
 		log.Fatalf("Failed to create admin user: %v", err)
 	}
@@ -164,3 +159,17 @@ func runCreateAdmin(email, password, name string) {
 	fmt.Printf("    Email: %s\n", email)
 	fmt.Println("    Role:  admin")
 }
+
+// hashNewPassword checks a password against security.password_policy and
+// hashes it at the configured cost, as the registration handlers do
+func hashNewPassword(cfg config.SecurityConfig, password, email, name string) (string, error) {
+	passwords.Configure(cfg)
+	if err := passwords.Validate(password, email, name); err != nil {
+		return "", fmt.Errorf("invalid password: %w", err)
+	}
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return hash, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -183,12 +184,16 @@ func runHealthChecks() tea.Cmd {
 		checks = append(checks, check5)
 
 		// Check 6: JWT secret configuration
-		check6 := checkResult{name: "JWT secret"}
-		if err == nil && cfg.Security.JWTSecret != "" {
+		check6 := checkResult{name: "Secrets"}
+		if problems := cfg.CheckSecrets(); len(problems) == 0 {
 			check6.status = "PASS"
 		} else {
+			messages := make([]string, len(problems))
+			for i, p := range problems {
+				messages[i] = p.Error()
+			}
 			check6.status = "WARN"
-			check6.err = fmt.Errorf("JWT secret is empty or default")
+			check6.err = fmt.Errorf("%s (production refuses to start)", strings.Join(messages, "; "))
 		}
 		checks = append(checks, check6)
 
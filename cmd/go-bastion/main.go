@@ -64,6 +64,41 @@ func main() {
 	case "doctor":
 		runDoctor()
 
+	case "routes":
+		runRoutes()
+
+	case "assets":
+		// go-bastion assets build
+		if len(os.Args) < 3 || os.Args[2] != "build" {
+			log.Fatal("Uso: go-bastion assets build")
+		}
+		runAssetsBuild()
+
+	case "templates":
+		// go-bastion templates check
+		if len(os.Args) < 3 || os.Args[2] != "check" {
+			log.Fatal("Uso: go-bastion templates check")
+		}
+		runTemplatesCheck()
+
+	case "config":
+		// go-bastion config print [env]
+		if len(os.Args) < 3 || os.Args[2] != "print" {
+			log.Fatal("Uso: go-bastion config print [env]")
+		}
+		env := ""
+		if len(os.Args) >= 4 {
+			env = os.Args[3]
+		}
+		runConfigPrint(env)
+
+	case "secrets":
+		// go-bastion secrets generate [bytes]
+		if len(os.Args) < 3 || os.Args[2] != "generate" {
+			log.Fatal("Uso: go-bastion secrets generate [bytes]")
+		}
+		runSecretsGenerate(os.Args[3:])
+
 	case "test":
 		verbose := len(os.Args) >= 3 && (os.Args[2] == "-v" || os.Args[2] == "--verbose")
 		runTests(verbose)
@@ -118,6 +153,11 @@ func printUsage() {
 	fmt.Println("  go-bastion migrate                    Corre migraciones de base de datos")
 	fmt.Println("  go-bastion seed                       Seed de datos (admin por defecto, etc.)")
 	fmt.Println("  go-bastion doctor                     Health check del sistema")
+	fmt.Println("  go-bastion routes                     Lista las rutas con su protección efectiva")
+	fmt.Println("  go-bastion assets build               Comprime static/ (gzip, brotli) y escribe manifest.json")
+	fmt.Println("  go-bastion templates check            Verifica las plantillas (sintaxis, funciones, bloques, tipos)")
+	fmt.Println("  go-bastion config print [env]         Muestra la configuración combinada (secretos ocultos)")
+	fmt.Println("  go-bastion secrets generate [bytes]   Genera secretos aleatorios fuertes (por defecto: 48 bytes)")
 	fmt.Println("  go-bastion test [-v]                  Ejecuta go test ./...")
 	fmt.Println("  go-bastion create-admin <email> <password> [name]")
 	fmt.Println("                                        Crea un usuario admin")
@@ -196,6 +196,7 @@ func generateRouterFile(moduleName string) string {
 		singular = strings.TrimSuffix(singular, "s")
 	}
 	plural := pluralize(moduleName)
+	pluralTitle := strings.Title(plural)
 
 	return fmt.Sprintf(`package %s
 
@@ -392,31 +393,22 @@ func delete%s(w http.ResponseWriter, r *http.Request, params map[string]string)
 	w.WriteHeader(http.StatusNoContent)
 }
 `, moduleName,
-		plural, singular,
-		plural, plural, plural, singular,
+		pluralTitle, plural, pluralTitle,
+		plural, plural, plural, pluralTitle,
 		singular, plural, plural, singular,
 		singular, plural, plural, singular,
 		singular, plural, plural, singular,
 		singular, plural, plural, singular,
-		singular, plural, singular,
+		pluralTitle, plural, pluralTitle,
 		plural, plural, plural, singular,
-		singular, plural, plural, singular,
-		singular, singular, singular,
-		singular,
-		plural,
-		singular,
-		singular, singular, singular,
-		singular,
-		plural,
-		singular,
-		singular, singular, singular,
+		singular, singular, singular, singular,
+		plural, singular,
+		singular, singular, singular, singular,
+		plural, singular, singular,
+		singular, singular, singular, singular,
 		plural, singular, singular,
 		singular, singular, singular,
-		singular,
-		plural,
-		singular,
-		singular, singular, singular,
-		plural, plural)
+		plural, singular)
 }
 
 func runNewModule(moduleName string) {
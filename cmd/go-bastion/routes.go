@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+)
+
+// runRoutes prints every registered route with its effective protection,
+// taking into account whether JWT and CSRF are enabled in config.json.
+func runRoutes() {
+	cfg, err := config.Load("config/config.json")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Registration only builds the table; handlers (and the template engine
+	// they would render with) are never called here.
+	r := frameworkrouter.New()
+	router.RegisterAll(r, &cfg, nil)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tAUTH\tROLES/PERMISSIONS\tCSRF\tRATE LIMIT\tCORS")
+
+	var warnings []string
+	for _, route := range r.Routes() {
+		auth := authColumn(route.Policy, cfg.Security)
+		access := accessColumn(route.Policy)
+		csrf := csrfColumn(route, cfg.Security)
+		limit := rateLimitColumn(route.Policy, cfg.RateLimit)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", route.Method, route.Pattern, auth, access, csrf, limit, corsColumn(route.Policy))
+
+		if warning := routeWarning(route, cfg); warning != "" {
+			warnings = append(warnings, fmt.Sprintf("%s %s: %s", route.Method, route.Pattern, warning))
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d routes\n", len(r.Routes()))
+	if len(warnings) > 0 {
+		fmt.Println("\nWarnings:")
+		for _, warning := range warnings {
+			fmt.Println("  ⚠️  " + warning)
+		}
+	}
+}
+
+func authColumn(p frameworkrouter.Policy, cfg config.SecurityConfig) string {
+	if p.Access == frameworkrouter.AccessPublic {
+		return "public"
+	}
+	if !cfg.EnableJWT {
+		return "authenticated (jwt disabled)"
+	}
+	return "authenticated"
+}
+
+func accessColumn(p frameworkrouter.Policy) string {
+	var parts []string
+	if len(p.Roles) > 0 {
+		parts = append(parts, "role:"+strings.Join(p.Roles, "|"))
+	}
+	if len(p.Permissions) > 0 {
+		parts = append(parts, "perm:"+strings.Join(p.Permissions, "+"))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+func csrfColumn(route frameworkrouter.Route, cfg config.SecurityConfig) string {
+	switch {
+	case isSafeMethod(route.Method):
+		return "-"
+	case route.Policy.CSRFExempt:
+		return "exempt"
+	case !cfg.EnableCSRF:
+		return "disabled"
+	default:
+		return "required"
+	}
+}
+
+func rateLimitColumn(p frameworkrouter.Policy, cfg config.RateLimitConfig) string {
+	if !cfg.Enabled {
+		return "disabled"
+	}
+	if p.RateLimit == "" {
+		return "default"
+	}
+	return p.RateLimit
+}
+
+func corsColumn(p frameworkrouter.Policy) string {
+	if p.CORS == "" {
+		return "default"
+	}
+	return p.CORS
+}
+
+// routeWarning flags protection gaps worth a second look
+func routeWarning(route frameworkrouter.Route, cfg config.Config) string {
+	p := route.Policy
+	_, knownLimit := cfg.RateLimit.Policies[p.RateLimit]
+	_, knownCORS := cfg.CORS.Policies[p.CORS]
+	switch {
+	case p.Access != frameworkrouter.AccessPublic && !cfg.Security.EnableJWT:
+		return "requires authentication but JWT is disabled, the route is open"
+	case !isSafeMethod(route.Method) && !cfg.Security.EnableCSRF && p.Access != frameworkrouter.AccessPublic:
+		return "state-changing route without CSRF protection (csrf disabled)"
+	case cfg.RateLimit.Enabled && p.RateLimit != "" && p.RateLimit != "default" && !knownLimit:
+		return fmt.Sprintf("rate limit policy %q is not configured, the route is not rate limited", p.RateLimit)
+	case p.CORS != "" && p.CORS != "default" && !knownCORS:
+		return fmt.Sprintf("cors policy %q is not configured, the default policy applies", p.CORS)
+	}
+	return ""
+}
+
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
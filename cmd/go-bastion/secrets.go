@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+)
+
+// runSecretsGenerate prints a strong jwt_secret ready to be exported or
+// mounted as a secret file.
+func runSecretsGenerate(args []string) {
+	n := 48
+	if len(args) > 0 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < config.MinSecretLength {
+			log.Fatalf("El tamaño debe ser un número de al menos %d bytes", config.MinSecretLength)
+		}
+	}
+
+	secret, err := config.GenerateSecret(n)
+	if err != nil {
+		log.Fatalf("Failed to generate secret: %v", err)
+	}
+
+	fmt.Printf("APP_JWT_SECRET=%s\n", secret)
+	fmt.Println()
+	fmt.Println("# Use it in one of these ways:")
+	fmt.Println("#   export APP_JWT_SECRET=...                      (overrides security.jwt_secret)")
+	fmt.Println("#   \"jwt_secret\": \"${APP_JWT_SECRET}\"             (reference in config.json)")
+	fmt.Println("#   APP_JWT_SECRET_FILE=/run/secrets/jwt_secret    (Docker/Kubernetes secret file)")
+}
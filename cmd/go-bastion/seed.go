@@ -7,13 +7,16 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/AlejandroMBJS/goBastion/internal/app/models"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
 )
 
+// seedAdminPassword is the password of the seeded admin. It is for local
+// development only: change it, or delete the user, before deploying.
+const seedAdminPassword = "Bastion-Dev-Seed-1"
+
 type seedModel struct {
 	status   string
 	done     bool
@@ -87,7 +90,7 @@ func (m seedModel) View() string {
 			s += successStyle.Render("✓ Seeding "+m.status) + "\n\n"
 			s += "  " + infoStyle.Render("Default admin user created:") + "\n"
 			s += "    Email:    admin@example.com\n"
-			s += "    Password: AdminPass123\n"
+			s += "    Password: " + seedAdminPassword + " (development only)\n"
 			s += "    Role:     admin\n"
 		} else {
 			s += errorStyle.Render("✗ Seeding "+m.status) + "\n"
@@ -113,7 +116,7 @@ func runSeedCmd() tea.Cmd {
 		}
 
 		// Create default admin user
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("AdminPass123"), bcrypt.DefaultCost)
+		hashedPassword, err := hashNewPassword(cfg.Security, seedAdminPassword, "admin@example.com", "Admin")
 		if err != nil {
 			return seedResultMsg{err: err}
 		}
@@ -121,11 +124,11 @@ func runSeedCmd() tea.Cmd {
 		input := models.RegisterInput{
 			Name:     "Admin",
 			Email:    "admin@example.com",
-			Password: "AdminPass123",
+			Password: seedAdminPassword,
 			Role:     "admin",
 		}
 
-		_, err = db.CreateUser(context.Background(), input, string(hashedPassword))
+		_, err = db.CreateUser(context.Background(), input, hashedPassword)
 		if err != nil {
 			// Check if user already exists
 			return seedResultMsg{err: fmt.Errorf("admin user may already exist or error: %v", err)}
@@ -148,19 +151,19 @@ func runSeed() {
 	}
 
 	// Create default admin user
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("AdminPass123"), bcrypt.DefaultCost)
+	hashedPassword, err := hashNewPassword(cfg.Security, seedAdminPassword, "admin@example.com", "Admin")
 	if err != nil {
-		log.Fatalf("Failed to hash password: %v", err)
+		log.Fatal(err)
 	}
 
 	input := models.RegisterInput{
 		Name:     "Admin",
 		Email:    "admin@example.com",
-		Password: "AdminPass123",
+		Password: seedAdminPassword,
 		Role:     "admin",
 	}
 
-	_, err = db.CreateUser(context.Background(), input, string(hashedPassword))
+	_, err = db.CreateUser(context.Background(), input, hashedPassword)
 	if err != nil {
 		log.Fatalf("Seeding failed (admin user may already exist): %v", err)
 	}
@@ -168,6 +171,6 @@ func runSeed() {
 	fmt.Println("✓ Seeding completed successfully")
 	fmt.Println("  Default admin user created:")
 	fmt.Println("    Email:    admin@example.com")
-	fmt.Println("    Password: AdminPass123")
+	fmt.Println("    Password: " + seedAdminPassword + " (development only, change it before deploying)")
 	fmt.Println("    Role:     admin")
 }
@@ -13,14 +13,21 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	gobastion "github.com/AlejandroMBJS/goBastion"
 	"github.com/AlejandroMBJS/goBastion/internal/app/router"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/admin"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/apikey"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/cors"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
-	"github.com/AlejandroMBJS/goBastion/internal/framework/docs"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/httperr"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/i18n"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/loginguard"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/oidc"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/ratelimit"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
-	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
 )
 
 type configModel struct {
@@ -129,7 +136,9 @@ func (m configModel) View() string {
 		rateLimitStatus = enabledStyle.Render("ENABLED")
 	}
 	s += labelStyle.Render("Status:") + rateLimitStatus + "\n"
-	s += labelStyle.Render("Max Requests:") + valueStyle.Render(fmt.Sprintf("%d per minute", m.cfg.RateLimit.RequestsPerMinute)) + "\n\n"
+	s += labelStyle.Render("Default Limit:") + valueStyle.Render(fmt.Sprintf("%d per minute", m.cfg.RateLimit.RequestsPerMinute)) + "\n"
+	s += labelStyle.Render("Store:") + valueStyle.Render(m.cfg.RateLimit.Store) + "\n"
+	s += labelStyle.Render("Policies:") + valueStyle.Render(fmt.Sprintf("%d", len(m.cfg.RateLimit.Policies))) + "\n\n"
 
 	// Routes
 	s += titleStyle.Render("AVAILABLE ROUTES") + "\n\n"
@@ -159,14 +168,12 @@ func (m configModel) View() string {
 
 func displayConfigAndServe(env string) {
 	// Load configuration
-	cfg, err := config.Load("config/config.json")
+	// config/config.json plus the config.<env>.json overlay and env overrides
+	cfg, err := config.LoadEnv("config/config.json", env)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-
-	if env == "" {
-		env = "development"
-	}
+	env = cfg.App.Environment
 
 	// Create and run the Bubble Tea program to display config
 	p := tea.NewProgram(configModel{cfg: &cfg, env: env})
@@ -182,6 +189,10 @@ func displayConfigAndServe(env string) {
 }
 
 func startServer(cfg *config.Config) {
+	// Rate limits, logging, feature flags, frontend and admin settings are
+	// reloaded when the config files change or on SIGHUP; the rest needs a restart
+	reloader := config.NewReloader("config/config.json", *cfg)
+
 	// Initialize database
 	if err := db.Init(cfg.Database); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -189,29 +200,70 @@ func startServer(cfg *config.Config) {
 	defer db.Close()
 	log.Println("Database initialized successfully")
 
+	// Configure brute-force protection for login endpoints
+	loginguard.Configure(cfg.LoginProtection, cfg.Security.JWTSecret, cfg.App.BaseURL)
+
+	// Configure password policy and hashing cost
+	passwords.Configure(cfg.Security)
+
+	// Configure API keys (personal access tokens)
+	apikey.Configure(cfg.APIKeys)
+
+	// Configure external identity providers (OpenID Connect)
+	oidc.Configure(cfg.OAuth, cfg.App.BaseURL)
+
 	// Initialize template engine
-	tmplEngine, err := view.NewEngine("templates")
+	// Embedded in builds with -tags embed, except in development
+	dev := cfg.App.Environment == "development"
+	tmplEngine, err := gobastion.NewViews(dev)
 	if err != nil {
 		log.Fatalf("Failed to initialize template engine: %v", err)
 	}
+	tmplEngine.SetDevMode(dev) // Recompile templates when they change
+
+	// Message catalogs (locales/) for @t and translated validation errors
+	locales, err := i18n.Load(gobastion.Locales(dev), cfg.App.Locale)
+	if err != nil {
+		log.Fatalf("Failed to load locales: %v", err)
+	}
+	tmplEngine.SetLocale(cfg.App.Locale)
+	tmplEngine.SetTranslator(locales.Localizer(cfg.App.Locale))
+	reloader.Subscribe(func(c *config.Config) { tmplEngine.SetVerbose(c.Logging.Verbose) })
 	log.Println("Template engine initialized successfully")
 
+	// Error responses: problem+json for APIs, error pages and login redirects for browsers
+	httperr.Configure(tmplEngine, "/login")
+
 	// Create router
 	r := frameworkrouter.New()
 
 	// Register global middlewares (order matters!)
+	// Resolve the real client first so logging, CSRF and rate limits see it
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
+	r.Use(middleware.ProxyHeaders(trustedProxies))
 	r.Use(middleware.RequestID())
+	reloader.Subscribe(func(c *config.Config) { middleware.ConfigureLogging(c.Logging) })
 	r.Use(middleware.Logging)
 	r.Use(middleware.Recover)
 	r.Use(middleware.WithTimeout(5 * time.Second))
-	r.Use(middleware.SecurityHeaders())
+	r.Use(middleware.SecurityHeaders(cfg.Security.Headers))
 	r.Use(middleware.MaxBodySize(cfg.Security.MaxBodyBytes))
-	r.Use(middleware.CORSMiddleware(cfg.Server.AllowedOrigins))
 
-	if cfg.RateLimit.Enabled {
-		r.Use(middleware.RateLimit(cfg.RateLimit))
-		log.Println("Rate limiting enabled")
+	corsPolicies, err := cors.FromConfig(*cfg)
+	if err != nil {
+		log.Fatalf("Invalid cors config: %v", err)
 	}
+	r.Use(middleware.CORS(corsPolicies))
+	r.Preflight(middleware.CORSPreflight(corsPolicies))
+
+	// Pick each request's locale: ?lang=, the lang cookie, then Accept-Language
+	r.Use(middleware.Locale(locales, cfg.Security))
+
+	// HTMX requests can get a fragment of the page at the same URL
+	r.Use(middleware.VaryHTMX)
 
 	if cfg.Security.EnableCSRF {
 		r.Use(middleware.CSRFMiddleware(cfg.Security))
@@ -223,27 +275,30 @@ func startServer(cfg *config.Config) {
 		log.Println("JWT authentication enabled")
 	}
 
-	// Register application routes
-	log.Println("Registering application routes...")
-	router.RegisterAuthRoutes(r, cfg.Security)
-	router.RegisterUserRoutes(r)
-
-	// Register HTML authentication routes
-	log.Println("Registering HTML authentication routes...")
-	router.RegisterAuthViewsRoutes(r, cfg.Security, tmplEngine)
+	// After JWT so per-user and per-API-key policies can see the caller
+	if cfg.RateLimit.Enabled {
+		limiter, err := ratelimit.FromConfig(cfg.RateLimit)
+		if err != nil {
+			log.Fatalf("Invalid rate_limit config: %v", err)
+		}
+		reloader.Subscribe(func(c *config.Config) {
+			if err := limiter.Update(c.RateLimit); err != nil {
+				log.Printf("Rate limit policies not reloaded: %v", err)
+			}
+		})
+		r.Use(middleware.RateLimit(limiter))
+		log.Printf("Rate limiting enabled (%s store)", cfg.RateLimit.Store)
+	}
 
-	// Register admin routes
-	log.Println("Registering admin routes...")
-	admin.RegisterRoutes(r, tmplEngine)
+	// Initialize chat broker (advanced example with SSE + HTMX)
+	router.InitChatBroker(context.Background())
 
-	// Register documentation routes
-	log.Println("Registering documentation routes...")
-	docs.RegisterRoutes(r)
+	// Pass full config for admin dashboard metrics
+	reloader.Subscribe(admin.SetFullConfig)
 
-	// Serve static files (CSS)
-	log.Println("Registering static file routes...")
-	staticHandler := http.StripPrefix("/static/", http.FileServer(http.Dir("./static")))
-	r.Handle("GET", "/static/css/output.css", frameworkrouter.WrapHandler(staticHandler))
+	// Register all routes; run `go-bastion routes` to review their protection
+	log.Println("Registering routes...")
+	router.RegisterAll(r, cfg, tmplEngine)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -254,6 +309,9 @@ func startServer(cfg *config.Config) {
 		IdleTimeout:  cfg.Server.GetIdleTimeout(),
 	}
 
+	// Watch config/config.json (and its environment overlay) for changes
+	go reloader.Watch(context.Background(), 2*time.Second)
+
 	// Graceful shutdown
 	go func() {
 		sigint := make(chan os.Signal, 1)
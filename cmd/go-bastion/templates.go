@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+// runTemplatesCheck compiles every template under templates/ and reports
+// syntax errors, unknown functions, unbalanced go::/::end and, for templates
+// whose data type is declared with Declare, fields that type doesn't have.
+func runTemplatesCheck() {
+	cfg, err := config.Load("config/config.json")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	views, err := view.NewEngine("templates")
+	if err != nil {
+		log.Fatalf("Failed to create template engine: %v", err)
+	}
+
+	// Registering the routes collects the data types the handlers declare
+	router.RegisterAll(frameworkrouter.New(), &cfg, views)
+
+	names, err := views.Templates()
+	if err != nil {
+		log.Fatalf("Failed to list templates: %v", err)
+	}
+	problems, err := views.Check()
+	if err != nil {
+		log.Fatalf("Failed to check templates: %v", err)
+	}
+
+	for _, problem := range problems {
+		fmt.Println("  ❌ " + problem.Error())
+	}
+	fmt.Printf("\n%d templates, %d problems\n", len(names), len(problems))
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
@@ -23,7 +23,7 @@
 // INITIALIZATION ORDER (CRITICAL):
 //  1. Load configuration (config.Load)
 //  2. Initialize database (db.InitDB)
-//  3. Initialize template engine (view.NewEngine)
+//  3. Initialize template engine (gobastion.NewViews)
 //  4. Create router (frameworkrouter.NewRouter)
 //  5. Register GLOBAL middleware (rate limiting, CSRF)
 //  6. Register HOME route
@@ -41,8 +41,7 @@
 // 1. Add your custom routes after framework routes:
 //
 //	// Framework routes (DO NOT REMOVE)
-//	router.RegisterAuthRoutes(r, cfg.Security, tmplEngine)
-//	admin.RegisterRoutes(r, tmplEngine, cfg.Security)
+//	router.RegisterAll(r, &cfg, tmplEngine)
 //
 //	// Your custom routes (ADD HERE)
 //	registerMyAppRoutes(r, tmplEngine, cfg)
@@ -50,8 +49,9 @@
 // 2. Add custom global middleware:
 //
 //	// Framework middleware (DO NOT REMOVE)
-//	r.Use(middleware.RateLimit(cfg.RateLimit))
-//	r.Use(middleware.CSRF(cfg.Security))
+//	r.Use(middleware.CSRFMiddleware(cfg.Security))
+//	r.Use(middleware.JWTAuthMiddleware(cfg.Security))
+//	r.Use(middleware.RateLimit(limiter))
 //
 //	// Your custom middleware (ADD HERE)
 //	r.Use(myLoggingMiddleware)
@@ -86,14 +86,21 @@ import (
 	"syscall"
 	"time"
 
+	gobastion "github.com/AlejandroMBJS/goBastion"
 	"github.com/AlejandroMBJS/goBastion/internal/app/router"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/admin"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/apikey"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/cors"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
-	"github.com/AlejandroMBJS/goBastion/internal/framework/docs"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/httperr"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/i18n"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/loginguard"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/oidc"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/ratelimit"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
-	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
 )
 
 func main() {
@@ -110,6 +117,10 @@ func main() {
 	log.Printf("  - JWT Enabled: %v", cfg.Security.EnableJWT)
 	log.Printf("  - Rate Limiting Enabled: %v", cfg.RateLimit.Enabled)
 
+	// Rate limits, logging, feature flags, frontend and admin settings are
+	// reloaded when the config files change or on SIGHUP; the rest needs a restart
+	reloader := config.NewReloader("config/config.json", cfg)
+
 	// Initialize database
 	if err := db.Init(cfg.Database); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -117,33 +128,73 @@ func main() {
 	defer db.Close()
 	log.Println("Database initialized successfully")
 
+	// Configure brute-force protection for login endpoints
+	loginguard.Configure(cfg.LoginProtection, cfg.Security.JWTSecret, cfg.App.BaseURL)
+
+	// Configure password policy and hashing cost
+	passwords.Configure(cfg.Security)
+
+	// Configure API keys (personal access tokens)
+	apikey.Configure(cfg.APIKeys)
+
+	// Configure external identity providers (OpenID Connect)
+	oidc.Configure(cfg.OAuth, cfg.App.BaseURL)
+
 	// Initialize template engine
-	tmplEngine, err := view.NewEngine("templates")
+	// Embedded in builds with -tags embed, except in development
+	dev := cfg.App.Environment == "development"
+	tmplEngine, err := gobastion.NewViews(dev)
 	if err != nil {
 		log.Fatalf("Failed to initialize template engine: %v", err)
 	}
-	tmplEngine.SetVerbose(cfg.Logging.Verbose)
+	tmplEngine.SetDevMode(dev) // Recompile templates when they change
+
+	// Message catalogs (locales/) for @t and translated validation errors
+	locales, err := i18n.Load(gobastion.Locales(dev), cfg.App.Locale)
+	if err != nil {
+		log.Fatalf("Failed to load locales: %v", err)
+	}
+	tmplEngine.SetLocale(cfg.App.Locale)
+	tmplEngine.SetTranslator(locales.Localizer(cfg.App.Locale))
+	reloader.Subscribe(func(c *config.Config) { tmplEngine.SetVerbose(c.Logging.Verbose) })
 	log.Println("Template engine initialized successfully")
 	if cfg.Logging.Verbose {
 		log.Println("  - Verbose template debugging: ENABLED")
 	}
 
+	// Error responses: problem+json for APIs, error pages and login redirects for browsers
+	httperr.Configure(tmplEngine, "/login")
+
 	// Create router
 	r := frameworkrouter.New()
 
 	// Register global middlewares (order matters!)
+	// Resolve the real client first so logging, CSRF and rate limits see it
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
+	r.Use(middleware.ProxyHeaders(trustedProxies))
 	r.Use(middleware.RequestID())
+	reloader.Subscribe(func(c *config.Config) { middleware.ConfigureLogging(c.Logging) })
 	r.Use(middleware.Logging)
 	r.Use(middleware.Recover)
 	r.Use(middleware.WithTimeout(5 * time.Second))
-	r.Use(middleware.SecurityHeaders())
+	r.Use(middleware.SecurityHeaders(cfg.Security.Headers))
 	r.Use(middleware.MaxBodySize(cfg.Security.MaxBodyBytes))
-	r.Use(middleware.CORSMiddleware(cfg.Server.AllowedOrigins))
 
-	if cfg.RateLimit.Enabled {
-		r.Use(middleware.RateLimit(cfg.RateLimit))
-		log.Println("Rate limiting enabled")
+	corsPolicies, err := cors.FromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Invalid cors config: %v", err)
 	}
+	r.Use(middleware.CORS(corsPolicies))
+	r.Preflight(middleware.CORSPreflight(corsPolicies))
+
+	// Pick each request's locale: ?lang=, the lang cookie, then Accept-Language
+	r.Use(middleware.Locale(locales, cfg.Security))
+
+	// HTMX requests can get a fragment of the page at the same URL
+	r.Use(middleware.VaryHTMX)
 
 	if cfg.Security.EnableCSRF {
 		r.Use(middleware.CSRFMiddleware(cfg.Security))
@@ -155,39 +206,33 @@ func main() {
 		log.Println("JWT authentication enabled")
 	}
 
-	// Register home route
-	log.Println("Registering home route...")
-	router.RegisterHomeRoutes(r, tmplEngine)
-
-	// Register application routes
-	log.Println("Registering application routes...")
-	router.RegisterAuthRoutes(r, cfg.Security)
-	router.RegisterUserRoutes(r)
-
-	// Register HTML authentication routes
-	log.Println("Registering HTML authentication routes...")
-	router.RegisterAuthViewsRoutes(r, cfg.Security, tmplEngine)
-
-	// Register admin routes
-	log.Println("Registering admin routes...")
-	admin.SetFullConfig(&cfg) // Pass full config for metrics
-	admin.RegisterRoutes(r, tmplEngine, cfg.Security)
+	// After JWT so per-user and per-API-key policies can see the caller
+	if cfg.RateLimit.Enabled {
+		limiter, err := ratelimit.FromConfig(cfg.RateLimit)
+		if err != nil {
+			log.Fatalf("Invalid rate_limit config: %v", err)
+		}
+		reloader.Subscribe(func(c *config.Config) {
+			if err := limiter.Update(c.RateLimit); err != nil {
+				log.Printf("Rate limit policies not reloaded: %v", err)
+			}
+		})
+		r.Use(middleware.RateLimit(limiter))
+		log.Printf("Rate limiting enabled (%s store)", cfg.RateLimit.Store)
+	}
 
-	// Initialize and register chat routes (advanced example with SSE + HTMX)
+	// Initialize chat broker (advanced example with SSE + HTMX)
 	log.Println("Initializing chat broker...")
 	ctx := context.Background()
 	router.InitChatBroker(ctx)
-	log.Println("Registering chat routes...")
-	router.RegisterChatRoutes(r, tmplEngine)
 
-	// Register documentation routes
-	log.Println("Registering documentation routes...")
-	docs.RegisterRoutes(r)
+	// Pass full config for admin dashboard metrics
+	reloader.Subscribe(admin.SetFullConfig)
 
-	// Serve static files (CSS)
-	log.Println("Registering static file routes...")
-	staticHandler := http.StripPrefix("/static/", http.FileServer(http.Dir("./static")))
-	r.Handle("GET", "/static/css/output.css", frameworkrouter.WrapHandler(staticHandler))
+	// Register all routes (home, API, auth pages, admin, chat, docs, static).
+	// Each route declares its own protection; run `go-bastion routes` to review it.
+	log.Println("Registering routes...")
+	router.RegisterAll(r, &cfg, tmplEngine)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -198,6 +243,9 @@ func main() {
 		IdleTimeout:  cfg.Server.GetIdleTimeout(),
 	}
 
+	// Watch config/config.json (and its environment overlay) for changes
+	go reloader.Watch(ctx, 2*time.Second)
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Server starting on %s", cfg.Server.Port)
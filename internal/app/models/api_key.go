@@ -0,0 +1,9 @@
+package models
+
+// APIKeyInput represents input for creating a personal API key. No scopes
+// means read only; expires_in_days <= 0 uses the configured default.
+type APIKeyInput struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"oneof=read write admin"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
@@ -1,8 +1,8 @@
 package models
 
 import (
-	"errors"
-	"strings"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/form"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
 )
 
 // User represents a user in the system
@@ -16,107 +16,57 @@ type User struct {
 	IsSuperuser bool   `json:"is_superuser"`
 }
 
-// UserInput represents input for creating or updating a user
+// UserInput represents input for creating or updating a user. Inputs are
+// bound and checked with form.Bind, by their validate tags.
 type UserInput struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	Name  string `json:"name" validate:"required,min=2,max=100"`
+	Email string `json:"email" validate:"required,email,max=255"`
+	Role  string `json:"role" validate:"oneof=user admin"`
 }
 
 // RegisterInput represents input for user registration
 type RegisterInput struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+	Role     string `json:"role" validate:"oneof=user admin"`
 }
 
 // LoginInput represents input for user login
 type LoginInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 // RefreshInput represents input for token refresh
 type RefreshInput struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
-// Validate validates UserInput
-func (u UserInput) Validate() error {
-	if len(strings.TrimSpace(u.Name)) < 2 {
-		return errors.New("name must be at least 2 characters long")
-	}
-
-	if len(u.Name) > 100 {
-		return errors.New("name must not exceed 100 characters")
-	}
-
-	if !strings.Contains(u.Email, "@") {
-		return errors.New("email must be a valid email address")
-	}
-
-	if len(u.Email) > 255 {
-		return errors.New("email must not exceed 255 characters")
-	}
-
-	if u.Role != "" && u.Role != "user" && u.Role != "admin" {
-		return errors.New("role must be either 'user' or 'admin'")
-	}
-
-	return nil
+// ChangePasswordInput represents input for changing the current user's
+// password. The new password is checked against the password policy by the
+// handler, which knows the account's name and email.
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
 }
 
-// Validate validates RegisterInput
-func (r RegisterInput) Validate() error {
-	if len(strings.TrimSpace(r.Name)) < 2 {
-		return errors.New("name must be at least 2 characters long")
-	}
-
-	if len(r.Name) > 100 {
-		return errors.New("name must not exceed 100 characters")
-	}
-
-	if !strings.Contains(r.Email, "@") {
-		return errors.New("email must be a valid email address")
-	}
-
-	if len(r.Email) > 255 {
-		return errors.New("email must not exceed 255 characters")
-	}
-
-	if len(r.Password) < 8 {
-		return errors.New("password must be at least 8 characters long")
-	}
-
-	if len(r.Password) > 100 {
-		return errors.New("password must not exceed 100 characters")
-	}
-
-	if r.Role != "" && r.Role != "user" && r.Role != "admin" {
-		return errors.New("role must be either 'user' or 'admin'")
-	}
-
-	return nil
+// TwoFactorCodeInput represents input carrying a TOTP or recovery code
+type TwoFactorCodeInput struct {
+	Code string `json:"code" validate:"required"`
 }
 
-// Validate validates LoginInput
-func (l LoginInput) Validate() error {
-	if !strings.Contains(l.Email, "@") {
-		return errors.New("email must be a valid email address")
-	}
-
-	if len(l.Password) < 1 {
-		return errors.New("password is required")
-	}
-
-	return nil
+// TwoFactorVerifyInput represents input for the second login step
+type TwoFactorVerifyInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
 }
 
-// Validate validates RefreshInput
-func (r RefreshInput) Validate() error {
-	if len(strings.TrimSpace(r.RefreshToken)) == 0 {
-		return errors.New("refresh token is required")
+// Validate checks the password against the password policy, once the tags
+// have passed
+func (r RegisterInput) Validate() error {
+	if err := passwords.Validate(r.Password, r.Email, r.Name); err != nil {
+		return form.Invalid("password", err)
 	}
 
 	return nil
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/apikey"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/form"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+)
+
+// apiKeyResponse is the JSON representation of an API key (never includes the secret)
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RegisterAPIKeyRoutes registers personal access token management routes
+func RegisterAPIKeyRoutes(r *frameworkrouter.Router) {
+	// GET /api/v1/auth/api-keys - List the current user's API keys (requires authentication)
+	r.Handle("GET", "/api/v1/auth/api-keys", handleAPIKeysList())
+
+	// POST /api/v1/auth/api-keys - Create an API key, the key is returned once (requires a login session)
+	r.Handle("POST", "/api/v1/auth/api-keys", handleAPIKeyCreate())
+
+	// DELETE /api/v1/auth/api-keys/{id} - Revoke one of the current user's API keys
+	r.Handle("DELETE", "/api/v1/auth/api-keys/{id}", handleAPIKeyRevoke())
+}
+
+// handleAPIKeysList returns the current user's API keys
+func handleAPIKeysList() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		keys, err := db.ListAPIKeys(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load API keys"})
+			return
+		}
+
+		out := make([]apiKeyResponse, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, toAPIKeyResponse(k))
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleAPIKeyCreate issues a new API key for the current user
+func handleAPIKeyCreate() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		// A leaked key must not be able to mint more keys
+		if middleware.GetAPIKey(r.Context()) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "API keys cannot be created with an API key"})
+			return
+		}
+
+		var input models.APIKeyInput
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
+			return
+		}
+
+		claims := middleware.GetClaims(r.Context())
+		for _, s := range input.Scopes {
+			if s == apikey.ScopeAdmin && claims.Role != "admin" {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "Only admins can create keys with the admin scope"})
+				return
+			}
+		}
+
+		token, key, err := apikey.Create(r.Context(), userID, input.Name, input.Scopes, input.ExpiresInDays)
+		if err != nil {
+			switch {
+			case errors.Is(err, apikey.ErrInvalidName), errors.Is(err, apikey.ErrInvalidScope), errors.Is(err, apikey.ErrExpiryTooLong):
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			case errors.Is(err, apikey.ErrTooManyKeys):
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			case errors.Is(err, apikey.ErrDisabled):
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			default:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create API key"})
+			}
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"key":     token,
+			"api_key": toAPIKeyResponse(key),
+			"message": "Store this key now, it will not be shown again",
+		})
+	}
+}
+
+// handleAPIKeyRevoke revokes one of the current user's API keys
+func handleAPIKeyRevoke() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		id, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid API key ID"})
+			return
+		}
+
+		key, err := db.GetAPIKey(r.Context(), id)
+		if err != nil || key.UserID != userID {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "API key not found"})
+			return
+		}
+
+		if err := apikey.Revoke(r.Context(), key, "user:"+strconv.FormatInt(userID, 10)); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to revoke API key"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"message": "API key revoked"})
+	}
+}
+
+func toAPIKeyResponse(k db.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  timeOrNil(k.ExpiresAt),
+		LastUsedAt: timeOrNil(k.LastUsedAt),
+		LastUsedIP: k.LastUsedIP,
+		RevokedAt:  timeOrNil(k.RevokedAt),
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// timeOrNil maps a zero time to null in JSON
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/apikey"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
+)
+
+func TestAPIKeyCreateValidation(t *testing.T) {
+	testDB(t)
+	apikey.Configure(config.APIKeysConfig{Enabled: true})
+	t.Cleanup(func() { apikey.Configure(config.APIKeysConfig{}) })
+
+	user, err := db.CreateUser(context.Background(), models.RegisterInput{Name: "Ana", Email: "ana@example.com", Role: "user"}, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.SecurityConfig{EnableJWT: true, JWTSecret: "test-secret"}
+	session, err := security.GenerateToken(cfg.JWTSecret, fmt.Sprintf("%d", user.ID), user.Role, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := frameworkrouter.New()
+	r.Use(middleware.JWTAuthMiddleware(cfg))
+	RegisterAPIKeyRoutes(r)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/auth/api-keys", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+session)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Errors come back per field, like the other JSON handlers'
+	rec := post(`{"name": "", "scopes": ["read", "root"]}`)
+	var body struct {
+		Errors map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusBadRequest {
+		t.Fatalf("POST = %d %s, want 400", rec.Code, rec.Body)
+	}
+	if body.Errors["name"] != "name is required" || body.Errors["scopes"] != "scopes must be one of: read, write, admin" {
+		t.Errorf("errors = %v", body.Errors)
+	}
+
+	if rec := post(`{"name": "ci", "scopes": ["read", "write"]}`); rec.Code != http.StatusCreated {
+		t.Errorf("POST = %d %s, want 201", rec.Code, rec.Body)
+	}
+}
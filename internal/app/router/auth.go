@@ -1,50 +1,54 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/AlejandroMBJS/goBastion/internal/app/models"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/form"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/loginguard"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // RegisterAuthRoutes registers authentication routes
 func RegisterAuthRoutes(r *frameworkrouter.Router, cfg config.SecurityConfig) {
+	limited := frameworkrouter.RateLimit("auth")
+
 	// POST /api/v1/auth/register
-	r.Handle("POST", "/api/v1/auth/register", handleRegister(cfg))
+	r.Handle("POST", "/api/v1/auth/register", handleRegister(cfg), frameworkrouter.Public(), frameworkrouter.CSRFExempt(), limited)
 
 	// POST /api/v1/auth/login
-	r.Handle("POST", "/api/v1/auth/login", handleLogin(cfg))
+	r.Handle("POST", "/api/v1/auth/login", handleLogin(cfg), frameworkrouter.Public(), frameworkrouter.CSRFExempt(), limited)
 
 	// POST /api/v1/auth/refresh
-	r.Handle("POST", "/api/v1/auth/refresh", handleRefresh(cfg))
+	r.Handle("POST", "/api/v1/auth/refresh", handleRefresh(cfg), frameworkrouter.Public(), frameworkrouter.CSRFExempt(), limited)
 
 	// GET /api/v1/auth/me (requires authentication)
 	r.Handle("GET", "/api/v1/auth/me", handleMe())
+
+	// POST /api/v1/auth/password (requires authentication)
+	r.Handle("POST", "/api/v1/auth/password", handleChangePassword(), limited)
 }
 
 // handleRegister handles user registration
 func handleRegister(cfg config.SecurityConfig) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		var input models.RegisterInput
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
-			return
-		}
-
-		// Validate input
-		if err := input.Validate(); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
 			return
 		}
+		input.Role = "user" // Sign-ups can't pick their role; admins promote users
 
 		// Check if user already exists
 		_, _, err := db.GetUserByEmail(r.Context(), input.Email)
@@ -54,14 +58,14 @@ func handleRegister(cfg config.SecurityConfig) frameworkrouter.Handler {
 		}
 
 		// Hash password
-		passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		passwordHash, err := passwords.Hash(input.Password)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
 			return
 		}
 
 		// Create user
-		user, err := db.CreateUser(r.Context(), input, string(passwordHash))
+		user, err := db.CreateUser(r.Context(), input, passwordHash)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
 			return
@@ -94,29 +98,35 @@ func handleRegister(cfg config.SecurityConfig) frameworkrouter.Handler {
 func handleLogin(cfg config.SecurityConfig) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		var input models.LoginInput
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
 			return
 		}
 
-		// Validate input
-		if err := input.Validate(); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		// Reject attempts while the account or IP is locked or backing off
+		ip := middleware.ClientIP(r)
+		if err := loginguard.Check(r.Context(), input.Email, ip); err != nil {
+			writeLoginThrottled(w, err)
 			return
 		}
 
 		// Get user by email
 		user, passwordHash, err := db.GetUserByEmail(r.Context(), input.Email)
 		if err != nil {
+			// Spend the same time as a real check so unknown emails can't be detected
+			loginguard.CompareWithDummy(input.Password)
+			loginguard.RecordFailure(r.Context(), input.Email, ip)
 			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid email or password"})
 			return
 		}
 
 		// Verify password
-		if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(input.Password)); err != nil {
+		if err := passwords.Compare(passwordHash, input.Password); err != nil {
+			loginguard.RecordFailure(r.Context(), input.Email, ip)
 			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid email or password"})
 			return
 		}
+		rehashIfNeeded(r.Context(), user.ID, passwordHash, input.Password)
 
 		// Check if user is active
 		if !user.IsActive {
@@ -124,26 +134,31 @@ func handleLogin(cfg config.SecurityConfig) frameworkrouter.Handler {
 			return
 		}
 
-		// Generate tokens
-		accessToken, refreshToken, err := security.CreateTokenPair(
-			cfg.JWTSecret,
-			fmt.Sprintf("%d", user.ID),
-			user.Role,
-			cfg.AccessTokenMinutes,
-			cfg.RefreshTokenMinutes,
-		)
+		// Require the second factor before issuing tokens
+		mfaEnabled, err := db.IsTOTPEnabled(r.Context(), user.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate tokens"})
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load two-factor status"})
+			return
+		}
+		if mfaEnabled {
+			challenge, err := security.GenerateMFAChallenge(cfg.JWTSecret, fmt.Sprintf("%d", user.ID), mfaChallengeMinutes)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate two-factor challenge"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{
+				"mfa_required":    true,
+				"challenge_token": challenge,
+			})
 			return
 		}
 
-		// Return response
-		writeJSON(w, http.StatusOK, map[string]any{
-			"user":          user,
-			"access_token":  accessToken,
-			"refresh_token": refreshToken,
-			"token_type":    "Bearer",
-		})
+		// Failures are only cleared once the login fully succeeds, so a known
+		// password can't be used to reset the counter between 2FA guesses
+		loginguard.RecordSuccess(r.Context(), user.Email)
+
+		// Generate tokens and return response
+		writeLoginTokens(w, cfg, user)
 	}
 }
 
@@ -151,14 +166,8 @@ func handleLogin(cfg config.SecurityConfig) frameworkrouter.Handler {
 func handleRefresh(cfg config.SecurityConfig) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		var input models.RefreshInput
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
-			return
-		}
-
-		// Validate input
-		if err := input.Validate(); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
 			return
 		}
 
@@ -222,6 +231,105 @@ func handleMe() frameworkrouter.Handler {
 	}
 }
 
+// handleChangePassword changes the current user's password after checking the
+// current one, the password policy and the password history
+func handleChangePassword() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		// Changing the password requires knowing it, which an API key doesn't prove
+		if middleware.GetAPIKey(r.Context()) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "Passwords cannot be changed with an API key"})
+			return
+		}
+
+		var input models.ChangePasswordInput
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
+			return
+		}
+
+		user, err := db.GetUser(r.Context(), int(userID))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+		passwordHash, err := db.GetPasswordHash(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load user"})
+			return
+		}
+
+		// Verify current password
+		if err := passwords.Compare(passwordHash, input.CurrentPassword); err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Current password is incorrect"})
+			return
+		}
+
+		// Check the new password against the policy and recent passwords
+		if err := passwords.Validate(input.NewPassword, user.Email, user.Name); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if n := passwords.HistorySize(); n > 0 {
+			history, err := db.ListPasswordHistory(r.Context(), userID, n)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to check password history"})
+				return
+			}
+			if passwords.InHistory(input.NewPassword, append(history, passwordHash)) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": passwords.ErrReused.Error()})
+				return
+			}
+		}
+
+		newHash, err := passwords.Hash(input.NewPassword)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
+			return
+		}
+		if err := db.SetUserPassword(r.Context(), userID, newHash); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update password"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Password changed"})
+	}
+}
+
+// rehashIfNeeded upgrades a verified password's hash when the bcrypt cost has
+// changed. Failures are only logged; the login itself has already succeeded.
+func rehashIfNeeded(ctx context.Context, userID int64, passwordHash, password string) {
+	if !passwords.NeedsRehash(passwordHash) {
+		return
+	}
+	newHash, err := passwords.Hash(password)
+	if err == nil {
+		err = db.RehashUserPassword(ctx, userID, newHash)
+	}
+	if err != nil {
+		log.Printf("Failed to rehash password for user %d: %v", userID, err)
+	}
+}
+
+// writeLoginThrottled responds to a login attempt rejected by the brute-force guard
+func writeLoginThrottled(w http.ResponseWriter, err error) {
+	var throttled *loginguard.ThrottledError
+	if !errors.As(err, &throttled) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to check login attempts"})
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(throttled.RetryAfterSeconds()))
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{
+		"error":       throttled.Error(),
+		"retry_after": throttled.RetryAfterSeconds(),
+	})
+}
+
 // writeJSON is a helper function to write JSON responses
 func writeJSON(w http.ResponseWriter, statusCode int, data any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
+)
+
+// Public sign-ups always get the user role, whatever they ask for
+func TestRegisterIgnoresRole(t *testing.T) {
+	testDB(t)
+
+	body := `{"name": "Mallory", "email": "mallory@example.com", "password": "correct-Horse-battery-9", "role": "admin"}`
+	req := httptest.NewRequest("POST", "/api/v1/auth/register", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handleRegister(config.SecurityConfig{JWTSecret: "test-secret"})(rec, req, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/v1/auth/register = %d: %s", rec.Code, rec.Body)
+	}
+
+	user, _, err := db.GetUserByEmail(context.Background(), "mallory@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != "user" {
+		t.Errorf("Role = %q, want user", user.Role)
+	}
+}
+
+// The sign-up password counts as a previous password
+func TestRegisterRecordsPasswordHistory(t *testing.T) {
+	testDB(t)
+	cfg := config.SecurityConfig{EnableJWT: true, JWTSecret: "test-secret", BcryptCost: 4}
+	cfg.PasswordPolicy.HistorySize = 5
+	passwords.Configure(cfg)
+	t.Cleanup(func() { passwords.Configure(config.SecurityConfig{}) })
+
+	body := `{"name": "Ana", "email": "ana@example.com", "password": "correct-Horse-battery-9"}`
+	req := httptest.NewRequest("POST", "/api/v1/auth/register", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handleRegister(cfg)(rec, req, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/v1/auth/register = %d: %s", rec.Code, rec.Body)
+	}
+
+	user, _, err := db.GetUserByEmail(context.Background(), "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history, err := db.ListPasswordHistory(context.Background(), user.ID, 5); err != nil || len(history) != 1 {
+		t.Fatalf("ListPasswordHistory() = %d entries, %v, want 1", len(history), err)
+	}
+
+	session, err := security.GenerateToken(cfg.JWTSecret, fmt.Sprintf("%d", user.ID), user.Role, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := frameworkrouter.New()
+	r.Use(middleware.JWTAuthMiddleware(cfg))
+	r.Handle("POST", "/api/v1/auth/password", handleChangePassword())
+	change := func(current, next string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"current_password": %q, "new_password": %q}`, current, next)
+		req := httptest.NewRequest("POST", "/api/v1/auth/password", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+session)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := change("correct-Horse-battery-9", "Another-long-phrase-7"); rec.Code != http.StatusOK {
+		t.Fatalf("Changing the password = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := change("Another-long-phrase-7", "correct-Horse-battery-9"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Changing back to the sign-up password = %d, want 400: %s", rec.Code, rec.Body)
+	}
+}
@@ -1,35 +1,55 @@
 package router
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/AlejandroMBJS/goBastion/internal/app/models"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/form"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/loginguard"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/oidc"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // RegisterAuthViewsRoutes registers HTML authentication routes
+//
+// These pages are public. Their forms carry @csrfField, checked by the CSRF
+// middleware like any other form post.
 func RegisterAuthViewsRoutes(r *frameworkrouter.Router, cfg config.SecurityConfig, views *view.Engine) {
+	public := frameworkrouter.Public()
+	limited := frameworkrouter.RateLimit("auth") // credential guessing
+
 	// GET /login - show login page
-	r.Handle("GET", "/login", handleLoginPage(cfg, views))
+	r.Handle("GET", "/login", handleLoginPage(cfg, views), public)
 
 	// POST /login - process login form
-	r.Handle("POST", "/login", handleLoginForm(cfg, views))
+	r.Handle("POST", "/login", handleLoginForm(cfg, views), public, limited)
+
+	// GET /login/2fa - show two-factor challenge page
+	r.Handle("GET", "/login/2fa", handleTwoFactorPage(cfg, views), public)
+
+	// POST /login/2fa - verify two-factor code
+	r.Handle("POST", "/login/2fa", handleTwoFactorForm(cfg, views), public, limited)
 
 	// GET /register - show register page
-	r.Handle("GET", "/register", handleRegisterPage(cfg, views))
+	r.Handle("GET", "/register", handleRegisterPage(cfg, views), public)
 
 	// POST /register - process register form
-	r.Handle("POST", "/register", handleRegisterForm(cfg, views))
+	r.Handle("POST", "/register", handleRegisterForm(cfg, views), public, limited)
+
+	// GET /unlock - unlock a locked-out account via emailed link
+	r.Handle("GET", "/unlock", handleUnlock(cfg, views), public)
 
 	// GET /logout - logout user
-	r.Handle("GET", "/logout", handleLogout())
+	r.Handle("GET", "/logout", handleLogout(cfg), public)
 }
 
 // handleLoginPage shows the login page
@@ -37,25 +57,10 @@ func handleLoginPage(cfg config.SecurityConfig, views *view.Engine) frameworkrou
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		data := map[string]any{
 			"Error":     "",
-			"CSRFToken": "",
+			"Providers": oidc.Providers(),
 		}
 
-		// Generate CSRF token if CSRF is enabled
-		if cfg.EnableCSRF {
-			token, err := security.GenerateCSRFToken(cfg.JWTSecret)
-			if err == nil {
-				data["CSRFToken"] = token
-				// Set CSRF cookie
-				http.SetCookie(w, &http.Cookie{
-					Name:     cfg.CSRFCookieName,
-					Value:    token,
-					Path:     "/",
-					HttpOnly: true,
-					Secure:   false, // Set to true in production with HTTPS
-					SameSite: http.SameSiteStrictMode,
-				})
-			}
-		}
+		rememberReturnTo(w, r, cfg)
 
 		if err := views.Render(w, "auth/login", data); err != nil {
 			http.Error(w, "Failed to render template", http.StatusInternalServerError)
@@ -66,58 +71,227 @@ func handleLoginPage(cfg config.SecurityConfig, views *view.Engine) frameworkrou
 // handleLoginForm processes the login form submission
 func handleLoginForm(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-		// Parse form
-		if err := r.ParseForm(); err != nil {
-			renderLoginError(w, views, cfg, "Invalid form data")
+		var input models.LoginInput
+		if err := form.Bind(r, params, &input); err != nil {
+			renderLoginError(w, views, cfg, form.Message(r.Context(), err))
 			return
 		}
+		email, password := input.Email, input.Password
 
-		email := r.FormValue("email")
-		password := r.FormValue("password")
-
-		// Validate CSRF if enabled
-		if cfg.EnableCSRF {
-			csrfCookie, err := r.Cookie(cfg.CSRFCookieName)
-			if err != nil || csrfCookie.Value == "" {
-				renderLoginError(w, views, cfg, "CSRF token missing")
-				return
-			}
-			csrfFormToken := r.FormValue("csrf_token")
-			if !security.ValidateCSRFToken(cfg.JWTSecret, csrfCookie.Value, csrfFormToken) {
-				renderLoginError(w, views, cfg, "CSRF token invalid")
-				return
-			}
-		}
-
-		// Validate input
-		loginInput := models.LoginInput{
-			Email:    email,
-			Password: password,
-		}
-		if err := loginInput.Validate(); err != nil {
-			renderLoginError(w, views, cfg, err.Error())
+		// Reject attempts while the account or IP is locked or backing off
+		ip := middleware.ClientIP(r)
+		if err := loginguard.Check(r.Context(), email, ip); err != nil {
+			renderLoginThrottled(w, views, cfg, err)
 			return
 		}
 
 		// Get user by email
 		user, passwordHash, err := db.GetUserByEmail(r.Context(), email)
 		if err != nil {
+			// Spend the same time as a real check so unknown emails can't be detected
+			loginguard.CompareWithDummy(password)
+			loginguard.RecordFailure(r.Context(), email, ip)
 			renderLoginError(w, views, cfg, "Invalid email or password")
 			return
 		}
 
 		// Verify password
-		if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		if err := passwords.Compare(passwordHash, password); err != nil {
+			loginguard.RecordFailure(r.Context(), email, ip)
 			renderLoginError(w, views, cfg, "Invalid email or password")
 			return
 		}
+		rehashIfNeeded(r.Context(), user.ID, passwordHash, password)
+
+		completeBrowserLogin(w, r, cfg, views, user)
+	}
+}
+
+// completeBrowserLogin finishes an HTML login once the user's identity is proven
+// (password or external provider): it starts the two-factor step when enabled,
+// otherwise sets the auth cookie and redirects based on role
+func completeBrowserLogin(w http.ResponseWriter, r *http.Request, cfg config.SecurityConfig, views *view.Engine, user models.User) {
+	// Check if user is active
+	if !user.IsActive {
+		renderLoginError(w, views, cfg, "Account is inactive")
+		return
+	}
+
+	// Require the second factor before setting the auth cookie
+	mfaEnabled, err := db.IsTOTPEnabled(r.Context(), user.ID)
+	if err != nil {
+		renderLoginError(w, views, cfg, "Failed to load two-factor status")
+		return
+	}
+	if mfaEnabled {
+		challenge, err := security.GenerateMFAChallenge(cfg.JWTSecret, fmt.Sprintf("%d", user.ID), mfaChallengeMinutes)
+		if err != nil {
+			renderLoginError(w, views, cfg, "Failed to start two-factor challenge")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     mfaChallengeCookie,
+			Value:    challenge,
+			Path:     "/login/2fa",
+			HttpOnly: true,
+			Secure:   cfg.SecureCookies,
+			SameSite: http.SameSiteLaxMode, // Lax so it survives the redirect back from an identity provider
+			MaxAge:   mfaChallengeMinutes * 60,
+		})
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return
+	}
+
+	loginguard.RecordSuccess(r.Context(), user.Email)
+
+	// Generate JWT token
+	accessToken, err := security.GenerateToken(
+		cfg.JWTSecret,
+		fmt.Sprintf("%d", user.ID),
+		user.Role,
+		cfg.AccessTokenMinutes,
+	)
+	if err != nil {
+		renderLoginError(w, views, cfg, "Failed to generate token")
+		return
+	}
+
+	// Set auth cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     "auth_token",
+		Value:    accessToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   cfg.AccessTokenMinutes * 60,
+	})
+
+	redirectAfterLogin(w, r, cfg, user)
+}
+
+// loginReturnCookie remembers the page a browser was sent to the login page from
+const loginReturnCookie = "login_return_to"
+
+// rememberReturnTo stores the ?return_to= of the login page so it survives the
+// two-factor step and external provider round-trips
+func rememberReturnTo(w http.ResponseWriter, r *http.Request, cfg config.SecurityConfig) {
+	returnTo := r.URL.Query().Get("return_to")
+	if returnTo == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginReturnCookie,
+		Value:    safeReturnTo(returnTo),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   oidcFlowMinutes * 60,
+	})
+}
+
+// redirectAfterLogin sends the user back to the page they were trying to reach,
+// or to their role's landing page
+func redirectAfterLogin(w http.ResponseWriter, r *http.Request, cfg config.SecurityConfig, user models.User) {
+	if c, err := r.Cookie(loginReturnCookie); err == nil && c.Value != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     loginReturnCookie,
+			Value:    "",
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   cfg.SecureCookies,
+			SameSite: http.SameSiteLaxMode,
+			MaxAge:   -1,
+		})
+		http.Redirect(w, r, safeReturnTo(c.Value), http.StatusSeeOther)
+		return
+	}
+
+	// Redirect based on role
+	if user.Role == "admin" || user.IsStaff || user.IsSuperuser {
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
+	} else {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// handleUnlock unlocks an account from the link sent when it was locked out
+func handleUnlock(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if _, err := loginguard.UnlockWithToken(r.Context(), r.URL.Query().Get("token")); err != nil {
+			renderLoginError(w, views, cfg, "This unlock link is invalid or has expired")
+			return
+		}
 
-		// Check if user is active
-		if !user.IsActive {
-			renderLoginError(w, views, cfg, "Account is inactive")
+		data := map[string]any{
+			"Error":     "",
+			"Success":   "Your account has been unlocked. You can sign in again.",
+			"Providers": oidc.Providers(),
+		}
+
+		if err := views.Render(w, "auth/login", data); err != nil {
+			http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		}
+	}
+}
+
+// mfaChallengeCookie holds the pending two-factor challenge between login steps
+const mfaChallengeCookie = "mfa_challenge"
+
+// handleTwoFactorPage shows the two-factor challenge page
+func handleTwoFactorPage(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		cookie, err := r.Cookie(mfaChallengeCookie)
+		if err != nil || cookie.Value == "" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
 
+		data := map[string]any{
+			"Error": "",
+		}
+
+		if err := views.Render(w, "auth/two_factor", data); err != nil {
+			http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		}
+	}
+}
+
+// handleTwoFactorForm verifies the TOTP or recovery code and completes the login
+func handleTwoFactorForm(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if err := r.ParseForm(); err != nil {
+			renderTwoFactorError(w, views, cfg, "Invalid form data")
+			return
+		}
+
+		challengeCookie, err := r.Cookie(mfaChallengeCookie)
+		if err != nil || challengeCookie.Value == "" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		user, err := userFromChallenge(r.Context(), cfg, challengeCookie.Value)
+		if err != nil {
+			renderLoginError(w, views, cfg, "Your sign-in session expired, please log in again")
+			return
+		}
+
+		// Failed codes count against the same budget as failed passwords
+		ip := middleware.ClientIP(r)
+		if err := loginguard.Check(r.Context(), user.Email, ip); err != nil {
+			renderLoginThrottled(w, views, cfg, err)
+			return
+		}
+
+		if err := verifySecondFactor(r.Context(), user.ID, r.FormValue("code")); err != nil {
+			loginguard.RecordFailure(r.Context(), user.Email, ip)
+			renderTwoFactorError(w, views, cfg, "Invalid two-factor code")
+			return
+		}
+		loginguard.RecordSuccess(r.Context(), user.Email)
+
 		// Generate JWT token
 		accessToken, err := security.GenerateToken(
 			cfg.JWTSecret,
@@ -126,27 +300,33 @@ func handleLoginForm(cfg config.SecurityConfig, views *view.Engine) frameworkrou
 			cfg.AccessTokenMinutes,
 		)
 		if err != nil {
-			renderLoginError(w, views, cfg, "Failed to generate token")
+			renderTwoFactorError(w, views, cfg, "Failed to generate token")
 			return
 		}
 
+		// Clear the challenge cookie with the attributes it was set with
+		http.SetCookie(w, &http.Cookie{
+			Name:     mfaChallengeCookie,
+			Value:    "",
+			Path:     "/login/2fa",
+			HttpOnly: true,
+			Secure:   cfg.SecureCookies,
+			SameSite: http.SameSiteLaxMode,
+			MaxAge:   -1,
+		})
+
 		// Set auth cookie
 		http.SetCookie(w, &http.Cookie{
 			Name:     "auth_token",
 			Value:    accessToken,
 			Path:     "/",
 			HttpOnly: true,
-			Secure:   false, // Set to true in production with HTTPS
+			Secure:   cfg.SecureCookies,
 			SameSite: http.SameSiteLaxMode,
 			MaxAge:   cfg.AccessTokenMinutes * 60,
 		})
 
-		// Redirect based on role
-		if user.Role == "admin" || user.IsStaff || user.IsSuperuser {
-			http.Redirect(w, r, "/admin", http.StatusSeeOther)
-		} else {
-			http.Redirect(w, r, "/", http.StatusSeeOther)
-		}
+		redirectAfterLogin(w, r, cfg, user)
 	}
 }
 
@@ -154,28 +334,8 @@ func handleLoginForm(cfg config.SecurityConfig, views *view.Engine) frameworkrou
 func handleRegisterPage(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		data := map[string]any{
-			"Error":     "",
-			"Success":   "",
-			"CSRFToken": "",
-			"Name":      "",
-			"Email":     "",
-		}
-
-		// Generate CSRF token if CSRF is enabled
-		if cfg.EnableCSRF {
-			token, err := security.GenerateCSRFToken(cfg.JWTSecret)
-			if err == nil {
-				data["CSRFToken"] = token
-				// Set CSRF cookie
-				http.SetCookie(w, &http.Cookie{
-					Name:     cfg.CSRFCookieName,
-					Value:    token,
-					Path:     "/",
-					HttpOnly: true,
-					Secure:   false, // Set to true in production with HTTPS
-					SameSite: http.SameSiteStrictMode,
-				})
-			}
+			"Form":    form.New(r, nil),
+			"Success": "",
 		}
 
 		if err := views.Render(w, "auth/register", data); err != nil {
@@ -184,70 +344,40 @@ func handleRegisterPage(cfg config.SecurityConfig, views *view.Engine) framework
 	}
 }
 
+// registerForm is the registration form: the API's input plus a confirmation
+type registerForm struct {
+	models.RegisterInput
+	ConfirmPassword string `form:"confirm_password" validate:"required,eqfield=Password"`
+}
+
 // handleRegisterForm processes the registration form submission
 func handleRegisterForm(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-		// Parse form
-		if err := r.ParseForm(); err != nil {
-			renderRegisterError(w, views, cfg, "Invalid form data", "", "")
-			return
-		}
-
-		name := r.FormValue("name")
-		email := r.FormValue("email")
-		password := r.FormValue("password")
-		confirmPassword := r.FormValue("confirm_password")
-
-		// Validate CSRF if enabled
-		if cfg.EnableCSRF {
-			csrfCookie, err := r.Cookie(cfg.CSRFCookieName)
-			if err != nil || csrfCookie.Value == "" {
-				renderRegisterError(w, views, cfg, "CSRF token missing", name, email)
-				return
-			}
-			csrfFormToken := r.FormValue("csrf_token")
-			if !security.ValidateCSRFToken(cfg.JWTSecret, csrfCookie.Value, csrfFormToken) {
-				renderRegisterError(w, views, cfg, "CSRF token invalid", name, email)
-				return
-			}
-		}
-
-		// Check password confirmation
-		if password != confirmPassword {
-			renderRegisterError(w, views, cfg, "Passwords do not match", name, email)
-			return
-		}
-
-		// Validate input
-		registerInput := models.RegisterInput{
-			Name:     name,
-			Email:    email,
-			Password: password,
-			Role:     "user", // Default role
-		}
-		if err := registerInput.Validate(); err != nil {
-			renderRegisterError(w, views, cfg, err.Error(), name, email)
+		var input registerForm
+		if err := form.Bind(r, params, &input); err != nil {
+			renderRegisterError(w, r, views, err)
 			return
 		}
+		input.Role = "user" // Sign-ups can't pick their role
 
 		// Check if user already exists
-		_, _, err := db.GetUserByEmail(r.Context(), email)
+		_, _, err := db.GetUserByEmail(r.Context(), input.Email)
 		if err == nil {
-			renderRegisterError(w, views, cfg, "User with this email already exists", name, email)
+			renderRegisterError(w, r, views, form.Invalid("email", errors.New("User with this email already exists")))
 			return
 		}
 
 		// Hash password
-		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		passwordHash, err := passwords.Hash(input.Password)
 		if err != nil {
-			renderRegisterError(w, views, cfg, "Failed to hash password", name, email)
+			renderRegisterError(w, r, views, errors.New("Failed to hash password"))
 			return
 		}
 
 		// Create user
-		user, err := db.CreateUser(r.Context(), registerInput, string(passwordHash))
+		user, err := db.CreateUser(r.Context(), input.RegisterInput, passwordHash)
 		if err != nil {
-			renderRegisterError(w, views, cfg, "Failed to create user", name, email)
+			renderRegisterError(w, r, views, errors.New("Failed to create user"))
 			return
 		}
 
@@ -259,7 +389,7 @@ func handleRegisterForm(cfg config.SecurityConfig, views *view.Engine) framework
 			cfg.AccessTokenMinutes,
 		)
 		if err != nil {
-			renderRegisterError(w, views, cfg, "Failed to generate token", name, email)
+			renderRegisterError(w, r, views, errors.New("Failed to generate token"))
 			return
 		}
 
@@ -269,7 +399,7 @@ func handleRegisterForm(cfg config.SecurityConfig, views *view.Engine) framework
 			Value:    accessToken,
 			Path:     "/",
 			HttpOnly: true,
-			Secure:   false, // Set to true in production with HTTPS
+			Secure:   cfg.SecureCookies,
 			SameSite: http.SameSiteLaxMode,
 			MaxAge:   cfg.AccessTokenMinutes * 60,
 		})
@@ -287,23 +417,7 @@ func handleRegisterForm(cfg config.SecurityConfig, views *view.Engine) framework
 func renderLoginError(w http.ResponseWriter, views *view.Engine, cfg config.SecurityConfig, errorMsg string) {
 	data := map[string]any{
 		"Error":     errorMsg,
-		"CSRFToken": "",
-	}
-
-	// Generate new CSRF token
-	if cfg.EnableCSRF {
-		token, err := security.GenerateCSRFToken(cfg.JWTSecret)
-		if err == nil {
-			data["CSRFToken"] = token
-			http.SetCookie(w, &http.Cookie{
-				Name:     cfg.CSRFCookieName,
-				Value:    token,
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   false,
-				SameSite: http.SameSiteStrictMode,
-			})
-		}
+		"Providers": oidc.Providers(),
 	}
 
 	w.WriteHeader(http.StatusBadRequest)
@@ -312,30 +426,38 @@ func renderLoginError(w http.ResponseWriter, views *view.Engine, cfg config.Secu
 	}
 }
 
-// Helper function to render register page with error
-func renderRegisterError(w http.ResponseWriter, views *view.Engine, cfg config.SecurityConfig, errorMsg, name, email string) {
+// renderLoginThrottled renders the login page for an attempt rejected by the brute-force guard
+func renderLoginThrottled(w http.ResponseWriter, views *view.Engine, cfg config.SecurityConfig, err error) {
+	var throttled *loginguard.ThrottledError
+	if !errors.As(err, &throttled) {
+		renderLoginError(w, views, cfg, "Failed to check login attempts")
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(throttled.RetryAfterSeconds()))
+	if throttled.Locked {
+		renderLoginError(w, views, cfg, fmt.Sprintf("Too many failed login attempts. Try again in %d minutes or use the unlock link sent to your email.", (throttled.RetryAfterSeconds()+59)/60))
+		return
+	}
+	renderLoginError(w, views, cfg, fmt.Sprintf("Please wait %d seconds before trying again.", throttled.RetryAfterSeconds()))
+}
+
+// Helper function to render two-factor page with error
+func renderTwoFactorError(w http.ResponseWriter, views *view.Engine, cfg config.SecurityConfig, errorMsg string) {
 	data := map[string]any{
-		"Error":     errorMsg,
-		"Success":   "",
-		"CSRFToken": "",
-		"Name":      name,
-		"Email":     email,
+		"Error": errorMsg,
 	}
 
-	// Generate new CSRF token
-	if cfg.EnableCSRF {
-		token, err := security.GenerateCSRFToken(cfg.JWTSecret)
-		if err == nil {
-			data["CSRFToken"] = token
-			http.SetCookie(w, &http.Cookie{
-				Name:     cfg.CSRFCookieName,
-				Value:    token,
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   false,
-				SameSite: http.SameSiteStrictMode,
-			})
-		}
+	w.WriteHeader(http.StatusBadRequest)
+	if err := views.Render(w, "auth/two_factor", data); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// Helper function to render register page with error
+func renderRegisterError(w http.ResponseWriter, r *http.Request, views *view.Engine, err error) {
+	data := map[string]any{
+		"Form":    form.New(r, err),
+		"Success": "",
 	}
 
 	w.WriteHeader(http.StatusBadRequest)
@@ -345,7 +467,7 @@ func renderRegisterError(w http.ResponseWriter, views *view.Engine, cfg config.S
 }
 
 // handleLogout handles user logout
-func handleLogout() frameworkrouter.Handler {
+func handleLogout(cfg config.SecurityConfig) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		// Clear auth cookie
 		http.SetCookie(w, &http.Cookie{
@@ -353,7 +475,7 @@ func handleLogout() frameworkrouter.Handler {
 			Value:    "",
 			Path:     "/",
 			HttpOnly: true,
-			Secure:   false,
+			Secure:   cfg.SecureCookies,
 			SameSite: http.SameSiteLaxMode,
 			MaxAge:   -1, // Delete cookie
 		})
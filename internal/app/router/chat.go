@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/AlejandroMBJS/goBastion/internal/app/chat"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/htmx"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
 
@@ -35,14 +36,31 @@ func RegisterChatRoutes(r *frameworkrouter.Router, views *view.Engine) {
 	// Server-Sent Events endpoint for real-time messages
 	r.GET("/chat/{room}/stream", handleChatStream)
 
-	// HTMX endpoint to send messages
-	r.POST("/chat/{room}/send", handleSendMessage(views))
+	// HTMX endpoint to send messages (limited per user by the "chat" policy)
+	r.POST("/chat/{room}/send", handleSendMessage(views), frameworkrouter.RateLimit("chat"))
 
 	// HTMX endpoint to get message history
 	r.GET("/chat/{room}/history", handleChatHistory(views))
 
 	// API endpoint for stats (demonstrates concurrent operations)
 	r.GET("/api/chat/stats", handleChatStats)
+
+	// Data types for go-bastion templates check
+	views.Declare("chat/room", chatRoomData{})
+	views.Declare("chat/messages", chatHistoryData{})
+}
+
+// chatRoomData is what chat/room renders
+type chatRoomData struct {
+	Title    string
+	RoomID   string
+	Username string
+	UserID   string
+}
+
+// chatHistoryData is what chat/messages renders
+type chatHistoryData struct {
+	Messages []chat.Message
 }
 
 // handleChatRoom renders the chat room interface
@@ -61,11 +79,11 @@ func handleChatRoom(views *view.Engine) frameworkrouter.Handler {
 			}
 		}
 
-		data := map[string]interface{}{
-			"Title":    "Chat Room - " + roomID,
-			"RoomID":   roomID,
-			"Username": username,
-			"UserID":   uuid.New().String()[:8],
+		data := chatRoomData{
+			Title:    "Chat Room - " + roomID,
+			RoomID:   roomID,
+			Username: username,
+			UserID:   uuid.New().String()[:8],
 		}
 
 		views.Render(w, "chat/room", data)
@@ -175,7 +193,8 @@ func handleSendMessage(views *view.Engine) frameworkrouter.Handler {
 		// Broadcast message (non-blocking, handled by broker goroutine)
 		messageBroker.Broadcast(msg)
 
-		// Return empty response (HTMX will clear the form)
+		// Return empty response; the chat:sent event clears the form
+		htmx.Trigger(w, "chat:sent")
 		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -191,9 +210,7 @@ func handleChatHistory(views *view.Engine) frameworkrouter.Handler {
 		// Get message history
 		messages := messageBroker.GetHistory(roomID, 50)
 
-		data := map[string]interface{}{
-			"Messages": messages,
-		}
+		data := chatHistoryData{Messages: messages}
 
 		// Render partial template
 		views.Render(w, "chat/messages", data)
@@ -276,76 +293,3 @@ func worker(ctx context.Context, id int, jobs <-chan Job, results chan<- Result)
 		}
 	}
 }
-
-// Rate Limiter Example using Token Bucket
-// Demonstrates: Concurrency patterns for rate limiting
-
-type RateLimiter struct {
-	tokens    chan struct{}
-	maxTokens int
-	refillInterval time.Duration
-	ctx       context.Context
-	cancel    context.CancelFunc
-}
-
-func NewRateLimiter(maxTokens int, refillInterval time.Duration) *RateLimiter {
-	ctx, cancel := context.WithCancel(context.Background())
-	rl := &RateLimiter{
-		tokens:         make(chan struct{}, maxTokens),
-		maxTokens:      maxTokens,
-		refillInterval: refillInterval,
-		ctx:            ctx,
-		cancel:         cancel,
-	}
-
-	// Fill initial tokens
-	for i := 0; i < maxTokens; i++ {
-		rl.tokens <- struct{}{}
-	}
-
-	// Start refill goroutine
-	go rl.refill()
-
-	return rl
-}
-
-func (rl *RateLimiter) refill() {
-	ticker := time.NewTicker(rl.refillInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			select {
-			case rl.tokens <- struct{}{}:
-				// Token added
-			default:
-				// Bucket full
-			}
-		case <-rl.ctx.Done():
-			return
-		}
-	}
-}
-
-func (rl *RateLimiter) Allow() bool {
-	select {
-	case <-rl.tokens:
-		return true
-	default:
-		return false
-	}
-}
-
-func (rl *RateLimiter) Wait(ctx context.Context) error {
-	select {
-	case <-rl.tokens:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-}
-
-func (rl *RateLimiter) Stop() {
-	rl.cancel()
-}
@@ -0,0 +1,119 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+)
+
+// EventCSPViolation is the security event recorded for each CSP violation report
+const EventCSPViolation = "csp_violation"
+
+// cspViolation is the subset of a violation report we keep. Browsers send it
+// either as {"csp-report": {...}} (report-uri, kebab-case keys) or as a
+// Reporting API batch [{"type": "csp-violation", "body": {...}}] (camelCase keys).
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	Disposition        string `json:"disposition"`
+}
+
+type cspReportingAPIBody struct {
+	DocumentURL        string `json:"documentURL"`
+	BlockedURL         string `json:"blockedURL"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	Disposition        string `json:"disposition"`
+}
+
+// RegisterCSPReportRoutes registers the endpoint browsers post Content-Security-Policy
+// violation reports to. Nothing is registered when reporting is off or the
+// report URI points to another host.
+func RegisterCSPReportRoutes(r *frameworkrouter.Router, cfg config.CSPConfig) {
+	if !cfg.Enabled || !strings.HasPrefix(cfg.ReportURI, "/") {
+		return
+	}
+
+	// POST /csp-report - browsers send reports without credentials or CSRF tokens
+	r.Handle("POST", cfg.ReportURI, handleCSPReport(), frameworkrouter.Public(), frameworkrouter.CSRFExempt())
+}
+
+// handleCSPReport records violation reports as security events
+func handleCSPReport() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		violations, err := decodeCSPReports(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ip := middleware.ClientIP(r)
+		for _, v := range violations {
+			detail := fmt.Sprintf("%s blocked on %s (%s)", v.EffectiveDirective, v.DocumentURI, v.Disposition)
+			if err := db.RecordSecurityEvent(r.Context(), EventCSPViolation, truncate(v.BlockedURI, 200), ip, truncate(detail, 500)); err != nil {
+				log.Printf("csp: failed to record violation: %v", err)
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// decodeCSPReports accepts both the report-uri and the Reporting API formats
+func decodeCSPReports(r *http.Request) ([]cspViolation, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		var batch []struct {
+			Type string              `json:"type"`
+			Body cspReportingAPIBody `json:"body"`
+		}
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return nil, err
+		}
+		var out []cspViolation
+		for _, report := range batch {
+			if report.Type != "csp-violation" {
+				continue
+			}
+			out = append(out, cspViolation{
+				DocumentURI:        report.Body.DocumentURL,
+				BlockedURI:         report.Body.BlockedURL,
+				EffectiveDirective: report.Body.EffectiveDirective,
+				Disposition:        report.Body.Disposition,
+			})
+		}
+		return out, nil
+	}
+
+	var report struct {
+		Report cspViolation `json:"csp-report"`
+	}
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, err
+	}
+	v := report.Report
+	if v.EffectiveDirective == "" {
+		v.EffectiveDirective = v.ViolatedDirective
+	}
+	return []cspViolation{v}, nil
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -9,7 +9,7 @@ import (
 
 // RegisterHomeRoutes registers the home page route
 func RegisterHomeRoutes(r *frameworkrouter.Router, views *view.Engine) {
-	r.Handle("GET", "/", handleHomePage(views))
+	r.Handle("GET", "/", handleHomePage(views), frameworkrouter.Public())
 }
 
 // handleHomePage renders the home page
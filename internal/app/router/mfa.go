@@ -0,0 +1,390 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/form"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/loginguard"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
+)
+
+const (
+	// mfaChallengeMinutes is how long a user has to complete the second login step
+	mfaChallengeMinutes = 5
+	// recoveryCodeCount is the number of recovery codes issued on enrollment
+	recoveryCodeCount = 10
+)
+
+// RegisterTwoFactorRoutes registers TOTP two-factor authentication routes
+func RegisterTwoFactorRoutes(r *frameworkrouter.Router, cfg config.SecurityConfig) {
+	// GET /api/v1/auth/2fa - Enrollment status (requires authentication)
+	r.Handle("GET", "/api/v1/auth/2fa", handleTwoFactorStatus())
+
+	// POST /api/v1/auth/2fa/setup - Start enrollment, returns secret and otpauth URI
+	r.Handle("POST", "/api/v1/auth/2fa/setup", handleTwoFactorSetup(cfg))
+
+	// POST /api/v1/auth/2fa/enable - Confirm enrollment with a code, returns recovery codes
+	r.Handle("POST", "/api/v1/auth/2fa/enable", handleTwoFactorEnable())
+
+	// POST /api/v1/auth/2fa/disable - Disable 2FA (requires a valid code)
+	r.Handle("POST", "/api/v1/auth/2fa/disable", handleTwoFactorDisable())
+
+	// POST /api/v1/auth/2fa/recovery-codes - Regenerate recovery codes (requires a valid code)
+	r.Handle("POST", "/api/v1/auth/2fa/recovery-codes", handleRecoveryCodesRegenerate())
+
+	// POST /api/v1/auth/2fa/verify - Second login step, exchanges a challenge for tokens
+	r.Handle("POST", "/api/v1/auth/2fa/verify", handleTwoFactorVerify(cfg), frameworkrouter.Public(), frameworkrouter.CSRFExempt(), frameworkrouter.RateLimit("auth"))
+}
+
+// handleTwoFactorStatus returns whether 2FA is enabled for the current user
+func handleTwoFactorStatus() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		enabled, err := db.IsTOTPEnabled(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load two-factor status"})
+			return
+		}
+
+		remaining := 0
+		if enabled {
+			remaining, _ = db.CountUnusedRecoveryCodes(r.Context(), userID)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"enabled":                  enabled,
+			"recovery_codes_remaining": remaining,
+		})
+	}
+}
+
+// handleTwoFactorSetup generates a new TOTP secret for the current user
+func handleTwoFactorSetup(cfg config.SecurityConfig) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		// A leaked key must not be able to take over or weaken the second factor
+		if middleware.GetAPIKey(r.Context()) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "Two-factor settings cannot be changed with an API key"})
+			return
+		}
+
+		user, err := db.GetUser(r.Context(), int(userID))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+
+		enabled, err := db.IsTOTPEnabled(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load two-factor status"})
+			return
+		}
+		if enabled {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+
+		secret, err := security.GenerateTOTPSecret()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate secret"})
+			return
+		}
+
+		if err := db.SavePendingTOTP(r.Context(), userID, secret); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save secret"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"secret":      secret,
+			"otpauth_uri": security.TOTPProvisioningURI(cfg.TOTPIssuer, user.Email, secret),
+		})
+	}
+}
+
+// handleTwoFactorEnable confirms enrollment and issues recovery codes
+func handleTwoFactorEnable() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		// A leaked key must not be able to take over or weaken the second factor
+		if middleware.GetAPIKey(r.Context()) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "Two-factor settings cannot be changed with an API key"})
+			return
+		}
+
+		var input models.TwoFactorCodeInput
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
+			return
+		}
+
+		totp, err := db.GetUserTOTP(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Start two-factor setup first"})
+			return
+		}
+		if totp.Enabled {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+
+		step, err := security.ValidateTOTP(totp.Secret, input.Code, time.Now(), 0)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid two-factor code"})
+			return
+		}
+
+		if err := db.EnableTOTP(r.Context(), userID, step); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to enable two-factor authentication"})
+			return
+		}
+
+		codes, err := issueRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate recovery codes"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"enabled":        true,
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// handleTwoFactorDisable removes 2FA after verifying a current code
+func handleTwoFactorDisable() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		// A leaked key must not be able to take over or weaken the second factor
+		if middleware.GetAPIKey(r.Context()) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "Two-factor settings cannot be changed with an API key"})
+			return
+		}
+
+		var input models.TwoFactorCodeInput
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
+			return
+		}
+
+		if err := verifySecondFactor(r.Context(), userID, input.Code); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid two-factor code"})
+			return
+		}
+
+		if err := db.DeleteUserTOTP(r.Context(), userID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+	}
+}
+
+// handleRecoveryCodesRegenerate replaces all recovery codes after verifying a current code
+func handleRecoveryCodesRegenerate() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		// A leaked key must not be able to take over or weaken the second factor
+		if middleware.GetAPIKey(r.Context()) != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "Two-factor settings cannot be changed with an API key"})
+			return
+		}
+
+		var input models.TwoFactorCodeInput
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
+			return
+		}
+
+		if err := verifySecondFactor(r.Context(), userID, input.Code); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid two-factor code"})
+			return
+		}
+
+		codes, err := issueRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate recovery codes"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+	}
+}
+
+// handleTwoFactorVerify completes a login that requires a second factor
+func handleTwoFactorVerify(cfg config.SecurityConfig) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var input models.TwoFactorVerifyInput
+		if err := form.Bind(r, params, &input); err != nil {
+			writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
+			return
+		}
+
+		user, err := userFromChallenge(r.Context(), cfg, input.ChallengeToken)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid or expired two-factor challenge"})
+			return
+		}
+
+		// Failed codes count against the same budget as failed passwords
+		ip := middleware.ClientIP(r)
+		if err := loginguard.Check(r.Context(), user.Email, ip); err != nil {
+			writeLoginThrottled(w, err)
+			return
+		}
+
+		if err := verifySecondFactor(r.Context(), user.ID, input.Code); err != nil {
+			loginguard.RecordFailure(r.Context(), user.Email, ip)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid two-factor code"})
+			return
+		}
+		loginguard.RecordSuccess(r.Context(), user.Email)
+
+		writeLoginTokens(w, cfg, user)
+	}
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code
+func verifySecondFactor(ctx context.Context, userID int64, code string) error {
+	totp, err := db.GetUserTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !totp.Enabled {
+		return security.ErrInvalidTOTPCode
+	}
+
+	// Anything longer than a TOTP code is treated as a recovery code
+	if len(strings.TrimSpace(code)) > security.TOTPDigits {
+		if err := db.UseRecoveryCode(ctx, userID, security.HashRecoveryCode(code)); err != nil {
+			return security.ErrInvalidTOTPCode
+		}
+		return nil
+	}
+
+	step, err := security.ValidateTOTP(totp.Secret, code, time.Now(), totp.LastUsedStep)
+	if err != nil {
+		return err
+	}
+	if err := db.MarkTOTPStepUsed(ctx, userID, step); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return security.ErrTOTPCodeReused
+		}
+		return err
+	}
+	return nil
+}
+
+// issueRecoveryCodes generates new recovery codes, stores their hashes and returns the plaintext once
+func issueRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes, err := security.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = security.HashRecoveryCode(code)
+	}
+
+	if err := db.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// userFromChallenge resolves the active user a two-factor challenge token was issued for
+func userFromChallenge(ctx context.Context, cfg config.SecurityConfig, token string) (models.User, error) {
+	sub, err := security.ParseMFAChallenge(cfg.JWTSecret, token)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		return models.User{}, security.ErrInvalidChallenge
+	}
+
+	user, err := db.GetUser(ctx, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !user.IsActive {
+		return models.User{}, security.ErrInvalidChallenge
+	}
+	return user, nil
+}
+
+// writeLoginTokens issues an access/refresh token pair for a fully authenticated user
+func writeLoginTokens(w http.ResponseWriter, cfg config.SecurityConfig, user models.User) {
+	accessToken, refreshToken, err := security.CreateTokenPair(
+		cfg.JWTSecret,
+		fmt.Sprintf("%d", user.ID),
+		user.Role,
+		cfg.AccessTokenMinutes,
+		cfg.RefreshTokenMinutes,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate tokens"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user":          user,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+	})
+}
+
+// currentUserID returns the authenticated user's ID from JWT claims
+func currentUserID(r *http.Request) (int64, bool) {
+	claims := middleware.GetClaims(r.Context())
+	if claims == nil {
+		return 0, false
+	}
+
+	userID, err := strconv.ParseInt(claims.Sub, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
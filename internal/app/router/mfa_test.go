@@ -0,0 +1,68 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/apikey"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+)
+
+// testDB opens a fresh database for the test
+func testDB(t *testing.T) {
+	t.Helper()
+	err := db.Init(config.DatabaseConfig{
+		Driver:       "sqlite3",
+		DSN:          filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+}
+
+// A leaked API key, even a write-scoped one, can't change the second factor
+func TestTwoFactorRefusesAPIKeys(t *testing.T) {
+	testDB(t)
+	apikey.Configure(config.APIKeysConfig{Enabled: true})
+	t.Cleanup(func() { apikey.Configure(config.APIKeysConfig{}) })
+
+	user, err := db.CreateUser(context.Background(), models.RegisterInput{Name: "Ana", Email: "ana@example.com", Role: "user"}, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := apikey.Create(context.Background(), user.ID, "ci", []string{"write"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.SecurityConfig{EnableJWT: true, JWTSecret: "test-secret"}
+	r := frameworkrouter.New()
+	r.Use(middleware.JWTAuthMiddleware(cfg))
+	RegisterTwoFactorRoutes(r, cfg)
+
+	for _, path := range []string{
+		"/api/v1/auth/2fa/setup",
+		"/api/v1/auth/2fa/enable",
+		"/api/v1/auth/2fa/disable",
+		"/api/v1/auth/2fa/recovery-codes",
+	} {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"code": "123456"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("POST %s with an API key = %d, want 403: %s", path, rec.Code, rec.Body)
+		}
+	}
+}
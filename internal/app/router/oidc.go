@@ -0,0 +1,380 @@
+package router
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/oidc"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+const (
+	// oidcFlowCookie carries the signed state of an in-flight provider login
+	oidcFlowCookie = "oidc_flow"
+	// oidcFlowMinutes is how long a user has to complete the provider login
+	oidcFlowMinutes = 10
+)
+
+var (
+	errOIDCNoAccount  = errors.New("no account linked to this identity")
+	errOIDCEmailInUse = errors.New("email belongs to an existing account")
+)
+
+// oidcFlow is the state kept between the redirect to the provider and the callback
+type oidcFlow struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	LinkUser int64  `json:"u,omitempty"` // set when linking to a signed-in user
+	ReturnTo string `json:"r,omitempty"`
+}
+
+// RegisterOIDCRoutes registers external identity provider (OpenID Connect) routes
+func RegisterOIDCRoutes(r *frameworkrouter.Router, cfg config.SecurityConfig, views *view.Engine) {
+	// GET /auth/oidc/{provider} - start login with a provider
+	r.Handle("GET", "/auth/oidc/{provider}", handleOIDCStart(cfg, views), frameworkrouter.Public())
+
+	// GET /auth/oidc/{provider}/callback - provider redirects back here
+	r.Handle("GET", "/auth/oidc/{provider}/callback", handleOIDCCallback(cfg, views), frameworkrouter.Public())
+
+	// GET /api/v1/auth/identities - list linked identities (requires authentication)
+	r.Handle("GET", "/api/v1/auth/identities", handleIdentitiesList())
+
+	// POST /api/v1/auth/identities/{provider} - start linking a provider (requires authentication)
+	r.Handle("POST", "/api/v1/auth/identities/{provider}", handleIdentityLink(cfg))
+
+	// DELETE /api/v1/auth/identities/{provider} - unlink a provider (requires authentication)
+	r.Handle("DELETE", "/api/v1/auth/identities/{provider}", handleIdentityUnlink())
+}
+
+// handleOIDCStart redirects the browser to the provider's authorization endpoint
+func handleOIDCStart(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		p, ok := oidc.Get(params["provider"])
+		if !ok {
+			renderLoginError(w, views, cfg, "Unknown sign-in provider")
+			return
+		}
+
+		authURL, err := startOIDCFlow(r.Context(), w, cfg, p, 0, "")
+		if err != nil {
+			log.Printf("OIDC start failed for %s: %v", p.Name(), err)
+			renderLoginError(w, views, cfg, fmt.Sprintf("Sign-in with %s is currently unavailable", p.DisplayName()))
+			return
+		}
+
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// handleOIDCCallback completes a provider login or link
+func handleOIDCCallback(cfg config.SecurityConfig, views *view.Engine) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		p, ok := oidc.Get(params["provider"])
+		if !ok {
+			renderLoginError(w, views, cfg, "Unknown sign-in provider")
+			return
+		}
+
+		// The flow cookie is single-use
+		flow, err := readOIDCFlow(r, cfg)
+		clearOIDCFlowCookie(w)
+
+		q := r.URL.Query()
+		if err != nil || flow.Provider != p.Name() || subtle.ConstantTimeCompare([]byte(flow.State), []byte(q.Get("state"))) != 1 {
+			renderLoginError(w, views, cfg, "Your sign-in session expired, please try again")
+			return
+		}
+		if q.Get("error") != "" {
+			renderLoginError(w, views, cfg, fmt.Sprintf("Sign-in with %s was cancelled", p.DisplayName()))
+			return
+		}
+
+		tok, err := p.Exchange(r.Context(), q.Get("code"), flow.Verifier)
+		if err != nil {
+			log.Printf("OIDC code exchange failed for %s: %v", p.Name(), err)
+			renderLoginError(w, views, cfg, fmt.Sprintf("Sign-in with %s failed", p.DisplayName()))
+			return
+		}
+		id, err := p.VerifyIDToken(r.Context(), tok.IDToken, flow.Nonce)
+		if err != nil {
+			log.Printf("OIDC ID token rejected for %s: %v", p.Name(), err)
+			renderLoginError(w, views, cfg, fmt.Sprintf("Sign-in with %s failed", p.DisplayName()))
+			return
+		}
+
+		// Linking a provider to the signed-in account
+		if flow.LinkUser != 0 {
+			if err := db.LinkIdentity(r.Context(), flow.LinkUser, p.Name(), id.Subject, id.Email); err != nil {
+				if errors.Is(err, db.ErrIdentityLinked) {
+					views.RenderError(w, http.StatusConflict, fmt.Sprintf("This %s account is already linked to another user", p.DisplayName()))
+					return
+				}
+				views.RenderError(w, http.StatusInternalServerError, "Failed to link account")
+				return
+			}
+			http.Redirect(w, r, flow.ReturnTo, http.StatusSeeOther)
+			return
+		}
+
+		user, err := userForIdentity(r.Context(), p, id)
+		switch {
+		case errors.Is(err, errOIDCEmailInUse):
+			renderLoginError(w, views, cfg, fmt.Sprintf("An account with this email already exists. Sign in with your password, then link %s to your account.", p.DisplayName()))
+			return
+		case errors.Is(err, errOIDCNoAccount):
+			renderLoginError(w, views, cfg, fmt.Sprintf("No account is linked to this %s identity", p.DisplayName()))
+			return
+		case err != nil:
+			log.Printf("OIDC account mapping failed for %s: %v", p.Name(), err)
+			renderLoginError(w, views, cfg, fmt.Sprintf("Sign-in with %s failed", p.DisplayName()))
+			return
+		}
+
+		completeBrowserLogin(w, r, cfg, views, user)
+	}
+}
+
+// userForIdentity maps a verified external identity onto a local user: an existing
+// link wins, then (if allowed) a user with the same verified email, then (if allowed)
+// a newly created user without a local password
+func userForIdentity(ctx context.Context, p *oidc.Provider, id *oidc.IDToken) (models.User, error) {
+	user, err := db.GetUserByIdentity(ctx, p.Name(), id.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if err != db.ErrNotFound {
+		return models.User{}, err
+	}
+
+	if id.Email != "" {
+		existing, _, err := db.GetUserByEmail(ctx, id.Email)
+		if err == nil {
+			if !p.LinkByEmail() || !id.EmailVerified {
+				return models.User{}, errOIDCEmailInUse
+			}
+			if err := db.LinkIdentity(ctx, existing.ID, p.Name(), id.Subject, id.Email); err != nil {
+				return models.User{}, err
+			}
+			return existing, nil
+		}
+		if err != db.ErrNotFound {
+			return models.User{}, err
+		}
+	}
+
+	if !p.AllowSignup() || id.Email == "" {
+		return models.User{}, errOIDCNoAccount
+	}
+
+	name := id.Name
+	if name == "" {
+		name = strings.Split(id.Email, "@")[0]
+	}
+
+	// An empty password hash never matches, so the account can only sign in through the provider
+	user, err = db.CreateUser(ctx, models.RegisterInput{Name: name, Email: id.Email, Role: "user"}, "")
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := db.LinkIdentity(ctx, user.ID, p.Name(), id.Subject, id.Email); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// handleIdentitiesList returns the current user's linked identities and the available providers
+func handleIdentitiesList() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		identities, err := db.ListUserIdentities(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load identities"})
+			return
+		}
+		if identities == nil {
+			identities = []db.UserIdentity{}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"identities": identities,
+			"providers":  oidc.Providers(),
+		})
+	}
+}
+
+// handleIdentityLink starts linking a provider to the current user.
+// The client must navigate the browser to the returned authorization_url.
+func handleIdentityLink(cfg config.SecurityConfig) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		p, ok := oidc.Get(params["provider"])
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Unknown provider"})
+			return
+		}
+
+		var input struct {
+			ReturnTo string `json:"return_to"`
+		}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+				return
+			}
+		}
+
+		authURL, err := startOIDCFlow(r.Context(), w, cfg, p, userID, input.ReturnTo)
+		if err != nil {
+			log.Printf("OIDC link start failed for %s: %v", p.Name(), err)
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "Provider is currently unavailable"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"authorization_url": authURL})
+	}
+}
+
+// handleIdentityUnlink removes a linked provider, refusing to remove the last way to sign in
+func handleIdentityUnlink() frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		userID, ok := currentUserID(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		passwordHash, err := db.GetPasswordHash(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+		identities, err := db.ListUserIdentities(r.Context(), userID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load identities"})
+			return
+		}
+		if passwordHash == "" && len(identities) <= 1 {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "Link another provider before removing your only way to sign in"})
+			return
+		}
+
+		if err := db.UnlinkIdentity(r.Context(), userID, params["provider"]); err != nil {
+			if err == db.ErrNotFound {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "Provider is not linked"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to unlink provider"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Provider unlinked"})
+	}
+}
+
+// startOIDCFlow stores a fresh state/nonce/PKCE verifier in the flow cookie and
+// returns the provider authorization URL
+func startOIDCFlow(ctx context.Context, w http.ResponseWriter, cfg config.SecurityConfig, p *oidc.Provider, linkUser int64, returnTo string) (string, error) {
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		return "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(oidcFlow{
+		Provider: p.Name(),
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		LinkUser: linkUser,
+		ReturnTo: safeReturnTo(returnTo),
+	})
+	if err != nil {
+		return "", err
+	}
+	token, err := security.GenerateOIDCStateToken(cfg.JWTSecret, string(payload), oidcFlowMinutes)
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    token,
+		Path:     "/auth/oidc/",
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode, // must be sent on the redirect back from the provider
+		MaxAge:   oidcFlowMinutes * 60,
+	})
+	return authURL, nil
+}
+
+func readOIDCFlow(r *http.Request, cfg config.SecurityConfig) (oidcFlow, error) {
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		return oidcFlow{}, err
+	}
+	payload, err := security.ParseOIDCStateToken(cfg.JWTSecret, cookie.Value)
+	if err != nil {
+		return oidcFlow{}, err
+	}
+	var flow oidcFlow
+	if err := json.Unmarshal([]byte(payload), &flow); err != nil {
+		return oidcFlow{}, err
+	}
+	return flow, nil
+}
+
+func clearOIDCFlowCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    "",
+		Path:     "/auth/oidc/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// safeReturnTo only allows local paths so the callback can't be used as an open redirect
+func safeReturnTo(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	gobastion "github.com/AlejandroMBJS/goBastion"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/admin"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/docs"
+	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/static"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+// RegisterAll registers every framework and application route.
+//
+// It is shared by cmd/server, `go-bastion serve` and `go-bastion routes`, so
+// the route table printed by the CLI is exactly the one being served. Add your
+// own Register*Routes calls at the end.
+func RegisterAll(r *frameworkrouter.Router, cfg *config.Config, views *view.Engine) {
+	// Home page
+	RegisterHomeRoutes(r, views)
+
+	// JSON API, called cross-origin with bearer tokens
+	api := r.Group("", frameworkrouter.CORS("api"))
+	RegisterAuthRoutes(api, cfg.Security)
+	RegisterTwoFactorRoutes(api, cfg.Security)
+	RegisterUserRoutes(api)
+	RegisterAPIKeyRoutes(api)
+
+	// HTML authentication
+	RegisterAuthViewsRoutes(r, cfg.Security, views)
+	RegisterOIDCRoutes(r, cfg.Security, views)
+
+	// Admin panel
+	admin.RegisterRoutes(r, views, cfg.Security)
+
+	// Chat example (SSE + HTMX)
+	RegisterChatRoutes(r, views)
+
+	// Content-Security-Policy violation reports
+	RegisterCSPReportRoutes(r, cfg.Security.Headers.CSP)
+
+	// API documentation
+	docs.RegisterRoutes(r)
+
+	// Static files, embedded in builds with -tags embed. Templates link to
+	// them with @asset("css/output.css"), which adds a content hash.
+	dev := cfg.App.Environment == "development"
+	assets := static.New(gobastion.Static(dev), "/static/")
+	assets.SetDevMode(dev)
+	if views != nil {
+		views.AddFunc("asset", assets.URL)
+	}
+	r.GET("/static/{path...}", frameworkrouter.WrapHandler(assets), frameworkrouter.Public(), frameworkrouter.Unlocalized())
+	r.Handle("HEAD", "/static/{path...}", frameworkrouter.WrapHandler(assets), frameworkrouter.Public(), frameworkrouter.Unlocalized())
+}
@@ -1,15 +1,14 @@
 package router
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/AlejandroMBJS/goBastion/internal/app/models"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/form"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // RegisterUserRoutes registers user CRUD routes
@@ -49,14 +48,8 @@ func handleListUsers(w http.ResponseWriter, r *http.Request, params map[string]s
 // handleCreateUser creates a new user
 func handleCreateUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	var input models.RegisterInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
-		return
-	}
-
-	// Validate input
-	if err := input.Validate(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if err := form.Bind(r, params, &input); err != nil {
+		writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
 		return
 	}
 
@@ -67,16 +60,15 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request, params map[string]
 		return
 	}
 
-	// Hash password (using bcrypt)
-	// Note: In production, you might want to use a stronger password policy
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	// Hash password (bcrypt, cost from security.bcrypt_cost)
+	passwordHash, err := passwords.Hash(input.Password)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
 		return
 	}
 
 	// Create user
-	user, err := db.CreateUser(r.Context(), input, string(passwordHash))
+	user, err := db.CreateUser(r.Context(), input, passwordHash)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
 		return
@@ -115,14 +107,8 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request, params map[string]
 	}
 
 	var input models.UserInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
-		return
-	}
-
-	// Validate input
-	if err := input.Validate(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if err := form.Bind(r, params, &input); err != nil {
+		writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
 		return
 	}
 
@@ -2,54 +2,71 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/apikey"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/form"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/loginguard"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
-	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-// Store full config for dashboard metrics
-var fullConfig *config.Config
+// Store full config for dashboard metrics (swapped when the config is reloaded)
+var fullConfig atomic.Pointer[config.Config]
 
-// RegisterRoutes registers admin routes with CSRF protection
+// RegisterRoutes registers admin routes (form posts are checked by the CSRF middleware)
 func RegisterRoutes(r *frameworkrouter.Router, views *view.Engine, cfg config.SecurityConfig) {
 	// Admin routes require authentication and admin role
-	adminAuth := middleware.RequireRole("admin")
+	adminOnly := frameworkrouter.RequireRole("admin")
 
 	// GET /admin - Dashboard
-	r.Handle("GET", "/admin", adminAuth(handleDashboard(views, cfg)))
+	r.Handle("GET", "/admin", handleDashboard(views, cfg), adminOnly)
 
 	// GET /admin/users - List users
-	r.Handle("GET", "/admin/users", adminAuth(handleUsersList(views, cfg)))
+	r.Handle("GET", "/admin/users", handleUsersList(views, cfg), adminOnly)
 
 	// GET /admin/users/new - Create new user form
-	r.Handle("GET", "/admin/users/new", adminAuth(handleUserNew(views, cfg)))
+	r.Handle("GET", "/admin/users/new", handleUserNew(views, cfg), adminOnly)
 
 	// POST /admin/users/new - Create new user
-	r.Handle("POST", "/admin/users/new", adminAuth(handleUserCreate(views, cfg)))
+	r.Handle("POST", "/admin/users/new", handleUserCreate(views, cfg), adminOnly)
 
 	// GET /admin/users/{id} - View/edit user
-	r.Handle("GET", "/admin/users/{id}", adminAuth(handleUserDetail(views, cfg)))
+	r.Handle("GET", "/admin/users/{id}", handleUserDetail(views, cfg), adminOnly)
 
 	// POST /admin/users/{id} - Update user
-	r.Handle("POST", "/admin/users/{id}", adminAuth(handleUserUpdate(views, cfg)))
+	r.Handle("POST", "/admin/users/{id}", handleUserUpdate(views, cfg), adminOnly)
 
 	// POST /admin/users/{id}/delete - Delete user
-	r.Handle("POST", "/admin/users/{id}/delete", adminAuth(handleUserDelete(views, cfg)))
+	r.Handle("POST", "/admin/users/{id}/delete", handleUserDelete(views, cfg), adminOnly)
+
+	// POST /admin/users/{id}/2fa/reset - Reset user's two-factor authentication
+	r.Handle("POST", "/admin/users/{id}/2fa/reset", handleUserTwoFactorReset(cfg), adminOnly)
+
+	// POST /admin/users/{id}/unlock - clear a login lockout
+	r.Handle("POST", "/admin/users/{id}/unlock", handleUserUnlock(cfg), adminOnly)
+
+	// POST /admin/users/{id}/api-keys/{keyID}/revoke - Revoke a user's API key
+	r.Handle("POST", "/admin/users/{id}/api-keys/{keyID}/revoke", handleUserAPIKeyRevoke(cfg), adminOnly)
 }
 
-// SetFullConfig stores the full configuration for metrics display
+// SetFullConfig stores the full configuration for metrics display. It is a
+// config.Reloader subscriber, so the dashboard follows reloads.
 func SetFullConfig(cfg *config.Config) {
-	fullConfig = cfg
+	fullConfig.Store(cfg)
 }
 
 // handleDashboard renders the admin dashboard
@@ -65,9 +82,11 @@ func handleDashboard(views *view.Engine, cfg config.SecurityConfig) frameworkrou
 		totalUsers, _ := db.CountWhere(r.Context(), "users", map[string]any{})
 		adminUsers, _ := db.CountWhere(r.Context(), "users", map[string]any{"role": "admin"})
 		activeUsers, _ := db.CountWhere(r.Context(), "users", map[string]any{"is_active": true})
+		lockedKeys, _ := db.ListLockedLoginKeys(r.Context(), time.Now())
 
 		// Get environment from config
 		environment := "production"
+		fullConfig := fullConfig.Load()
 		if fullConfig != nil {
 			environment = fullConfig.App.Environment
 		}
@@ -78,6 +97,7 @@ func handleDashboard(views *view.Engine, cfg config.SecurityConfig) frameworkrou
 			"AdminUsers":   adminUsers,
 			"ActiveUsers":  activeUsers,
 			"RegularUsers": totalUsers - adminUsers,
+			"LockedKeys":   len(lockedKeys),
 		}
 
 		// Build system config data
@@ -88,29 +108,70 @@ func handleDashboard(views *view.Engine, cfg config.SecurityConfig) frameworkrou
 			"DatabaseDriver":  "SQLite", // Default
 			"RateLimiting":    false,
 			"RequestsPerMin":  0,
+			"LoginProtection": false,
 		}
 
 		if fullConfig != nil {
 			systemConfig["DatabaseDriver"] = fullConfig.Database.Driver
 			systemConfig["RateLimiting"] = fullConfig.RateLimit.Enabled
 			systemConfig["RequestsPerMin"] = fullConfig.RateLimit.RequestsPerMinute
-		}
+			systemConfig["LoginProtection"] = fullConfig.LoginProtection.Enabled
+		}
+
+		// Recent lockout/unlock events, loaded while the top of the page is sent
+		securityEvents := view.Async(func() ([]SecurityEventRow, error) {
+			events, _ := db.ListSecurityEvents(r.Context(), 10)
+			rows := make([]SecurityEventRow, len(events))
+			for i, e := range events {
+				rows[i] = SecurityEventRow{
+					EventType: e.EventType,
+					Subject:   e.Subject,
+					IP:        e.IP,
+					Detail:    e.Detail,
+					CreatedAt: e.CreatedAt.Format("2006-01-02 15:04:05"),
+				}
+			}
+			return rows, nil
+		})
 
 		data := map[string]any{
-			"Title":        "Admin Dashboard",
-			"UserName":     claims.Sub,
-			"CSRFToken":    generateAndSetCSRFToken(w, cfg),
-			"Metrics":      metrics,
-			"SystemConfig": systemConfig,
+			"Title":          "Admin Dashboard",
+			"UserName":       claims.Sub,
+			"Metrics":        metrics,
+			"SystemConfig":   systemConfig,
+			"SecurityEvents": securityEvents,
 		}
 
-		if err := views.Render(w, "admin/dashboard", data); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Streamed, so the response may have started when an error occurs
+		if err := views.Stream(w, "admin/dashboard", data); err != nil {
+			log.Printf("admin: failed to render the dashboard: %v", err)
 		}
 	}
 }
 
-// UserRow wraps a user with CSRF token for template rendering
+// SecurityEventRow is a security event formatted for template rendering
+type SecurityEventRow struct {
+	EventType string
+	Subject   string
+	IP        string
+	Detail    string
+	CreatedAt string
+}
+
+// APIKeyRow is an API key formatted for template rendering
+type APIKeyRow struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	Prefix    string
+	Scopes    string
+	ExpiresAt string
+	LastUsed  string
+	Status    string
+	Active    bool
+}
+
+// UserRow is a user formatted for template rendering
 type UserRow struct {
 	ID          int64
 	Email       string
@@ -119,7 +180,6 @@ type UserRow struct {
 	IsActive    bool
 	IsStaff     bool
 	IsSuperuser bool
-	CSRFToken   string
 }
 
 // handleUsersList renders the users list page
@@ -131,9 +191,7 @@ func handleUsersList(views *view.Engine, cfg config.SecurityConfig) frameworkrou
 			return
 		}
 
-		csrfToken := generateAndSetCSRFToken(w, cfg)
-
-		// Convert users to UserRow with CSRF token
+		// Convert users to UserRow
 		userRows := make([]UserRow, len(users))
 		for i, user := range users {
 			userRows[i] = UserRow{
@@ -144,14 +202,12 @@ func handleUsersList(views *view.Engine, cfg config.SecurityConfig) frameworkrou
 				IsActive:    user.IsActive,
 				IsStaff:     user.IsStaff,
 				IsSuperuser: user.IsSuperuser,
-				CSRFToken:   csrfToken,
 			}
 		}
 
 		data := map[string]any{
-			"Title":     "Users",
-			"Users":     userRows,
-			"CSRFToken": csrfToken,
+			"Title": "Users",
+			"Users": userRows,
 		}
 
 		if err := views.Render(w, "admin/users_list", data); err != nil {
@@ -179,10 +235,25 @@ func handleUserDetail(views *view.Engine, cfg config.SecurityConfig) frameworkro
 			return
 		}
 
+		twoFactorEnabled, _ := db.IsTOTPEnabled(r.Context(), user.ID)
+
+		lockedUntil := ""
+		if until, err := loginguard.LockedUntil(r.Context(), user.Email); err == nil && !until.IsZero() {
+			lockedUntil = until.Format("2006-01-02 15:04:05")
+		}
+
+		keys, _ := db.ListAPIKeys(r.Context(), user.ID)
+		keyRows := make([]APIKeyRow, len(keys))
+		for i, k := range keys {
+			keyRows[i] = apiKeyRow(k, time.Now())
+		}
+
 		data := map[string]any{
-			"Title":     fmt.Sprintf("Edit User: %s", user.Name),
-			"User":      user,
-			"CSRFToken": generateAndSetCSRFToken(w, cfg),
+			"Title":            fmt.Sprintf("Edit User: %s", user.Name),
+			"User":             user,
+			"TwoFactorEnabled": twoFactorEnabled,
+			"LockedUntil":      lockedUntil,
+			"APIKeys":          keyRows,
 		}
 
 		if err := views.Render(w, "admin/user_detail", data); err != nil {
@@ -200,59 +271,34 @@ func handleUserUpdate(views *view.Engine, cfg config.SecurityConfig) frameworkro
 			return
 		}
 
-		// Parse form data
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Failed to parse form", http.StatusBadRequest)
-			return
-		}
-
 		// Check if it's a JSON request (from API)
 		contentType := r.Header.Get("Content-Type")
 		if contentType == "application/json" {
-			handleUserUpdateJSON(w, r, id)
+			handleUserUpdateJSON(w, r, params, id)
 			return
 		}
 
-		// Validate CSRF token for form submissions
-		if !validateCSRFFromForm(r, cfg) {
-			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		var input userForm
+		if err := form.Bind(r, params, &input); err != nil {
+			http.Error(w, form.Message(r.Context(), err), http.StatusBadRequest)
 			return
 		}
 
-		// Handle form submission
-		name := r.FormValue("name")
-		email := r.FormValue("email")
-		role := r.FormValue("role")
-		isStaff := r.FormValue("is_staff") == "on"
-		isSuperuser := r.FormValue("is_superuser") == "on"
-		isActive := r.FormValue("is_active") == "on"
-
 		// Update basic user info
-		input := models.UserInput{
-			Name:  name,
-			Email: email,
-			Role:  role,
-		}
-
-		if err := input.Validate(); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		_, err = db.UpdateUser(r.Context(), id, input)
+		_, err = db.UpdateUser(r.Context(), id, input.UserInput)
 		if err != nil {
 			http.Error(w, "Failed to update user", http.StatusInternalServerError)
 			return
 		}
 
 		// Update admin fields
-		if err := db.UpdateUserAdmin(r.Context(), id, isStaff, isSuperuser); err != nil {
+		if err := db.UpdateUserAdmin(r.Context(), id, input.IsStaff, input.IsSuperuser); err != nil {
 			http.Error(w, "Failed to update admin fields", http.StatusInternalServerError)
 			return
 		}
 
 		// Update active status
-		if err := db.UpdateUserActive(r.Context(), id, isActive); err != nil {
+		if err := db.UpdateUserActive(r.Context(), id, input.IsActive); err != nil {
 			http.Error(w, "Failed to update active status", http.StatusInternalServerError)
 			return
 		}
@@ -262,34 +308,17 @@ func handleUserUpdate(views *view.Engine, cfg config.SecurityConfig) frameworkro
 	}
 }
 
-// handleUserUpdateJSON handles JSON API updates
-func handleUserUpdateJSON(w http.ResponseWriter, r *http.Request, id int) {
-	var input struct {
-		Name        string `json:"name"`
-		Email       string `json:"email"`
-		Role        string `json:"role"`
-		IsStaff     bool   `json:"is_staff"`
-		IsSuperuser bool   `json:"is_superuser"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+// handleUserUpdateJSON handles JSON API updates, which leave the active
+// status alone
+func handleUserUpdateJSON(w http.ResponseWriter, r *http.Request, params map[string]string, id int) {
+	var input userForm
+	if err := form.Bind(r, params, &input); err != nil {
+		writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
 		return
 	}
 
 	// Update basic user info
-	userInput := models.UserInput{
-		Name:  input.Name,
-		Email: input.Email,
-		Role:  input.Role,
-	}
-
-	if err := userInput.Validate(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-
-	user, err := db.UpdateUser(r.Context(), id, userInput)
+	user, err := db.UpdateUser(r.Context(), id, input.UserInput)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update user"})
 		return
@@ -315,9 +344,8 @@ func handleUserUpdateJSON(w http.ResponseWriter, r *http.Request, id int) {
 func handleUserNew(views *view.Engine, cfg config.SecurityConfig) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		data := map[string]any{
-			"Title":     "Create New User",
-			"Error":     "",
-			"CSRFToken": generateAndSetCSRFToken(w, cfg),
+			"Title": "Create New User",
+			"Form":  form.Values(url.Values{"role": {"user"}, "is_active": {"on"}}),
 		}
 
 		if err := views.Render(w, "admin/user_new", data); err != nil {
@@ -329,70 +357,43 @@ func handleUserNew(views *view.Engine, cfg config.SecurityConfig) frameworkroute
 // handleUserCreate creates a new user
 func handleUserCreate(views *view.Engine, cfg config.SecurityConfig) frameworkrouter.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-		// Parse form data
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Failed to parse form", http.StatusBadRequest)
-			return
-		}
-
-		// Validate CSRF token
-		if !validateCSRFFromForm(r, cfg) {
-			http.Error(w, "CSRF token invalid", http.StatusForbidden)
-			return
-		}
-
-		name := r.FormValue("name")
-		email := r.FormValue("email")
-		password := r.FormValue("password")
-		role := r.FormValue("role")
-		isStaff := r.FormValue("is_staff") == "on"
-		isSuperuser := r.FormValue("is_superuser") == "on"
-		isActive := r.FormValue("is_active") == "on"
-
-		// Validate input
-		input := models.RegisterInput{
-			Name:     name,
-			Email:    email,
-			Password: password,
-			Role:     role,
-		}
-
-		if err := input.Validate(); err != nil {
-			renderUserNewError(w, views, cfg, err.Error(), name, email, role)
+		var input newUserForm
+		if err := form.Bind(r, params, &input); err != nil {
+			renderUserNewError(w, r, views, err)
 			return
 		}
 
 		// Check if user already exists
-		_, _, err := db.GetUserByEmail(r.Context(), email)
+		_, _, err := db.GetUserByEmail(r.Context(), input.Email)
 		if err == nil {
-			renderUserNewError(w, views, cfg, "User with this email already exists", name, email, role)
+			renderUserNewError(w, r, views, form.Invalid("email", errors.New("User with this email already exists")))
 			return
 		}
 
 		// Hash password
-		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		passwordHash, err := passwords.Hash(input.Password)
 		if err != nil {
-			renderUserNewError(w, views, cfg, "Failed to hash password", name, email, role)
+			renderUserNewError(w, r, views, errors.New("Failed to hash password"))
 			return
 		}
 
 		// Create user
-		user, err := db.CreateUser(r.Context(), input, string(passwordHash))
+		user, err := db.CreateUser(r.Context(), input.RegisterInput, passwordHash)
 		if err != nil {
-			renderUserNewError(w, views, cfg, "Failed to create user", name, email, role)
+			renderUserNewError(w, r, views, errors.New("Failed to create user"))
 			return
 		}
 
 		// Update admin fields
-		if err := db.UpdateUserAdmin(r.Context(), int(user.ID), isStaff, isSuperuser); err != nil {
-			renderUserNewError(w, views, cfg, "Failed to set admin permissions", name, email, role)
+		if err := db.UpdateUserAdmin(r.Context(), int(user.ID), input.IsStaff, input.IsSuperuser); err != nil {
+			renderUserNewError(w, r, views, errors.New("Failed to set admin permissions"))
 			return
 		}
 
 		// Update active status if needed
-		if !isActive {
-			if err := db.UpdateUserActive(r.Context(), int(user.ID), isActive); err != nil {
-				renderUserNewError(w, views, cfg, "Failed to set active status", name, email, role)
+		if !input.IsActive {
+			if err := db.UpdateUserActive(r.Context(), int(user.ID), input.IsActive); err != nil {
+				renderUserNewError(w, r, views, errors.New("Failed to set active status"))
 				return
 			}
 		}
@@ -411,18 +412,6 @@ func handleUserDelete(views *view.Engine, cfg config.SecurityConfig) frameworkro
 			return
 		}
 
-		// Parse form to get CSRF token
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Failed to parse form", http.StatusBadRequest)
-			return
-		}
-
-		// Validate CSRF token
-		if !validateCSRFFromForm(r, cfg) {
-			http.Error(w, "CSRF token invalid", http.StatusForbidden)
-			return
-		}
-
 		// Delete user
 		err = db.DeleteUser(r.Context(), id)
 		if err != nil {
@@ -439,63 +428,148 @@ func handleUserDelete(views *view.Engine, cfg config.SecurityConfig) frameworkro
 	}
 }
 
-// renderUserNewError renders the create user form with an error
-func renderUserNewError(w http.ResponseWriter, views *view.Engine, cfg config.SecurityConfig, errorMsg, name, email, role string) {
-	data := map[string]any{
-		"Title":     "Create New User",
-		"Error":     errorMsg,
-		"Name":      name,
-		"Email":     email,
-		"Role":      role,
-		"CSRFToken": generateAndSetCSRFToken(w, cfg),
-	}
+// handleUserTwoFactorReset removes a user's TOTP enrollment and recovery codes
+func handleUserTwoFactorReset(cfg config.SecurityConfig) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := strconv.Atoi(params["id"])
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
 
-	w.WriteHeader(http.StatusBadRequest)
-	if err := views.Render(w, "admin/user_new", data); err != nil {
-		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		if err := db.DeleteUserTOTP(r.Context(), int64(id)); err != nil {
+			http.Error(w, "Failed to reset two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+
+		// Redirect back to user detail
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", id), http.StatusSeeOther)
 	}
 }
 
-func writeJSON(w http.ResponseWriter, statusCode int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+// handleUserUnlock clears a login lockout for a user
+func handleUserUnlock(cfg config.SecurityConfig) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := strconv.Atoi(params["id"])
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		user, err := db.GetUser(r.Context(), id)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		actor := "admin"
+		if claims := middleware.GetClaims(r.Context()); claims != nil {
+			actor = "admin:" + claims.Sub
+		}
+		if err := loginguard.Unlock(r.Context(), user.Email, actor); err != nil {
+			http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+			return
+		}
+
+		// Redirect back to user detail
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", id), http.StatusSeeOther)
+	}
 }
 
-// generateAndSetCSRFToken generates a CSRF token and sets the cookie
-func generateAndSetCSRFToken(w http.ResponseWriter, cfg config.SecurityConfig) string {
-	if !cfg.EnableCSRF {
-		return ""
+// handleUserAPIKeyRevoke revokes one of a user's API keys
+func handleUserAPIKeyRevoke(cfg config.SecurityConfig) frameworkrouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := strconv.Atoi(params["id"])
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		keyID, err := strconv.ParseInt(params["keyID"], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+			return
+		}
+
+		key, err := db.GetAPIKey(r.Context(), keyID)
+		if err != nil || key.UserID != int64(id) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		actor := "admin"
+		if claims := middleware.GetClaims(r.Context()); claims != nil {
+			actor = "admin:" + claims.Sub
+		}
+		if err := apikey.Revoke(r.Context(), key, actor); err != nil {
+			http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+			return
+		}
+
+		// Redirect back to user detail
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", id), http.StatusSeeOther)
 	}
+}
 
-	token, err := security.GenerateCSRFToken(cfg.JWTSecret)
-	if err != nil {
-		return ""
+// apiKeyRow formats an API key for the user detail page
+func apiKeyRow(k db.APIKey, now time.Time) APIKeyRow {
+	row := APIKeyRow{
+		ID:        k.ID,
+		UserID:    k.UserID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    strings.Join(k.Scopes, ", "),
+		ExpiresAt: "Never",
+		LastUsed:  "Never",
+		Status:    "Active",
+		Active:    true,
 	}
+	if !k.ExpiresAt.IsZero() {
+		row.ExpiresAt = k.ExpiresAt.Format("2006-01-02")
+	}
+	if !k.LastUsedAt.IsZero() {
+		row.LastUsed = k.LastUsedAt.Format("2006-01-02 15:04") + " from " + k.LastUsedIP
+	}
+	switch {
+	case !k.RevokedAt.IsZero():
+		row.Status, row.Active = "Revoked", false
+	case !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt):
+		row.Status, row.Active = "Expired", false
+	}
+	return row
+}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     cfg.CSRFCookieName,
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false, // Set to true in production with HTTPS
-		SameSite: http.SameSiteStrictMode,
-	})
+// userForm is the user edit form, also sent as JSON by the API
+type userForm struct {
+	models.UserInput
+	IsStaff     bool `json:"is_staff"`
+	IsSuperuser bool `json:"is_superuser"`
+	IsActive    bool `json:"is_active"`
+}
 
-	return token
+// newUserForm is the create user form
+type newUserForm struct {
+	models.RegisterInput
+	IsStaff     bool `form:"is_staff"`
+	IsSuperuser bool `form:"is_superuser"`
+	IsActive    bool `form:"is_active"`
 }
 
-// validateCSRFFromForm validates CSRF token from form submission
-func validateCSRFFromForm(r *http.Request, cfg config.SecurityConfig) bool {
-	if !cfg.EnableCSRF {
-		return true
+// renderUserNewError renders the create user form again with what was
+// submitted and its errors
+func renderUserNewError(w http.ResponseWriter, r *http.Request, views *view.Engine, err error) {
+	data := map[string]any{
+		"Title": "Create New User",
+		"Form":  form.New(r, err),
 	}
 
-	csrfCookie, err := r.Cookie(cfg.CSRFCookieName)
-	if err != nil || csrfCookie.Value == "" {
-		return false
+	w.WriteHeader(http.StatusBadRequest)
+	if err := views.Render(w, "admin/user_new", data); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 	}
+}
 
-	csrfFormToken := r.FormValue("csrf_token")
-	return security.ValidateCSRFToken(cfg.JWTSecret, csrfCookie.Value, csrfFormToken)
+func writeJSON(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
 }
@@ -0,0 +1,289 @@
+// Package apikey implements personal access tokens for machine clients
+// (CI scripts, integrations) that can't use short-lived login JWTs.
+//
+// A key looks like "gbk_<prefix>_<secret>". The prefix is stored in clear and
+// used to look the key up; only a SHA-256 hash of the full key is stored, so
+// the key itself is shown exactly once, when it is created. Keys are named,
+// scoped and expiring, and can be revoked by their owner or an admin.
+//
+// Keys are sent like JWTs, as "Authorization: Bearer gbk_...", and are
+// accepted by middleware.JWTAuthMiddleware.
+//
+// SCOPES:
+//   - read:  GET, HEAD and OPTIONS requests
+//   - write: all methods (implies read)
+//   - admin: act with the owner's admin role; without it an admin's key acts as a regular user
+//
+// USAGE:
+//
+//	apikey.Configure(cfg.APIKeys)
+//
+//	token, key, err := apikey.Create(ctx, userID, "ci", []string{apikey.ScopeRead}, 30)
+//	// show token to the user once
+//
+//	key, user, err := apikey.Authenticate(ctx, token, ip)
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+)
+
+// Scopes that can be granted to a key
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// Security event types recorded for key lifecycle changes
+const (
+	EventAPIKeyCreated = "api_key_created"
+	EventAPIKeyRevoked = "api_key_revoked"
+)
+
+// tokenPrefix marks a bearer token as an API key rather than a JWT
+const tokenPrefix = "gbk_"
+
+var (
+	ErrDisabled      = errors.New("API keys are disabled")
+	ErrInvalidKey    = errors.New("invalid or expired API key")
+	ErrInvalidName   = errors.New("name is required (max 100 characters)")
+	ErrInvalidScope  = errors.New("scopes must be one or more of: read, write, admin")
+	ErrExpiryTooLong = errors.New("expiry exceeds the allowed maximum")
+	ErrTooManyKeys   = errors.New("maximum number of active API keys reached")
+)
+
+var (
+	mu       sync.RWMutex
+	settings config.APIKeysConfig
+
+	// now is replaceable in tests
+	now = time.Now
+)
+
+// Configure sets the API key settings. Call it once at startup.
+func Configure(cfg config.APIKeysConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	settings = cfg
+}
+
+func current() config.APIKeysConfig {
+	mu.RLock()
+	defer mu.RUnlock()
+	return settings
+}
+
+// Enabled reports whether API keys are accepted
+func Enabled() bool {
+	return current().Enabled
+}
+
+// IsAPIKey reports whether a bearer token is an API key (as opposed to a JWT)
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, tokenPrefix)
+}
+
+// Create issues a new key for a user and returns the full token, which is not
+// stored and can't be recovered. expiresInDays <= 0 uses the configured default.
+func Create(ctx context.Context, userID int64, name string, scopes []string, expiresInDays int) (string, db.APIKey, error) {
+	cfg := current()
+	if !cfg.Enabled {
+		return "", db.APIKey{}, ErrDisabled
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", db.APIKey{}, ErrInvalidName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", db.APIKey{}, err
+	}
+
+	if expiresInDays <= 0 {
+		expiresInDays = cfg.DefaultExpiryDays
+	}
+	if cfg.MaxExpiryDays > 0 && (expiresInDays <= 0 || expiresInDays > cfg.MaxExpiryDays) {
+		return "", db.APIKey{}, ErrExpiryTooLong
+	}
+
+	if cfg.MaxKeysPerUser > 0 {
+		active, err := db.CountActiveAPIKeys(ctx, userID, now())
+		if err != nil {
+			return "", db.APIKey{}, err
+		}
+		if active >= cfg.MaxKeysPerUser {
+			return "", db.APIKey{}, ErrTooManyKeys
+		}
+	}
+
+	token, prefix, err := generate()
+	if err != nil {
+		return "", db.APIKey{}, err
+	}
+
+	k := db.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: hash(token),
+		Scopes:  scopes,
+	}
+	if expiresInDays > 0 {
+		k.ExpiresAt = now().AddDate(0, 0, expiresInDays)
+	}
+
+	k, err = db.CreateAPIKey(ctx, k)
+	if err != nil {
+		return "", db.APIKey{}, err
+	}
+
+	db.RecordSecurityEvent(ctx, EventAPIKeyCreated, fmt.Sprintf("user:%d", userID), "", k.Prefix+" "+k.Name)
+	return token, k, nil
+}
+
+// Authenticate validates a token and returns the key and its owner. Every
+// failure (unknown, wrong secret, revoked, expired, inactive owner) returns
+// ErrInvalidKey so callers can't tell them apart.
+func Authenticate(ctx context.Context, token, ip string) (db.APIKey, models.User, error) {
+	if !Enabled() {
+		return db.APIKey{}, models.User{}, ErrDisabled
+	}
+
+	prefix, ok := parsePrefix(token)
+	if !ok {
+		return db.APIKey{}, models.User{}, ErrInvalidKey
+	}
+
+	k, err := db.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return db.APIKey{}, models.User{}, ErrInvalidKey
+	}
+	if subtle.ConstantTimeCompare([]byte(hash(token)), []byte(k.KeyHash)) != 1 {
+		return db.APIKey{}, models.User{}, ErrInvalidKey
+	}
+
+	t := now()
+	if !k.RevokedAt.IsZero() || (!k.ExpiresAt.IsZero() && !t.Before(k.ExpiresAt)) {
+		return db.APIKey{}, models.User{}, ErrInvalidKey
+	}
+
+	user, err := db.GetUser(ctx, int(k.UserID))
+	if err != nil || !user.IsActive {
+		return db.APIKey{}, models.User{}, ErrInvalidKey
+	}
+
+	db.TouchAPIKey(ctx, k.ID, ip, t)
+	return k, user, nil
+}
+
+// Revoke revokes a key and records who did it
+func Revoke(ctx context.Context, k db.APIKey, actor string) error {
+	if err := db.RevokeAPIKey(ctx, k.ID, now()); err != nil {
+		return err
+	}
+	db.RecordSecurityEvent(ctx, EventAPIKeyRevoked, fmt.Sprintf("user:%d", k.UserID), "", k.Prefix+" by "+actor)
+	return nil
+}
+
+// HasScope reports whether the key was granted a scope
+func HasScope(k db.APIKey, scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the key's scopes permit a request with the given method
+func Allows(k db.APIKey, method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return HasScope(k, ScopeRead) || HasScope(k, ScopeWrite)
+	default:
+		return HasScope(k, ScopeWrite)
+	}
+}
+
+// EffectiveRole is the role a request made with the key acts as: the owner's
+// role, except that admin requires the admin scope
+func EffectiveRole(k db.APIKey, user models.User) string {
+	if user.Role == "admin" && !HasScope(k, ScopeAdmin) {
+		return "user"
+	}
+	return user.Role
+}
+
+// normalizeScopes validates and de-duplicates scopes, defaulting to read
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{ScopeRead}, nil
+	}
+
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch s {
+		case ScopeRead, ScopeWrite, ScopeAdmin:
+		default:
+			return nil, ErrInvalidScope
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// generate returns a new token and its public prefix
+func generate() (token, prefix string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix = tokenPrefix + hex.EncodeToString(id)
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
+
+// parsePrefix extracts the public prefix ("gbk_<id>") from a token
+func parsePrefix(token string) (string, bool) {
+	if !IsAPIKey(token) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(token, tokenPrefix)
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 12 || secret == "" {
+		return "", false
+	}
+	return tokenPrefix + id, true
+}
+
+// hash returns the hex SHA-256 of a token. Keys carry 256 bits of randomness,
+// so a fast hash is sufficient (unlike passwords).
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
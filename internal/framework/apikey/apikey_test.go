@@ -0,0 +1,79 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+)
+
+func TestGenerateAndParsePrefix(t *testing.T) {
+	token, prefix, err := generate()
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if !IsAPIKey(token) {
+		t.Errorf("Expected %q to be recognized as an API key", token)
+	}
+
+	got, ok := parsePrefix(token)
+	if !ok || got != prefix {
+		t.Errorf("parsePrefix(%q) = %q, %v; want %q", token, got, ok, prefix)
+	}
+
+	for _, bad := range []string{"", "gbk_", "gbk_short_secret", "gbk_0123456789ab", "eyJhbGciOi.x.y"} {
+		if _, ok := parsePrefix(bad); ok {
+			t.Errorf("Expected parsePrefix(%q) to fail", bad)
+		}
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	scopes, err := normalizeScopes(nil)
+	if err != nil || len(scopes) != 1 || scopes[0] != ScopeRead {
+		t.Errorf("Expected default [read], got %v, %v", scopes, err)
+	}
+
+	scopes, err = normalizeScopes([]string{"Write", "read", "write"})
+	if err != nil || len(scopes) != 2 {
+		t.Errorf("Expected de-duplicated scopes, got %v, %v", scopes, err)
+	}
+
+	if _, err := normalizeScopes([]string{"read", "root"}); err != ErrInvalidScope {
+		t.Errorf("Expected ErrInvalidScope, got %v", err)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		method string
+		want   bool
+	}{
+		{[]string{ScopeRead}, "GET", true},
+		{[]string{ScopeRead}, "POST", false},
+		{[]string{ScopeRead}, "DELETE", false},
+		{[]string{ScopeWrite}, "GET", true},
+		{[]string{ScopeWrite}, "PATCH", true},
+		{[]string{ScopeAdmin}, "GET", false},
+	}
+
+	for _, tt := range tests {
+		if got := Allows(db.APIKey{Scopes: tt.scopes}, tt.method); got != tt.want {
+			t.Errorf("Allows(%v, %s) = %v, want %v", tt.scopes, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveRole(t *testing.T) {
+	admin := models.User{Role: "admin"}
+	if got := EffectiveRole(db.APIKey{Scopes: []string{ScopeWrite}}, admin); got != "user" {
+		t.Errorf("Expected admin key without admin scope to act as user, got %q", got)
+	}
+	if got := EffectiveRole(db.APIKey{Scopes: []string{ScopeWrite, ScopeAdmin}}, admin); got != "admin" {
+		t.Errorf("Expected admin scope to keep admin role, got %q", got)
+	}
+	if got := EffectiveRole(db.APIKey{Scopes: []string{ScopeAdmin}}, models.User{Role: "user"}); got != "user" {
+		t.Errorf("Expected admin scope not to elevate a regular user, got %q", got)
+	}
+}
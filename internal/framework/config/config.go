@@ -5,23 +5,23 @@
 // This package defines the configuration structure and loading logic for the entire
 // goBastion framework. It provides:
 //   - Type-safe configuration structs
-//   - JSON file loading with defaults
-//   - Environment variable overrides
-//   - Validation of critical settings
+//   - JSON file loading with defaults and per-environment overlays
+//   - Environment variable overrides for every field
+//   - Validation of critical settings, reporting every problem at once
 //
 // WHEN TO MODIFY:
 //   - ✅ ADD new configuration fields to existing structs (extend)
 //   - ✅ ADD new top-level config sections (e.g., EmailConfig)
-//   - ✅ ADD new environment variable overrides in Load()
 //   - ⚠️  MODIFY existing field types carefully (may break apps)
 //   - ❌ DO NOT remove existing configuration fields (breaking change)
 //   - ❌ DO NOT change JSON field names (breaks config files)
 //
-// CONFIGURATION FLOW:
-//  1. Load() reads config/config.json
-//  2. Applies sensible defaults for missing values
-//  3. Overrides with environment variables (if set)
-//  4. Returns fully populated Config struct
+// CONFIGURATION FLOW (see layers.go):
+//  1. Defaults() provides sensible values for everything
+//  2. Load() merges config/config.json, then config/config.<env>.json
+//  3. Resolves ${ENV} and ${file:/path} references (see secrets.go)
+//  4. Overrides with environment variables, e.g. GOBASTION_SERVER_PORT
+//  5. Validates the result and returns a fully populated Config struct
 //
 // USAGE IN YOUR APP:
 //
@@ -52,27 +52,19 @@
 //           Email EmailConfig `json:"email"`
 //       }
 //
-//  3. Add defaults in Load():
+//  3. Add defaults in Defaults():
 //       Email: EmailConfig{
 //           SMTPHost: "localhost",
 //           SMTPPort: 587,
 //       },
 //
-//  4. Add env overrides in Load() if needed:
-//       if host := os.Getenv("APP_SMTP_HOST"); host != "" {
-//           cfg.Email.SMTPHost = host
-//       }
+//  4. Add checks to Validate() if needed. Environment overrides
+//     (GOBASTION_EMAIL_SMTP_HOST) and unknown-key detection work automatically.
 //
 // For documentation on config fields, see: README.md "Configuration" section
 package config
 
-import (
-	"encoding/json"
-	"os"
-	"strconv"
-	"strings"
-	"time"
-)
+import "time"
 
 // AppConfig holds application-level settings like name, environment, and branding.
 //
@@ -93,11 +85,12 @@ type ServerConfig struct {
 	WriteTimeoutSeconds int      `json:"write_timeout_seconds"` // Write timeout in seconds
 	IdleTimeoutSeconds  int      `json:"idle_timeout_seconds"`  // Idle timeout in seconds
 	AllowedOrigins      []string `json:"allowed_origins"`       // CORS allowed origins
+	TrustedProxies      []string `json:"trusted_proxies"`       // CIDRs/IPs of reverse proxies whose Forwarded/X-Forwarded-* headers are believed
 }
 
 type DatabaseConfig struct {
 	Driver                 string `json:"driver"`
-	DSN                    string `json:"dsn"`
+	DSN                    string `json:"dsn" secret:"dsn"` // Passwords are masked by `go-bastion config print`
 	MaxOpenConns           int    `json:"max_open_conns"`
 	MaxIdleConns           int    `json:"max_idle_conns"`
 	ConnMaxLifetimeMinutes int    `json:"conn_max_lifetime_minutes"`
@@ -107,16 +100,126 @@ type SecurityConfig struct {
 	EnableCSRF          bool   `json:"enable_csrf"`
 	CSRFHeaderName      string `json:"csrf_header_name"`
 	CSRFCookieName      string `json:"csrf_cookie_name"`
+	SecureCookies       bool   `json:"secure_cookies"` // Mark session and CSRF cookies Secure (always on in production)
 	EnableJWT           bool   `json:"enable_jwt"`
-	JWTSecret           string `json:"jwt_secret"`
+	JWTSecret           string `json:"jwt_secret" secret:"true"`
 	AccessTokenMinutes  int    `json:"access_token_minutes"`
 	RefreshTokenMinutes int    `json:"refresh_token_minutes"`
 	MaxBodyBytes        int64  `json:"max_body_bytes"`
+	TOTPIssuer          string `json:"totp_issuer"` // Issuer name shown in authenticator apps
+	BcryptCost          int    `json:"bcrypt_cost"` // Password hashing cost; stored hashes are upgraded on login
+
+	PasswordPolicy     PasswordPolicyConfig `json:"password_policy"`      // Rules for new passwords
+	Headers            HeadersConfig        `json:"headers"`              // Security response headers
+	CSRFTrustedOrigins []string             `json:"csrf_trusted_origins"` // Other origins allowed to submit forms, e.g. "https://app.example.com"
+}
+
+// PasswordPolicyConfig holds the rules applied when a password is set
+type PasswordPolicyConfig struct {
+	MinLength            int    `json:"min_length"`             // Minimum length in characters
+	MaxLength            int    `json:"max_length"`             // Maximum length in bytes (bcrypt uses at most 72)
+	RequireUppercase     bool   `json:"require_uppercase"`      // Require at least one A-Z
+	RequireLowercase     bool   `json:"require_lowercase"`      // Require at least one a-z
+	RequireDigit         bool   `json:"require_digit"`          // Require at least one 0-9
+	RequireSymbol        bool   `json:"require_symbol"`         // Require at least one non-alphanumeric character
+	DisallowPersonalInfo bool   `json:"disallow_personal_info"` // Reject passwords containing the user's name or email
+	BreachedListPath     string `json:"breached_list_path"`     // File of SHA-1 hashes of breached/common passwords ("" = disabled)
+	HistorySize          int    `json:"history_size"`           // Number of previous passwords that can't be reused (0 = disabled)
+}
+
+// HeadersConfig configures the security headers sent with every response
+type HeadersConfig struct {
+	CSP                       CSPConfig           `json:"csp"`                          // Content-Security-Policy
+	HSTS                      HSTSConfig          `json:"hsts"`                         // Strict-Transport-Security
+	PermissionsPolicy         map[string][]string `json:"permissions_policy"`           // Feature -> allowlist ("self", "*" or origins); empty list disables the feature
+	CrossOriginOpenerPolicy   string              `json:"cross_origin_opener_policy"`   // e.g. "same-origin" ("" = not sent)
+	CrossOriginEmbedderPolicy string              `json:"cross_origin_embedder_policy"` // e.g. "require-corp" ("" = not sent)
+	FrameOptions              string              `json:"frame_options"`                // X-Frame-Options ("" = not sent)
+	ReferrerPolicy            string              `json:"referrer_policy"`              // Referrer-Policy ("" = not sent)
+}
+
+// CSPConfig builds the Content-Security-Policy header
+type CSPConfig struct {
+	Enabled         bool                `json:"enabled"`          // Send the CSP header
+	ReportOnly      bool                `json:"report_only"`      // Send Content-Security-Policy-Report-Only instead (violations reported, not blocked)
+	Directives      map[string][]string `json:"directives"`       // Directive -> sources, e.g. "script-src": ["'self'"]; entries in config.json replace the default per directive
+	NonceDirectives []string            `json:"nonce_directives"` // Directives that get the per-request nonce (@cspNonce in templates)
+	ReportURI       string              `json:"report_uri"`       // Where browsers send violation reports ("" = no reporting)
+}
+
+// HSTSConfig configures Strict-Transport-Security
+type HSTSConfig struct {
+	Enabled           bool `json:"enabled"`            // Send the HSTS header
+	MaxAgeSeconds     int  `json:"max_age_seconds"`    // How long browsers must use HTTPS only
+	IncludeSubdomains bool `json:"include_subdomains"` // Apply to all subdomains
+	Preload           bool `json:"preload"`            // Opt in to browser preload lists
+}
+
+// CORSConfig holds the Cross-Origin Resource Sharing policies routes can use
+type CORSConfig struct {
+	Policies map[string]CORSPolicyConfig `json:"policies"` // Named policies, attached to routes with router.CORS("name"); "default" applies to the rest
+}
+
+// CORSPolicyConfig is a named CORS policy
+type CORSPolicyConfig struct {
+	AllowedOrigins        []string `json:"allowed_origins"`         // Exact origins, wildcards ("https://*.example.com") or "*"
+	AllowedOriginPatterns []string `json:"allowed_origin_patterns"` // Regular expressions matched against the whole origin
+	AllowedMethods        []string `json:"allowed_methods"`         // Methods allowed cross-origin (GET, HEAD and POST are always allowed)
+	AllowedHeaders        []string `json:"allowed_headers"`         // Request headers allowed cross-origin ("*" = any, without credentials)
+	ExposedHeaders        []string `json:"exposed_headers"`         // Response headers readable by scripts
+	AllowCredentials      bool     `json:"allow_credentials"`       // Allow cookies/Authorization; can't be combined with "*"
+	MaxAgeSeconds         int      `json:"max_age_seconds"`         // How long browsers may cache a preflight (0 = don't send)
 }
 
 type RateLimitConfig struct {
-	Enabled           bool `json:"enabled"`             // Enable rate limiting
-	RequestsPerMinute int  `json:"requests_per_minute"` // Max requests per minute per IP
+	Enabled           bool                             `json:"enabled"`             // Enable rate limiting
+	RequestsPerMinute int                              `json:"requests_per_minute"` // Max requests per minute per IP for routes without a policy (unless "default" is configured)
+	Store             string                           `json:"store"`               // "memory" (per instance) or "database" (counters shared by every instance using the database)
+	Policies          map[string]RateLimitPolicyConfig `json:"policies"`            // Named policies, attached to routes with router.RateLimit("name")
+}
+
+// RateLimitPolicyConfig is a named rate limit
+type RateLimitPolicyConfig struct {
+	Algorithm     string `json:"algorithm"`      // "sliding_window" (default) or "token_bucket" (allows bursts up to limit)
+	Limit         int    `json:"limit"`          // Requests per window; for token_bucket the bucket size (0 = unlimited)
+	WindowSeconds int    `json:"window_seconds"` // Window length; for token_bucket the time to refill an empty bucket
+	Key           string `json:"key"`            // What is counted: "ip" (default), "user" or "api_key"; anonymous requests fall back to ip
+}
+
+// LoginProtectionConfig holds brute-force protection settings for login endpoints
+type LoginProtectionConfig struct {
+	Enabled               bool `json:"enabled"`                  // Enable failed-attempt tracking and lockout
+	MaxAttemptsPerAccount int  `json:"max_attempts_per_account"` // Failures before an account is locked
+	MaxAttemptsPerIP      int  `json:"max_attempts_per_ip"`      // Failures before an IP is locked
+	LockoutMinutes        int  `json:"lockout_minutes"`          // Lockout duration (also the failure counting window)
+	BackoffBaseSeconds    int  `json:"backoff_base_seconds"`     // Delay after the first failure, doubled on each failure
+	BackoffMaxSeconds     int  `json:"backoff_max_seconds"`      // Upper bound for the backoff delay
+	UnlockTokenMinutes    int  `json:"unlock_token_minutes"`     // Validity of emailed unlock links
+}
+
+// APIKeysConfig holds settings for personal access tokens used by machine clients
+type APIKeysConfig struct {
+	Enabled           bool `json:"enabled"`             // Accept API keys as bearer tokens
+	DefaultExpiryDays int  `json:"default_expiry_days"` // Expiry applied when a key is created without one
+	MaxExpiryDays     int  `json:"max_expiry_days"`     // Longest allowed expiry (0 = keys may never expire)
+	MaxKeysPerUser    int  `json:"max_keys_per_user"`   // Active keys a user may hold (0 = unlimited)
+}
+
+// OAuthConfig holds external identity provider settings for social/corporate login
+type OAuthConfig struct {
+	Providers []OIDCProviderConfig `json:"providers"` // OpenID Connect providers shown on the login page
+}
+
+// OIDCProviderConfig holds the settings for a single OpenID Connect provider
+type OIDCProviderConfig struct {
+	Name         string   `json:"name"`                        // URL slug, e.g. "google" -> /auth/oidc/google
+	DisplayName  string   `json:"display_name"`                // Button label on the login page
+	IssuerURL    string   `json:"issuer_url"`                  // Discovery is read from <issuer>/.well-known/openid-configuration
+	ClientID     string   `json:"client_id"`                   // OAuth2 client ID
+	ClientSecret string   `json:"client_secret" secret:"true"` // OAuth2 client secret (empty for public clients)
+	Scopes       []string `json:"scopes"`                      // Requested scopes (defaults to openid, email, profile)
+	AllowSignup  bool     `json:"allow_signup"`                // Create a local user for unknown identities
+	LinkByEmail  bool     `json:"link_by_email"`               // Link to an existing user with the same verified email
 }
 
 // FrontendConfig holds frontend/theme settings
@@ -158,21 +261,25 @@ type FeaturesConfig struct {
 }
 
 type Config struct {
-	App       AppConfig       `json:"app"`        // Application settings
-	Server    ServerConfig    `json:"server"`     // Server settings
-	Database  DatabaseConfig  `json:"database"`   // Database settings
-	Security  SecurityConfig  `json:"security"`   // Security settings
-	RateLimit RateLimitConfig `json:"rate_limit"` // Rate limiting settings
-	Frontend  FrontendConfig  `json:"frontend"`   // Frontend/theme settings
-	Admin     AdminConfig     `json:"admin"`      // Admin panel settings
-	Logging   LoggingConfig   `json:"logging"`    // Logging settings
-	Features  FeaturesConfig  `json:"features"`   // Feature flags
+	App             AppConfig             `json:"app"`              // Application settings
+	Server          ServerConfig          `json:"server"`           // Server settings
+	CORS            CORSConfig            `json:"cors"`             // Cross-origin policies
+	Database        DatabaseConfig        `json:"database"`         // Database settings
+	Security        SecurityConfig        `json:"security"`         // Security settings
+	RateLimit       RateLimitConfig       `json:"rate_limit"`       // Rate limiting settings
+	LoginProtection LoginProtectionConfig `json:"login_protection"` // Brute-force protection settings
+	APIKeys         APIKeysConfig         `json:"api_keys"`         // Personal access tokens
+	OAuth           OAuthConfig           `json:"oauth"`            // External identity providers
+	Frontend        FrontendConfig        `json:"frontend"`         // Frontend/theme settings
+	Admin           AdminConfig           `json:"admin"`            // Admin panel settings
+	Logging         LoggingConfig         `json:"logging"`          // Logging settings
+	Features        FeaturesConfig        `json:"features"`         // Feature flags
 }
 
-// Load reads configuration from a file and applies environment variable overrides
-func Load(path string) (Config, error) {
-	// Start with sane defaults
-	cfg := Config{
+// Defaults returns the configuration used for every value the files and the
+// environment leave unset
+func Defaults() Config {
+	return Config{
 		App: AppConfig{
 			Name:        "goBastion",
 			Environment: "development",
@@ -204,10 +311,87 @@ func Load(path string) (Config, error) {
 			AccessTokenMinutes:  15,
 			RefreshTokenMinutes: 4320,
 			MaxBodyBytes:        1048576,
+			TOTPIssuer:          "goBastion",
+			BcryptCost:          10,
+			PasswordPolicy: PasswordPolicyConfig{
+				MinLength:            8,
+				MaxLength:            72,
+				RequireUppercase:     true,
+				RequireLowercase:     true,
+				RequireDigit:         true,
+				DisallowPersonalInfo: true,
+				BreachedListPath:     "config/breached_passwords.txt",
+				HistorySize:          5,
+			},
+			Headers: HeadersConfig{
+				CSP: CSPConfig{
+					Enabled: true,
+					Directives: map[string][]string{
+						"default-src":     {"'self'"},
+						"script-src":      {"'self'", "https://cdn.jsdelivr.net", "https://unpkg.com"},
+						"style-src":       {"'self'", "'unsafe-inline'", "https://cdn.jsdelivr.net"},
+						"img-src":         {"'self'", "data:"},
+						"font-src":        {"'self'", "https://cdn.jsdelivr.net"},
+						"connect-src":     {"'self'"},
+						"object-src":      {"'none'"},
+						"base-uri":        {"'self'"},
+						"form-action":     {"'self'"},
+						"frame-ancestors": {"'self'"},
+					},
+					NonceDirectives: []string{"script-src"},
+					ReportURI:       "/csp-report",
+				},
+				HSTS: HSTSConfig{
+					Enabled:           true,
+					MaxAgeSeconds:     31536000,
+					IncludeSubdomains: true,
+				},
+				PermissionsPolicy: map[string][]string{
+					"camera":      {},
+					"microphone":  {},
+					"geolocation": {},
+					"payment":     {},
+				},
+				CrossOriginOpenerPolicy: "same-origin",
+				FrameOptions:            "SAMEORIGIN",
+				ReferrerPolicy:          "same-origin", // no-referrer would make browsers send "Origin: null" on form posts
+			},
+		},
+		CORS: CORSConfig{
+			Policies: map[string]CORSPolicyConfig{
+				// The JSON API is called with bearer tokens, so it never needs cookies
+				"api": {
+					AllowedOrigins: []string{"http://localhost:3000"},
+					AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+					AllowedHeaders: []string{"Content-Type", "Authorization"},
+					ExposedHeaders: []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+					MaxAgeSeconds:  600,
+				},
+			},
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:           true,
 			RequestsPerMinute: 60,
+			Store:             "memory",
+			Policies: map[string]RateLimitPolicyConfig{
+				"auth": {Algorithm: "sliding_window", Limit: 10, WindowSeconds: 60, Key: "ip"},
+				"chat": {Algorithm: "token_bucket", Limit: 10, WindowSeconds: 20, Key: "user"},
+			},
+		},
+		LoginProtection: LoginProtectionConfig{
+			Enabled:               true,
+			MaxAttemptsPerAccount: 5,
+			MaxAttemptsPerIP:      20,
+			LockoutMinutes:        15,
+			BackoffBaseSeconds:    1,
+			BackoffMaxSeconds:     30,
+			UnlockTokenMinutes:    60,
+		},
+		APIKeys: APIKeysConfig{
+			Enabled:           true,
+			DefaultExpiryDays: 90,
+			MaxExpiryDays:     365,
+			MaxKeysPerUser:    10,
 		},
 		Frontend: FrontendConfig{
 			Theme: ThemeConfig{
@@ -237,47 +421,21 @@ func Load(path string) (Config, error) {
 			EnableMetrics:       true,
 		},
 	}
+}
 
-	// Load from file if it exists
-	if data, err := os.ReadFile(path); err == nil {
-		if err := json.Unmarshal(data, &cfg); err != nil {
-			return cfg, err
-		}
-	}
-
-	// Apply environment variable overrides
-	if port := os.Getenv("APP_PORT"); port != "" {
-		cfg.Server.Port = port
-	}
-	if driver := os.Getenv("APP_DB_DRIVER"); driver != "" {
-		cfg.Database.Driver = driver
-	}
-	if dsn := os.Getenv("APP_DB_DSN"); dsn != "" {
-		cfg.Database.DSN = dsn
-	}
-	if secret := os.Getenv("APP_JWT_SECRET"); secret != "" {
-		cfg.Security.JWTSecret = secret
-	}
-	if maxBody := os.Getenv("APP_MAX_BODY_BYTES"); maxBody != "" {
-		if val, err := strconv.ParseInt(maxBody, 10, 64); err == nil {
-			cfg.Security.MaxBodyBytes = val
-		}
-	}
-	if origins := os.Getenv("APP_ALLOWED_ORIGINS"); origins != "" {
-		cfg.Server.AllowedOrigins = strings.Split(origins, ",")
-	}
-	if readTimeout := os.Getenv("APP_READ_TIMEOUT_SECONDS"); readTimeout != "" {
-		if val, err := strconv.Atoi(readTimeout); err == nil {
-			cfg.Server.ReadTimeoutSeconds = val
-		}
-	}
-	if writeTimeout := os.Getenv("APP_WRITE_TIMEOUT_SECONDS"); writeTimeout != "" {
-		if val, err := strconv.Atoi(writeTimeout); err == nil {
-			cfg.Server.WriteTimeoutSeconds = val
-		}
-	}
+// Load reads the configuration for the current environment: path, then the
+// config.<env>.json overlay next to it, then environment variable overrides
+// (see layers.go). The environment comes from GOBASTION_APP_ENVIRONMENT (or
+// APP_ENVIRONMENT), falling back to app.environment in path.
+func Load(path string) (Config, error) {
+	return LoadEnv(path, "")
+}
 
-	return cfg, nil
+// LoadEnv is Load for an explicit environment, as in `go-bastion serve production`.
+// An empty env is chosen like Load does.
+func LoadEnv(path, env string) (Config, error) {
+	cfg, _, err := Inspect(path, env)
+	return cfg, err
 }
 
 // GetReadTimeout returns the configured read timeout as time.Duration
@@ -0,0 +1,521 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The configuration is built in layers, each overriding the previous one:
+//
+//  1. Defaults()
+//  2. config/config.json
+//  3. config/config.<env>.json (e.g. config.production.json), if it exists
+//  4. ${ENV} and ${file:/path} references in the values (see secrets.go)
+//  5. The legacy APP_* environment variables
+//  6. GOBASTION_<PATH> environment variables, one per field
+//
+// Objects (including maps such as rate_limit.policies) are merged key by key,
+// so an overlay only lists what changes; arrays and scalars replace the
+// previous value and null keeps it. Every key in a file must exist in
+// Config and have the right type, and every GOBASTION_* variable must name a
+// field: typos are errors instead of silently ignored settings.
+
+// EnvPrefix starts the environment variable of every configuration field:
+// the JSON path in upper case with "_" separators, e.g. GOBASTION_SERVER_PORT
+// for server.port. Lists take comma-separated values; maps and lists of
+// objects take JSON, e.g. GOBASTION_CORS_POLICIES='{"api": {...}}'.
+const EnvPrefix = "GOBASTION_"
+
+// envNamePattern restricts environment names, which become part of a file name
+var envNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// legacyEnv are the overrides that predate GOBASTION_*; they still work but
+// GOBASTION_* wins when both are set
+var legacyEnv = []struct{ name, path string }{
+	{"APP_ENVIRONMENT", "app.environment"},
+	{"APP_PORT", "server.port"},
+	{"APP_DB_DRIVER", "database.driver"},
+	{"APP_DB_DSN", "database.dsn"},
+	{"APP_JWT_SECRET", "security.jwt_secret"},
+	{"APP_MAX_BODY_BYTES", "security.max_body_bytes"},
+	{"APP_ALLOWED_ORIGINS", "server.allowed_origins"},
+	{"APP_READ_TIMEOUT_SECONDS", "server.read_timeout_seconds"},
+	{"APP_WRITE_TIMEOUT_SECONDS", "server.write_timeout_seconds"},
+}
+
+// errReported is returned once a problem has been added to the loader
+var errReported = errors.New("reported")
+
+// Sources records where a loaded configuration came from
+type Sources struct {
+	Environment string            // Environment whose overlay was applied
+	Files       []string          // Files merged, in order
+	Values      map[string]string // JSON path -> file or environment variable that set it (unlisted = default)
+}
+
+// Of returns the file or environment variable that set path ("" = default)
+func (s Sources) Of(path string) string {
+	for p := path; p != ""; p = parentPath(p) {
+		if source, ok := s.Values[p]; ok {
+			return source
+		}
+	}
+	return ""
+}
+
+// set records source for path, replacing what was recorded below it
+func (s Sources) set(path, source string) {
+	for p := range s.Values {
+		if strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			delete(s.Values, p)
+		}
+	}
+	s.Values[path] = source
+}
+
+// parentPath drops the last element of a JSON path
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// OverlayPath returns the overlay of path for env: config/config.json ->
+// config/config.production.json
+func OverlayPath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+// EnvName returns the GOBASTION_* variable of a JSON path
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// Inspect loads the configuration like LoadEnv and also reports where every
+// value came from, for `go-bastion config print`. All problems are returned
+// together as a *ValidationError.
+func Inspect(path, env string) (Config, Sources, error) {
+	cfg := Defaults()
+	l := loader{sources: Sources{Values: make(map[string]string)}}
+
+	l.mergeFile(&cfg, path)
+
+	envSource := "command line"
+	if env == "" {
+		envSource = ""
+		for _, name := range []string{EnvName("app.environment"), "APP_ENVIRONMENT"} {
+			if value, _ := lookupEnv(name); value != "" {
+				env, envSource = value, name
+				break
+			}
+		}
+	}
+	if env == "" {
+		env = cfg.App.Environment
+	}
+	if envNamePattern.MatchString(env) {
+		l.mergeFile(&cfg, OverlayPath(path, env))
+	}
+
+	var refProblems []FieldError
+	resolveReferences(reflect.ValueOf(&cfg), "", &refProblems)
+	for _, p := range refProblems {
+		p.Source = l.sources.Of(p.Path)
+		l.problems = append(l.problems, p)
+	}
+	l.applyEnv(&cfg)
+
+	// The selected environment wins over app.environment in the overlay
+	cfg.App.Environment = env
+	if envSource != "" {
+		l.sources.set("app.environment", envSource)
+	}
+	l.sources.Environment = env
+
+	for _, p := range cfg.Validate() {
+		p.Source = l.sources.Of(p.Path)
+		l.problems = append(l.problems, p)
+	}
+
+	if cfg.App.Environment == "production" {
+		// Production is served over HTTPS, so cookies never travel in clear text
+		cfg.Security.SecureCookies = true
+
+		// ...and never runs with the secrets from the repository
+		for _, p := range cfg.CheckSecrets() {
+			if l.reported(p.Path) {
+				continue // e.g. an unresolved ${...} reference
+			}
+			p.Source = l.sources.Of(p.Path)
+			p.Message += " (refused in production)"
+			l.problems = append(l.problems, p)
+		}
+	}
+
+	if len(l.problems) > 0 {
+		return cfg, l.sources, &ValidationError{Problems: l.problems}
+	}
+	return cfg, l.sources, nil
+}
+
+// loader collects the problems and sources of one Inspect call
+type loader struct {
+	problems []FieldError
+	sources  Sources
+}
+
+// reported reports whether a problem with path was already found
+func (l *loader) reported(path string) bool {
+	for _, p := range l.problems {
+		if p.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *loader) add(path, source, format string, args ...any) {
+	l.problems = append(l.problems, FieldError{Path: path, Source: source, Message: fmt.Sprintf(format, args...)})
+}
+
+// mergeFile merges a JSON file into cfg. A missing file is skipped; a file
+// with unknown keys or wrongly typed values is reported and not merged.
+func (l *loader) mergeFile(cfg *Config, path string) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		l.add("", path, "%v", err)
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := position(data, syntaxErr.Offset)
+			l.add("", fmt.Sprintf("%s:%d:%d", path, line, col), "invalid JSON: %v", err)
+		} else {
+			l.add("", path, "invalid JSON: %v", err)
+		}
+		return
+	}
+
+	before := len(l.problems)
+	l.check(reflect.TypeOf(*cfg), value, "", path)
+	if len(l.problems) > before {
+		return
+	}
+	if err := merge(cfg, value); err != nil {
+		l.add("", path, "%v", err)
+		return
+	}
+	l.sources.Files = append(l.sources.Files, path)
+}
+
+// merge merges a decoded JSON document into cfg. Unlike json.Unmarshal it also
+// merges the entries of maps, so an overlay can change one field of a named
+// policy without repeating the rest.
+func merge(cfg *Config, overlay any) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var base any
+	if err := dec.Decode(&base); err != nil {
+		return err
+	}
+
+	if data, err = json.Marshal(mergeValues(base, overlay)); err != nil {
+		return err
+	}
+	var merged Config
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return err
+	}
+	*cfg = merged
+	return nil
+}
+
+// mergeValues merges objects key by key; anything else replaces base, except
+// null, which keeps it
+func mergeValues(base, overlay any) any {
+	if overlay == nil {
+		return base
+	}
+	b, ok := base.(map[string]any)
+	o, isObject := overlay.(map[string]any)
+	if !ok || !isObject {
+		return overlay
+	}
+	for key, value := range o {
+		b[key] = mergeValues(b[key], value)
+	}
+	return b
+}
+
+// position converts a byte offset into a 1-based line and column
+func position(data []byte, offset int64) (line, col int) {
+	before := data[:min(int(offset), len(data))]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// check compares a decoded JSON value with the Go type it will be stored in,
+// reporting unknown keys and mismatched types, and records its source
+func (l *loader) check(t reflect.Type, value any, path, source string) {
+	if value == nil {
+		// null keeps the previous value
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			l.add(path, source, "expected an object, got %s", jsonKind(value))
+			return
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(obj) {
+			field, ok := fields[key]
+			if !ok {
+				l.add(joinPath(path, key), source, "unknown key")
+				continue
+			}
+			l.check(field.Type, obj[key], joinPath(path, key), source)
+		}
+		return
+
+	case reflect.Map:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			l.add(path, source, "expected an object, got %s", jsonKind(value))
+			return
+		}
+		for _, key := range sortedKeys(obj) {
+			l.check(t.Elem(), obj[key], joinPath(path, key), source)
+		}
+		return
+
+	case reflect.Slice:
+		arr, ok := value.([]any)
+		if !ok {
+			l.add(path, source, "expected an array, got %s", jsonKind(value))
+			return
+		}
+		for i, elem := range arr {
+			l.check(t.Elem(), elem, fmt.Sprintf("%s[%d]", path, i), source)
+		}
+
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			l.add(path, source, "expected a string, got %s", jsonKind(value))
+			return
+		}
+
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			l.add(path, source, "expected true or false, got %s", jsonKind(value))
+			return
+		}
+
+	case reflect.Int, reflect.Int64:
+		n, ok := value.(json.Number)
+		if !ok {
+			l.add(path, source, "expected a number, got %s", jsonKind(value))
+			return
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, t.Bits()); err != nil {
+			l.add(path, source, "expected a whole number, got %s", n)
+			return
+		}
+	}
+	l.sources.set(path, source)
+}
+
+// jsonKind names the type of a decoded JSON value for error messages
+func jsonKind(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	}
+	return "null"
+}
+
+// jsonFields returns the fields of a struct type by JSON name
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := range t.NumField() {
+		if name := jsonName(t.Field(i)); name != "" {
+			fields[name] = t.Field(i)
+		}
+	}
+	return fields
+}
+
+// jsonName returns the JSON name of a struct field ("" = not serialized)
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// fields calls fn with the JSON path of every field of a config struct that
+// isn't itself a struct
+func fields(v reflect.Value, path string, fn func(path string, field reflect.Value, sf reflect.StructField)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		if v.Field(i).Kind() == reflect.Struct {
+			fields(v.Field(i), joinPath(path, name), fn)
+			continue
+		}
+		fn(joinPath(path, name), v.Field(i), t.Field(i))
+	}
+}
+
+// applyEnv applies the legacy APP_* variables, then one GOBASTION_* variable
+// per field, and reports GOBASTION_* variables that don't name a field
+func (l *loader) applyEnv(cfg *Config) {
+	byPath := make(map[string]reflect.Value)
+	fields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.Value, _ reflect.StructField) {
+		byPath[path] = field
+	})
+
+	for _, legacy := range legacyEnv {
+		l.setFromEnv(byPath[legacy.path], legacy.path, legacy.name)
+	}
+
+	known := make(map[string]bool, len(byPath))
+	for _, path := range sortedKeys(byPath) {
+		name := EnvName(path)
+		known[name] = true
+		l.setFromEnv(byPath[path], path, name)
+	}
+
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, EnvPrefix) && !known[name] && !known[strings.TrimSuffix(name, "_FILE")] {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.Sort(unknown)
+	for _, name := range unknown {
+		l.add("", name, "unknown environment variable (no such configuration field)")
+	}
+}
+
+// setFromEnv sets field from the environment variable name (or name_FILE)
+func (l *loader) setFromEnv(field reflect.Value, path, name string) {
+	value, err := lookupEnv(name)
+	if err != nil {
+		l.add(path, name, "%v", err)
+		return
+	}
+	if value == "" {
+		return
+	}
+	if err := l.setString(field, value, path, name); err != nil {
+		if err != errReported {
+			l.add(path, name, "%v", err)
+		}
+		return
+	}
+	l.sources.set(path, name)
+}
+
+// setString parses an environment variable value into field
+func (l *loader) setString(field reflect.Value, value, path, source string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+		return nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		field.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a whole number, got %q", value)
+		}
+		field.SetInt(n)
+		return nil
+	}
+
+	// Lists of strings may be comma-separated; everything else is JSON
+	if field.Type() == reflect.TypeOf([]string(nil)) && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+		return nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	before := len(l.problems)
+	l.check(field.Type(), decoded, path, source)
+	if len(l.problems) > before {
+		return errReported
+	}
+	ptr := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+		return err
+	}
+	field.Set(ptr.Elem())
+	return nil
+}
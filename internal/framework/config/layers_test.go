@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShippedConfigIsValid(t *testing.T) {
+	if _, err := Load("../../../config/config.json"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	writeAt(t, path, `{
+		"app": {"environment": "staging"},
+		"server": {"port": ":8080", "allowed_origins": ["http://localhost:3000"]},
+		"rate_limit": {"policies": {"auth": {"algorithm": "sliding_window", "limit": 10, "window_seconds": 60}}}
+	}`)
+	writeAt(t, filepath.Join(dir, "config.staging.json"), `{
+		"server": {"port": ":9000", "allowed_origins": ["https://staging.example.com"]},
+		"rate_limit": {"policies": {"auth": {"limit": 3}}}
+	}`)
+
+	cfg, sources, err := Inspect(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != ":9000" || len(cfg.Server.AllowedOrigins) != 1 || cfg.Server.AllowedOrigins[0] != "https://staging.example.com" {
+		t.Errorf("Overlay not applied: %+v", cfg.Server)
+	}
+	// Map entries are merged, not replaced
+	if auth := cfg.RateLimit.Policies["auth"]; auth.Limit != 3 || auth.WindowSeconds != 60 {
+		t.Errorf("auth policy = %+v, want limit 3 with the base window", auth)
+	}
+	if sources.Of("server.port") != filepath.Join(dir, "config.staging.json") || sources.Of("server.read_timeout_seconds") != "" {
+		t.Errorf("Unexpected sources %v", sources.Values)
+	}
+
+	// The environment can be chosen explicitly; a missing overlay is fine
+	if cfg, err := LoadEnv(path, "test"); err != nil || cfg.Server.Port != ":8080" || cfg.App.Environment != "test" {
+		t.Errorf("LoadEnv(test): port %q, environment %q, %v", cfg.Server.Port, cfg.App.Environment, err)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	t.Setenv("GOBASTION_SERVER_PORT", ":7000")
+	t.Setenv("GOBASTION_SECURITY_PASSWORD_POLICY_MIN_LENGTH", "12")
+	t.Setenv("GOBASTION_SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+	t.Setenv("GOBASTION_RATE_LIMIT_POLICIES", `{"auth": {"limit": 1, "window_seconds": 5}}`)
+	t.Setenv("APP_PORT", ":6000") // GOBASTION_* wins over the legacy name
+
+	cfg, sources, err := Inspect(filepath.Join(t.TempDir(), "missing.json"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != ":7000" || sources.Of("server.port") != "GOBASTION_SERVER_PORT" {
+		t.Errorf("port = %q from %q", cfg.Server.Port, sources.Of("server.port"))
+	}
+	if cfg.Security.PasswordPolicy.MinLength != 12 {
+		t.Errorf("min_length = %d", cfg.Security.PasswordPolicy.MinLength)
+	}
+	if len(cfg.Server.TrustedProxies) != 2 || cfg.Server.TrustedProxies[1] != "192.168.1.1" {
+		t.Errorf("trusted_proxies = %q", cfg.Server.TrustedProxies)
+	}
+	if len(cfg.RateLimit.Policies) != 1 || cfg.RateLimit.Policies["auth"].Limit != 1 {
+		t.Errorf("policies = %+v", cfg.RateLimit.Policies)
+	}
+}
+
+func TestValidationListsEveryProblem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeAt(t, path, `{
+		"server": {"prot": ":8080", "read_timeout_seconds": "10"},
+		"logging": {"level": "verbose"}
+	}`)
+	t.Setenv("GOBASTION_SERVER_IDLE_TIMEOUT_SECONDS", "soon")
+	t.Setenv("GOBASTION_SERVER_PROT", ":8080")
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+
+	want := []string{
+		"server.prot: unknown key",
+		"server.read_timeout_seconds: expected a number, got a string",
+		"GOBASTION_SERVER_IDLE_TIMEOUT_SECONDS: server.idle_timeout_seconds: expected a whole number",
+		"GOBASTION_SERVER_PROT: unknown environment variable",
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("Expected %q in:\n%v", w, err)
+		}
+	}
+	// A file with schema errors isn't merged, so its other values aren't validated
+	if strings.Contains(err.Error(), "logging.level") {
+		t.Errorf("Unexpected logging.level problem in:\n%v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Defaults()
+	cfg.Server.Port = "8080"
+	cfg.Security.BcryptCost = 50
+	cfg.Logging.Level = "verbose"
+
+	var paths []string
+	for _, p := range cfg.Validate() {
+		paths = append(paths, p.Path)
+	}
+	if got := strings.Join(paths, " "); got != "server.port security.bcrypt_cost logging.level" {
+		t.Errorf("Problems at %q", got)
+	}
+	if problems := Defaults().Validate(); len(problems) > 0 {
+		t.Errorf("Defaults are invalid: %v", problems)
+	}
+}
+
+func TestMasked(t *testing.T) {
+	cfg := Defaults()
+	cfg.Security.JWTSecret = "super-secret"
+	cfg.Database.DSN = "postgres://app:hunter2@db:5432/app"
+	cfg.OAuth.Providers = []OIDCProviderConfig{{Name: "google", ClientSecret: "shh"}}
+
+	masked := cfg.Masked()
+	if masked.Security.JWTSecret != secretMask || masked.OAuth.Providers[0].ClientSecret != secretMask {
+		t.Errorf("Secrets not masked: %q, %q", masked.Security.JWTSecret, masked.OAuth.Providers[0].ClientSecret)
+	}
+	if strings.Contains(masked.Database.DSN, "hunter2") || masked.Database.DSN != "postgres://app:"+secretMask+"@db:5432/app" {
+		t.Errorf("dsn = %q", masked.Database.DSN)
+	}
+	if redactDSN("app:hunter2@tcp(db:3306)/app") != "app:"+secretMask+"@tcp(db:3306)/app" {
+		t.Errorf("MySQL DSN not redacted: %q", redactDSN("app:hunter2@tcp(db:3306)/app"))
+	}
+	// The original is untouched
+	if cfg.OAuth.Providers[0].ClientSecret != "shh" {
+		t.Error("Masked modified the original configuration")
+	}
+}
+
+func writeAt(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Reloader holds the configuration of the running server and reloads it when
+// the files change or the process receives SIGHUP, without a restart.
+//
+// Only the sections read on every request are swapped in: rate_limit (policies
+// and requests_per_minute), logging, features, frontend and admin. Everything
+// else is wired once at startup (listeners, database, middlewares, secrets),
+// so changes to it are reported as needing a restart and not applied, and
+// Current always describes what is actually running.
+//
+//	reloader := config.NewReloader("config/config.json", cfg)
+//	reloader.Subscribe(func(cfg *config.Config) { admin.SetFullConfig(cfg) })
+//	go reloader.Watch(ctx, 2*time.Second)
+type Reloader struct {
+	path string
+	env  string
+
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex // Serializes reloads and subscriptions
+	subscribers []func(*Config)
+}
+
+// ReloadResult lists the keys that changed in a reload, as JSON paths
+type ReloadResult struct {
+	Applied []string // Now in effect
+	Restart []string // Changed in the files but only applied after a restart
+}
+
+// NewReloader starts from cfg, loaded from path. Reloads keep its environment.
+func NewReloader(path string, cfg Config) *Reloader {
+	r := &Reloader{path: path, env: cfg.App.Environment}
+	r.current.Store(&cfg)
+	return r
+}
+
+// Current returns the configuration in effect. It must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// Subscribe calls fn with the new configuration after every reload that
+// changes a reloadable key, and once right away with the current one
+func (r *Reloader) Subscribe(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+	fn(r.Current())
+}
+
+// Reload loads and validates the files again and swaps in the reloadable
+// sections. An invalid configuration is returned as an error and nothing
+// changes.
+func (r *Reloader) Reload() (ReloadResult, error) {
+	loaded, err := LoadEnv(r.path, r.env)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.Current()
+	next := *old
+	next.RateLimit = loaded.RateLimit
+	next.RateLimit.Enabled = old.RateLimit.Enabled // The middleware is installed (or not) at startup
+	next.RateLimit.Store = old.RateLimit.Store
+	next.Logging = loaded.Logging
+	next.Features = loaded.Features
+	next.Frontend = loaded.Frontend
+	next.Admin = loaded.Admin
+
+	result := ReloadResult{
+		Applied: changedPaths(*old, next),
+		Restart: changedPaths(next, loaded),
+	}
+	if len(result.Applied) > 0 {
+		r.current.Store(&next)
+		for _, fn := range r.subscribers {
+			fn(&next)
+		}
+	}
+	return result, nil
+}
+
+// Watch reloads on SIGHUP and whenever the config file or its environment
+// overlay changes, checking every interval, until ctx is done. Results are
+// logged; an invalid file keeps the current configuration.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	files := []string{r.path, OverlayPath(r.path, r.env)}
+	stamp := fileStamps(files)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("config: SIGHUP received, reloading")
+		case <-ticker.C:
+			now := fileStamps(files)
+			if now == stamp {
+				continue
+			}
+			stamp = now
+			log.Println("config: file changed, reloading")
+		}
+		r.logReload()
+	}
+}
+
+func (r *Reloader) logReload() {
+	result, err := r.Reload()
+	switch {
+	case err != nil:
+		log.Printf("config: reload failed, keeping the current configuration: %v", err)
+	case len(result.Applied) == 0 && len(result.Restart) == 0:
+		log.Println("config: no changes")
+	default:
+		if len(result.Applied) > 0 {
+			log.Printf("config: applied %s", strings.Join(result.Applied, ", "))
+		}
+		if len(result.Restart) > 0 {
+			log.Printf("config: restart needed to apply %s", strings.Join(result.Restart, ", "))
+		}
+	}
+}
+
+// fileStamps summarizes the modification time and size of files (missing
+// files included), so any change to them changes the result
+func fileStamps(files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return b.String()
+}
+
+// changedPaths returns the JSON paths of the values that differ between a and b
+func changedPaths(a, b Config) []string {
+	fa, fb := flatten(a), flatten(b)
+	paths := make(map[string]bool, len(fa))
+	for path := range fa {
+		paths[path] = true
+	}
+	for path := range fb {
+		paths[path] = true
+	}
+
+	var changed []string
+	for _, path := range sortedKeys(paths) {
+		if va, ok := fa[path]; !ok || va != fb[path] {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// flatten maps every leaf of cfg (arrays count as leaves) to its JSON encoding
+func flatten(cfg Config) map[string]string {
+	data, _ := json.Marshal(cfg)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	dec.Decode(&value)
+
+	flat := make(map[string]string)
+	var walk func(path string, value any)
+	walk = func(path string, value any) {
+		if obj, ok := value.(map[string]any); ok {
+			for key, v := range obj {
+				walk(joinPath(path, key), v)
+			}
+			return
+		}
+		encoded, _ := json.Marshal(value)
+		flat[path] = string(encoded)
+	}
+	walk("", value)
+	return flat
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeAt(t, path, `{"server": {"port": ":8080"}, "logging": {"level": "info"}}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloader := NewReloader(path, cfg)
+	var seen []string
+	reloader.Subscribe(func(c *Config) { seen = append(seen, c.Logging.Level) })
+
+	writeAt(t, path, `{
+		"server": {"port": ":9090"},
+		"logging": {"level": "warn"},
+		"rate_limit": {"enabled": false, "requests_per_minute": 30}
+	}`)
+	result, err := reloader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"logging.level", "rate_limit.requests_per_minute"}; !slices.Equal(result.Applied, want) {
+		t.Errorf("Applied = %v, want %v", result.Applied, want)
+	}
+	if want := []string{"rate_limit.enabled", "server.port"}; !slices.Equal(result.Restart, want) {
+		t.Errorf("Restart = %v, want %v", result.Restart, want)
+	}
+
+	// Current describes what is running: the new level, the old port
+	current := reloader.Current()
+	if current.Logging.Level != "warn" || current.Server.Port != ":8080" || !current.RateLimit.Enabled {
+		t.Errorf("Unexpected current config: level %q, port %q, rate limit %v", current.Logging.Level, current.Server.Port, current.RateLimit.Enabled)
+	}
+	if !slices.Equal(seen, []string{"info", "warn"}) {
+		t.Errorf("Subscriber saw %v", seen)
+	}
+
+	// An invalid file changes nothing
+	writeAt(t, path, `{"logging": {"level": "loud"}}`)
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("Expected an invalid config to be rejected")
+	}
+	if reloader.Current().Logging.Level != "warn" || len(seen) != 2 {
+		t.Error("An invalid reload changed the configuration")
+	}
+}
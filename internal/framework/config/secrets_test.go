@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadResolvesReferences(t *testing.T) {
+	secretFile := writeFile(t, "client_secret", "from-a-file\n")
+	t.Setenv("TEST_JWT_SECRET", "from-the-environment")
+
+	path := writeFile(t, "config.json", `{
+		"database": {"dsn": "file:test.db?_foreign_keys=on"},
+		"security": {"jwt_secret": "${TEST_JWT_SECRET}", "csrf_header_name": "${TEST_UNSET:-X-CSRF}"},
+		"oauth": {"providers": [{"name": "google", "issuer_url": "https://accounts.google.com", "client_id": "id", "client_secret": "${file:`+secretFile+`}"}]}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Security.JWTSecret != "from-the-environment" {
+		t.Errorf("jwt_secret = %q", cfg.Security.JWTSecret)
+	}
+	if cfg.Security.CSRFHeaderName != "X-CSRF" {
+		t.Errorf("Expected the default of an unset variable, got %q", cfg.Security.CSRFHeaderName)
+	}
+	if cfg.OAuth.Providers[0].ClientSecret != "from-a-file" {
+		t.Errorf("client_secret = %q", cfg.OAuth.Providers[0].ClientSecret)
+	}
+	// Plain "file:" values are SQLite DSNs, not references
+	if cfg.Database.DSN != "file:test.db?_foreign_keys=on" {
+		t.Errorf("dsn = %q", cfg.Database.DSN)
+	}
+}
+
+func TestLoadReferenceErrors(t *testing.T) {
+	path := writeFile(t, "config.json", `{"security": {"jwt_secret": "${TEST_UNSET}"}}`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "security.jwt_secret") {
+		t.Errorf("Expected an error naming the unset reference, got %v", err)
+	}
+
+	path = writeFile(t, "config.json", `{"security": {"jwt_secret": "${file:/does/not/exist}"}}`)
+	if _, err := Load(path); err == nil {
+		t.Error("Expected a missing secret file to be an error")
+	}
+}
+
+func TestFileEnvOverride(t *testing.T) {
+	t.Setenv("APP_JWT_SECRET_FILE", writeFile(t, "jwt_secret", "mounted-secret\n"))
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Security.JWTSecret != "mounted-secret" {
+		t.Errorf("jwt_secret = %q", cfg.Security.JWTSecret)
+	}
+
+	// The variable itself wins over its _FILE variant
+	t.Setenv("APP_JWT_SECRET", "direct")
+	if cfg, _ := Load(filepath.Join(t.TempDir(), "missing.json")); cfg.Security.JWTSecret != "direct" {
+		t.Errorf("jwt_secret = %q, want the APP_JWT_SECRET value", cfg.Security.JWTSecret)
+	}
+}
+
+func TestProductionRefusesWeakSecrets(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	t.Setenv("APP_ENVIRONMENT", "production")
+
+	if _, err := Load(missing); err == nil || !strings.Contains(err.Error(), "jwt_secret") {
+		t.Errorf("Expected the default secret to be refused, got %v", err)
+	}
+
+	t.Setenv("APP_JWT_SECRET", "too-short")
+	if _, err := Load(missing); err == nil {
+		t.Error("Expected a short secret to be refused")
+	}
+
+	secret, err := GenerateSecret(48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_JWT_SECRET", secret)
+	cfg, err := Load(missing)
+	if err != nil {
+		t.Fatalf("Expected a generated secret to be accepted, got %v", err)
+	}
+	if !cfg.Security.SecureCookies {
+		t.Error("Expected production to force secure cookies")
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// FieldError is a problem with one configuration value
+type FieldError struct {
+	Path    string // JSON path, e.g. "server.port" ("" = the whole source)
+	Source  string // File or environment variable the value came from ("" = default)
+	Message string
+}
+
+func (e FieldError) Error() string {
+	var parts []string
+	if e.Source != "" {
+		parts = append(parts, e.Source)
+	}
+	if e.Path != "" {
+		parts = append(parts, e.Path)
+	}
+	return strings.Join(append(parts, e.Message), ": ")
+}
+
+// ValidationError lists every problem found while loading the configuration,
+// so they can all be fixed at once
+type ValidationError struct {
+	Problems []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if len(e.Problems) == 1 {
+		b.WriteString("invalid configuration (1 problem):")
+	} else {
+		fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	}
+	for _, p := range e.Problems {
+		b.WriteString("\n  - " + p.Error())
+	}
+	return b.String()
+}
+
+// Validate checks the values of a merged configuration that JSON types alone
+// can't catch
+func (c Config) Validate() []FieldError {
+	var v validator
+
+	v.check(envNamePattern.MatchString(c.App.Environment), "app.environment", "must be a lower-case name such as \"production\"")
+
+	v.check(validAddr(c.Server.Port), "server.port", "must be an address like \":8080\" or \"127.0.0.1:8080\"")
+	v.check(c.Server.ReadTimeoutSeconds >= 0, "server.read_timeout_seconds", "must not be negative")
+	v.check(c.Server.WriteTimeoutSeconds >= 0, "server.write_timeout_seconds", "must not be negative")
+	v.check(c.Server.IdleTimeoutSeconds >= 0, "server.idle_timeout_seconds", "must not be negative")
+
+	v.check(c.Database.Driver != "", "database.driver", "is required")
+	v.check(c.Database.DSN != "", "database.dsn", "is required")
+	v.check(c.Database.MaxOpenConns >= 0, "database.max_open_conns", "must not be negative")
+	v.check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+
+	s := c.Security
+	if s.EnableCSRF {
+		v.check(s.CSRFHeaderName != "", "security.csrf_header_name", "is required when enable_csrf is on")
+		v.check(s.CSRFCookieName != "", "security.csrf_cookie_name", "is required when enable_csrf is on")
+	}
+	if s.EnableJWT {
+		v.check(s.AccessTokenMinutes > 0, "security.access_token_minutes", "must be positive when enable_jwt is on")
+		v.check(s.RefreshTokenMinutes > 0, "security.refresh_token_minutes", "must be positive when enable_jwt is on")
+	}
+	v.check(s.MaxBodyBytes > 0, "security.max_body_bytes", "must be positive")
+	v.check(s.BcryptCost >= 4 && s.BcryptCost <= 31, "security.bcrypt_cost", "must be between 4 and 31")
+	v.check(s.PasswordPolicy.MinLength >= 1, "security.password_policy.min_length", "must be at least 1")
+	v.check(s.PasswordPolicy.MaxLength <= 72, "security.password_policy.max_length", "must be at most 72 (bcrypt ignores longer passwords)")
+	v.check(s.PasswordPolicy.MinLength <= s.PasswordPolicy.MaxLength, "security.password_policy.min_length", "must not exceed max_length")
+	v.check(s.PasswordPolicy.HistorySize >= 0, "security.password_policy.history_size", "must not be negative")
+
+	v.oneOf(c.RateLimit.Store, "rate_limit.store", "", "memory", "database")
+	v.check(c.RateLimit.RequestsPerMinute >= 0, "rate_limit.requests_per_minute", "must not be negative")
+	for _, name := range sortedKeys(c.RateLimit.Policies) {
+		p := c.RateLimit.Policies[name]
+		path := "rate_limit.policies." + name
+		v.oneOf(p.Algorithm, path+".algorithm", "", "sliding_window", "token_bucket")
+		v.oneOf(p.Key, path+".key", "", "ip", "user", "api_key")
+		v.check(p.Limit >= 0, path+".limit", "must not be negative")
+		v.check(p.Limit == 0 || p.WindowSeconds > 0, path+".window_seconds", "must be positive")
+	}
+
+	for _, name := range sortedKeys(c.CORS.Policies) {
+		p := c.CORS.Policies[name]
+		for _, origin := range p.AllowedOrigins {
+			if origin == "*" && p.AllowCredentials {
+				v.check(false, "cors.policies."+name+".allow_credentials", "can't be combined with the \"*\" origin")
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, p := range c.OAuth.Providers {
+		path := fmt.Sprintf("oauth.providers[%d]", i)
+		v.check(p.Name != "", path+".name", "is required")
+		v.check(!seen[p.Name], path+".name", fmt.Sprintf("%q is used by another provider", p.Name))
+		v.check(p.IssuerURL != "", path+".issuer_url", "is required")
+		v.check(p.ClientID != "", path+".client_id", "is required")
+		seen[p.Name] = true
+	}
+
+	v.oneOf(c.Logging.Level, "logging.level", "debug", "info", "warn", "error")
+	v.oneOf(c.Logging.Format, "logging.format", "text", "json")
+
+	return v.problems
+}
+
+type validator struct {
+	problems []FieldError
+}
+
+func (v *validator) check(ok bool, path, message string) {
+	if !ok {
+		v.problems = append(v.problems, FieldError{Path: path, Message: message})
+	}
+}
+
+func (v *validator) oneOf(value, path string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	var quoted []string
+	for _, a := range allowed {
+		if a != "" {
+			quoted = append(quoted, strconv.Quote(a))
+		}
+	}
+	v.check(false, path, fmt.Sprintf("is %q, must be one of %s", value, strings.Join(quoted, ", ")))
+}
+
+// validAddr reports whether addr is a listen address with a valid port
+func validAddr(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 0 && n <= 65535
+}
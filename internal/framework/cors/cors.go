@@ -0,0 +1,254 @@
+// Package cors implements Cross-Origin Resource Sharing policies.
+//
+// Policies are named in config.json (cors.policies) and attached to routes with
+// router.CORS("name"), usually for a whole group:
+//
+//	api := r.Group("/api", router.CORS("api"))
+//
+// Routes without one use the "default" policy, which is built from
+// server.allowed_origins unless cors.policies.default is configured.
+//
+// middleware.CORS adds the response headers to actual requests, and
+// middleware.CORSPreflight answers preflight (OPTIONS) requests, which the
+// router dispatches to it with the policy of the route being asked about.
+// Disallowed origins get no CORS headers, so browsers block the response;
+// disallowed preflights are answered 403.
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+)
+
+// DefaultPolicy is used by routes that don't declare a policy
+const DefaultPolicy = "default"
+
+// Policy is a compiled CORS policy
+type Policy struct {
+	Name             string
+	anyOrigin        bool
+	origins          map[string]bool
+	patterns         []*regexp.Regexp
+	methods          map[string]bool
+	allowMethods     string
+	anyHeader        bool
+	headers          map[string]bool
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// New compiles a policy
+func New(name string, cfg config.CORSPolicyConfig) (*Policy, error) {
+	p := &Policy{
+		Name:             name,
+		origins:          make(map[string]bool),
+		methods:          map[string]bool{"GET": true, "HEAD": true, "POST": true},
+		headers:          make(map[string]bool),
+		allowCredentials: cfg.AllowCredentials,
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimRight(origin, "/"))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			// "https://*.example.com" matches any subdomain, but not example.com itself
+			pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, `[a-z0-9-]+(\.[a-z0-9-]+)*`) + "$"
+			p.patterns = append(p.patterns, regexp.MustCompile(pattern))
+		default:
+			p.origins[origin] = true
+		}
+	}
+	for _, pattern := range cfg.AllowedOriginPatterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("cors policy %q: invalid origin pattern %q: %w", name, pattern, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	if p.anyOrigin && p.allowCredentials {
+		return nil, fmt.Errorf("cors policy %q: allow_credentials can't be combined with the \"*\" origin", name)
+	}
+
+	var methods []string
+	for _, method := range cfg.AllowedMethods {
+		method = strings.ToUpper(method)
+		p.methods[method] = true
+		methods = append(methods, method)
+	}
+	p.allowMethods = strings.Join(methods, ", ")
+
+	for _, header := range cfg.AllowedHeaders {
+		if header == "" {
+			continue
+		}
+		if header == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers[strings.ToLower(header)] = true
+	}
+	if p.anyHeader && p.allowCredentials {
+		return nil, fmt.Errorf("cors policy %q: allow_credentials can't be combined with the \"*\" header", name)
+	}
+
+	p.exposedHeaders = strings.Join(cfg.ExposedHeaders, ", ")
+	if cfg.MaxAgeSeconds > 0 {
+		p.maxAge = strconv.Itoa(cfg.MaxAgeSeconds)
+	}
+	return p, nil
+}
+
+// AllowsOrigin reports whether scripts from origin may call routes using the policy
+func (p *Policy) AllowsOrigin(origin string) bool {
+	if origin == "" || origin == "null" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin sets the headers every allowed cross-origin response carries
+func (p *Policy) allowOrigin(h http.Header, origin string) {
+	if p.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Actual adds the CORS headers to the response of a non-preflight request
+func (p *Policy) Actual(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	if !p.anyOrigin {
+		// The response depends on Origin, so caches must keep one copy per origin
+		h.Add("Vary", "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if !p.AllowsOrigin(origin) {
+		return
+	}
+	p.allowOrigin(h, origin)
+	if p.exposedHeaders != "" {
+		h.Set("Access-Control-Expose-Headers", p.exposedHeaders)
+	}
+}
+
+// IsPreflight reports whether r is a CORS preflight request
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// Preflight checks a preflight request against the policy. When it is allowed
+// it sets the response headers and returns nil; otherwise it returns why not,
+// and the response must not be a success.
+func (p *Policy) Preflight(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	if !p.AllowsOrigin(origin) {
+		return fmt.Errorf("origin %s is not allowed", origin)
+	}
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !p.methods[method] {
+		return fmt.Errorf("method %s is not allowed", method)
+	}
+
+	var headers []string
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		if !p.anyHeader && !p.headers[header] {
+			return fmt.Errorf("header %s is not allowed", header)
+		}
+		headers = append(headers, header)
+	}
+
+	p.allowOrigin(h, origin)
+	if p.allowMethods != "" {
+		h.Set("Access-Control-Allow-Methods", p.allowMethods)
+	} else {
+		h.Set("Access-Control-Allow-Methods", method)
+	}
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	return nil
+}
+
+// Policies are the configured policies by name
+type Policies map[string]*Policy
+
+// FromConfig compiles cors.policies. Without a "default" policy one is built
+// from server.allowed_origins, allowing credentials unless it contains "*".
+func FromConfig(cfg config.Config) (Policies, error) {
+	policies := make(Policies, len(cfg.CORS.Policies)+1)
+	for name, pc := range cfg.CORS.Policies {
+		p, err := New(name, pc)
+		if err != nil {
+			return nil, err
+		}
+		policies[name] = p
+	}
+
+	if _, ok := policies[DefaultPolicy]; !ok {
+		def := config.CORSPolicyConfig{
+			AllowedOrigins:   cfg.Server.AllowedOrigins,
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders:   []string{"Content-Type", "Authorization", cfg.Security.CSRFHeaderName},
+			AllowCredentials: true,
+			MaxAgeSeconds:    600,
+		}
+		for _, origin := range def.AllowedOrigins {
+			if origin == "*" {
+				def.AllowCredentials = false
+			}
+		}
+		p, err := New(DefaultPolicy, def)
+		if err != nil {
+			return nil, err
+		}
+		policies[DefaultPolicy] = p
+	}
+	return policies, nil
+}
+
+// Get returns the named policy ("" = default). Unknown names fall back to the
+// default policy; `go-bastion routes` warns about them.
+func (ps Policies) Get(name string) *Policy {
+	if p, ok := ps[name]; ok {
+		return p
+	}
+	return ps[DefaultPolicy]
+}
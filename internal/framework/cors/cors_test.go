@@ -0,0 +1,128 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+)
+
+func TestAllowsOrigin(t *testing.T) {
+	p, err := New("test", config.CORSPolicyConfig{
+		AllowedOrigins:        []string{"https://app.example.com/", "https://*.example.org"},
+		AllowedOriginPatterns: []string{`http://localhost:\d+`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"https://app.example.com":       true,
+		"HTTPS://APP.EXAMPLE.COM":       true,
+		"http://app.example.com":        false,
+		"https://a.b.example.org":       true,
+		"https://example.org":           false,
+		"https://evil.com/.example.org": false,
+		"http://localhost:3000":         true,
+		"http://localhost:3000.evil":    false,
+		"null":                          false,
+		"":                              false,
+	}
+	for origin, want := range tests {
+		if got := p.AllowsOrigin(origin); got != want {
+			t.Errorf("AllowsOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestActual(t *testing.T) {
+	p, _ := New("test", config.CORSPolicyConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	p.Actual(w, r)
+	h := w.Header()
+	if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+		h.Get("Access-Control-Expose-Headers") != "X-Request-ID" || h.Get("Vary") != "Origin" {
+		t.Errorf("Allowed origin: unexpected headers %v", h)
+	}
+
+	r.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	p.Actual(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Vary") != "Origin" {
+		t.Errorf("Disallowed origin: unexpected headers %v", w.Header())
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	p, _ := New("test", config.CORSPolicyConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAgeSeconds:  600,
+	})
+
+	preflight := func(origin, method, headers string) (http.Header, error) {
+		r := httptest.NewRequest("OPTIONS", "/items", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		w := httptest.NewRecorder()
+		err := p.Preflight(w, r)
+		return w.Header(), err
+	}
+
+	h, err := preflight("https://app.example.com", "DELETE", "authorization, content-type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("Access-Control-Allow-Methods") != "GET, DELETE" || h.Get("Access-Control-Allow-Headers") != "authorization, content-type" ||
+		h.Get("Access-Control-Max-Age") != "600" || h.Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Unexpected preflight headers %v", h)
+	}
+	if len(h.Values("Vary")) != 3 {
+		t.Errorf("Expected Vary on Origin and the request headers, got %v", h.Values("Vary"))
+	}
+
+	for _, tt := range [][3]string{
+		{"https://evil.example", "DELETE", ""},
+		{"https://app.example.com", "PUT", ""},
+		{"https://app.example.com", "DELETE", "X-Custom"},
+	} {
+		h, err := preflight(tt[0], tt[1], tt[2])
+		if err == nil || h.Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected preflight %v to be rejected without CORS headers", tt)
+		}
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	_, err := FromConfig(config.Config{CORS: config.CORSConfig{Policies: map[string]config.CORSPolicyConfig{
+		"bad": {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+	}}})
+	if err == nil {
+		t.Error("Expected credentials with the \"*\" origin to be rejected")
+	}
+
+	// The default policy comes from server.allowed_origins; "*" drops credentials
+	policies, err := FromConfig(config.Config{Server: config.ServerConfig{AllowedOrigins: []string{"*"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://anywhere.example")
+	w := httptest.NewRecorder()
+	policies.Get("unknown").Actual(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Unexpected headers %v", w.Header())
+	}
+}
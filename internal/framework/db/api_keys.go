@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// APIKey is a personal access token. Only a hash of the secret is stored;
+// Prefix is the public part used to look the key up and to identify it in lists.
+// Zero times mean "never" (no expiry, never used, not revoked).
+type APIKey struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	LastUsedIP string
+	RevokedAt  time.Time
+	CreatedAt  time.Time
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at"
+
+// CreateAPIKey stores a new API key and returns it with its ID set
+func CreateAPIKey(ctx context.Context, k APIKey) (APIKey, error) {
+	data := map[string]any{
+		"user_id":  k.UserID,
+		"name":     k.Name,
+		"prefix":   k.Prefix,
+		"key_hash": k.KeyHash,
+		"scopes":   strings.Join(k.Scopes, " "),
+	}
+	if !k.ExpiresAt.IsZero() {
+		data["expires_at"] = k.ExpiresAt.Unix()
+	}
+
+	id, err := Insert(ctx, "api_keys", data)
+	if err != nil {
+		return APIKey{}, err
+	}
+	return GetAPIKey(ctx, id)
+}
+
+// GetAPIKey retrieves an API key by ID
+func GetAPIKey(ctx context.Context, id int64) (APIKey, error) {
+	row := DB.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ?", id)
+	return scanAPIKey(row)
+}
+
+// GetAPIKeyByPrefix retrieves an API key by its public prefix
+func GetAPIKeyByPrefix(ctx context.Context, prefix string) (APIKey, error) {
+	row := DB.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = ?", prefix)
+	return scanAPIKey(row)
+}
+
+// ListAPIKeys returns all API keys of a user, newest first
+func ListAPIKeys(ctx context.Context, userID int64) ([]APIKey, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+// CountActiveAPIKeys counts a user's keys that are neither revoked nor expired
+func CountActiveAPIKeys(ctx context.Context, userID int64, now time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM api_keys WHERE user_id = ? AND revoked_at = 0 AND (expires_at = 0 OR expires_at > ?)"
+	var n int
+	err := DB.QueryRowContext(ctx, query, userID, now.Unix()).Scan(&n)
+	return n, err
+}
+
+// RevokeAPIKey marks a key as revoked. Revoking an already revoked key is a no-op.
+func RevokeAPIKey(ctx context.Context, id int64, now time.Time) error {
+	result, err := DB.ExecContext(ctx, "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at = 0", now.Unix(), id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := GetAPIKey(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TouchAPIKey records a use of the key. Writes are skipped if the key was
+// already marked as used within the last minute, so busy clients don't cause
+// a write per request.
+func TouchAPIKey(ctx context.Context, id int64, ip string, now time.Time) error {
+	query := "UPDATE api_keys SET last_used_at = ?, last_used_ip = ? WHERE id = ? AND last_used_at < ?"
+	_, err := DB.ExecContext(ctx, query, now.Unix(), ip, id, now.Add(-time.Minute).Unix())
+	return err
+}
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row interface{ Scan(...any) error }) (APIKey, error) {
+	var k APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt int64
+	var createdAt sql.NullTime
+
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, &scopes, &expiresAt, &lastUsedAt, &k.LastUsedIP, &revokedAt, &createdAt)
+	if err == sql.ErrNoRows {
+		return APIKey{}, ErrNotFound
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	k.Scopes = strings.Fields(scopes)
+	k.ExpiresAt = unixOrZero(expiresAt)
+	k.LastUsedAt = unixOrZero(lastUsedAt)
+	k.RevokedAt = unixOrZero(revokedAt)
+	k.CreatedAt = createdAt.Time
+	return k, nil
+}
+
+// unixOrZero converts a unix timestamp column where 0 means "not set"
+func unixOrZero(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
@@ -64,6 +64,88 @@ func migrate() error {
 
 	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 	CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+
+	CREATE TABLE IF NOT EXISTS user_totp (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret TEXT NOT NULL,
+		enabled INTEGER NOT NULL DEFAULT 0,
+		last_used_step INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS user_recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		used_at TIMESTAMP NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON user_recovery_codes(user_id);
+
+	CREATE TABLE IF NOT EXISTS login_failures (
+		key TEXT PRIMARY KEY,
+		failures INTEGER NOT NULL DEFAULT 0,
+		last_failure_at INTEGER NOT NULL DEFAULT 0,
+		locked_until INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS security_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_type TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		ip TEXT NOT NULL DEFAULT '',
+		detail TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_security_events_created ON security_events(created_at);
+
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		provider TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		email TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(provider, subject),
+		UNIQUE(user_id, provider)
+	);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL UNIQUE,
+		key_hash TEXT NOT NULL,
+		scopes TEXT NOT NULL DEFAULT '',
+		expires_at INTEGER NOT NULL DEFAULT 0,
+		last_used_at INTEGER NOT NULL DEFAULT 0,
+		last_used_ip TEXT NOT NULL DEFAULT '',
+		revoked_at INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+	CREATE TABLE IF NOT EXISTS password_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_password_history_user ON password_history(user_id);
+
+	CREATE TABLE IF NOT EXISTS rate_limits (
+		key TEXT PRIMARY KEY,
+		tokens REAL NOT NULL DEFAULT 0,
+		previous REAL NOT NULL DEFAULT 0,
+		stamp INTEGER NOT NULL DEFAULT 0,
+		version INTEGER NOT NULL DEFAULT 0,
+		expires_at INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rate_limits_expires ON rate_limits(expires_at);
 	`
 
 	_, err := DB.Exec(schema)
@@ -127,6 +209,12 @@ func CreateUser(ctx context.Context, in models.RegisterInput, passwordHash strin
 		return models.User{}, err
 	}
 
+	if passwordHash != "" {
+		if err := addPasswordHistory(ctx, id, passwordHash); err != nil {
+			return models.User{}, err
+		}
+	}
+
 	return GetUser(ctx, int(id))
 }
 
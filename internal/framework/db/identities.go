@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/app/models"
+)
+
+// ErrIdentityLinked is returned when an external identity already belongs to another user
+var ErrIdentityLinked = errors.New("identity already linked to another account")
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetUserByIdentity retrieves the user linked to a provider subject
+func GetUserByIdentity(ctx context.Context, provider, subject string) (models.User, error) {
+	var userID int64
+	query := "SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?"
+	err := DB.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return models.User{}, ErrNotFound
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	return GetUser(ctx, int(userID))
+}
+
+// LinkIdentity attaches an external identity to a user. Linking the same identity
+// to the same user again is a no-op; linking it to a different user returns ErrIdentityLinked.
+func LinkIdentity(ctx context.Context, userID int64, provider, subject, email string) error {
+	existing, err := GetUserByIdentity(ctx, provider, subject)
+	if err == nil {
+		if existing.ID != userID {
+			return ErrIdentityLinked
+		}
+		return nil
+	}
+	if err != ErrNotFound {
+		return err
+	}
+
+	data := map[string]any{
+		"user_id":  userID,
+		"provider": provider,
+		"subject":  subject,
+		"email":    email,
+	}
+	_, err = Insert(ctx, "user_identities", data)
+	return err
+}
+
+// ListUserIdentities returns the external identities linked to a user
+func ListUserIdentities(ctx context.Context, userID int64) ([]UserIdentity, error) {
+	rows, err := FindManyBy(ctx, "user_identities", map[string]any{"user_id": userID}, "provider", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []UserIdentity
+	for rows.Next() {
+		var i UserIdentity
+		var createdAt sql.NullTime
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &createdAt); err != nil {
+			return nil, err
+		}
+		i.CreatedAt = createdAt.Time
+		identities = append(identities, i)
+	}
+
+	return identities, rows.Err()
+}
+
+// UnlinkIdentity removes a user's identity for a provider
+func UnlinkIdentity(ctx context.Context, userID int64, provider string) error {
+	result, err := DB.ExecContext(ctx, "DELETE FROM user_identities WHERE user_id = ? AND provider = ?", userID, provider)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
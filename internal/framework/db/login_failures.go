@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LoginFailure tracks failed login attempts for a key ("account:<email>" or "ip:<addr>")
+type LoginFailure struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// SecurityEvent is an audit entry such as an account lockout or unlock
+type SecurityEvent struct {
+	ID        int64
+	EventType string
+	Subject   string
+	IP        string
+	Detail    string
+	CreatedAt time.Time
+}
+
+// GetLoginFailure retrieves the failure record for a key
+func GetLoginFailure(ctx context.Context, key string) (LoginFailure, error) {
+	var f LoginFailure
+	var lastFailure, lockedUntil int64
+
+	query := "SELECT key, failures, last_failure_at, locked_until FROM login_failures WHERE key = ?"
+	err := DB.QueryRowContext(ctx, query, key).Scan(&f.Key, &f.Failures, &lastFailure, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return LoginFailure{Key: key}, ErrNotFound
+	}
+	if err != nil {
+		return LoginFailure{}, err
+	}
+
+	f.LastFailureAt = time.Unix(lastFailure, 0)
+	f.LockedUntil = time.Unix(lockedUntil, 0)
+	return f, nil
+}
+
+// IncrementLoginFailure records a failure for a key and returns the new failure count.
+// Failures older than window are forgotten before counting.
+func IncrementLoginFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	query := `
+	INSERT INTO login_failures (key, failures, last_failure_at, locked_until)
+	VALUES (?, 1, ?, 0)
+	ON CONFLICT(key) DO UPDATE SET
+		failures = CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END,
+		last_failure_at = excluded.last_failure_at`
+	if _, err := DB.ExecContext(ctx, query, key, now.Unix(), now.Add(-window).Unix()); err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err := DB.QueryRowContext(ctx, "SELECT failures FROM login_failures WHERE key = ?", key).Scan(&failures)
+	return failures, err
+}
+
+// LockLoginKey locks a key until the given time and resets its failure count
+func LockLoginKey(ctx context.Context, key string, until time.Time) error {
+	_, err := DB.ExecContext(ctx, "UPDATE login_failures SET locked_until = ?, failures = 0 WHERE key = ?", until.Unix(), key)
+	return err
+}
+
+// ClearLoginFailures removes all failure tracking for a key
+func ClearLoginFailures(ctx context.Context, key string) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM login_failures WHERE key = ?", key)
+	return err
+}
+
+// ListLockedLoginKeys returns all keys currently locked out
+func ListLockedLoginKeys(ctx context.Context, now time.Time) ([]LoginFailure, error) {
+	query := "SELECT key, failures, last_failure_at, locked_until FROM login_failures WHERE locked_until > ? ORDER BY locked_until DESC"
+	rows, err := DB.QueryContext(ctx, query, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locked []LoginFailure
+	for rows.Next() {
+		var f LoginFailure
+		var lastFailure, lockedUntil int64
+		if err := rows.Scan(&f.Key, &f.Failures, &lastFailure, &lockedUntil); err != nil {
+			return nil, err
+		}
+		f.LastFailureAt = time.Unix(lastFailure, 0)
+		f.LockedUntil = time.Unix(lockedUntil, 0)
+		locked = append(locked, f)
+	}
+
+	return locked, rows.Err()
+}
+
+// RecordSecurityEvent stores an audit event
+func RecordSecurityEvent(ctx context.Context, eventType, subject, ip, detail string) error {
+	data := map[string]any{
+		"event_type": eventType,
+		"subject":    subject,
+		"ip":         ip,
+		"detail":     detail,
+	}
+	_, err := Insert(ctx, "security_events", data)
+	return err
+}
+
+// ListSecurityEvents returns the most recent security events
+func ListSecurityEvents(ctx context.Context, limit int) ([]SecurityEvent, error) {
+	rows, err := FindManyBy(ctx, "security_events", map[string]any{}, "id DESC", limit, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []SecurityEvent
+	for rows.Next() {
+		var e SecurityEvent
+		var createdAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.EventType, &e.Subject, &e.IP, &e.Detail, &createdAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = createdAt.Time
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
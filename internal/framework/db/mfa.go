@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// UserTOTP holds a user's TOTP enrollment
+type UserTOTP struct {
+	UserID       int64
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+// GetUserTOTP retrieves the TOTP enrollment for a user
+func GetUserTOTP(ctx context.Context, userID int64) (UserTOTP, error) {
+	var t UserTOTP
+	var enabled int
+
+	query := "SELECT user_id, secret, enabled, last_used_step FROM user_totp WHERE user_id = ?"
+	err := DB.QueryRowContext(ctx, query, userID).Scan(&t.UserID, &t.Secret, &enabled, &t.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return UserTOTP{}, ErrNotFound
+	}
+	if err != nil {
+		return UserTOTP{}, err
+	}
+
+	t.Enabled = enabled == 1
+	return t, nil
+}
+
+// IsTOTPEnabled reports whether the user has confirmed TOTP enrollment
+func IsTOTPEnabled(ctx context.Context, userID int64) (bool, error) {
+	t, err := GetUserTOTP(ctx, userID)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return t.Enabled, nil
+}
+
+// SavePendingTOTP stores a new, not yet confirmed TOTP secret for a user,
+// replacing any previous pending enrollment
+func SavePendingTOTP(ctx context.Context, userID int64, secret string) error {
+	query := `
+	INSERT INTO user_totp (user_id, secret, enabled, last_used_step)
+	VALUES (?, ?, 0, 0)
+	ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, enabled = 0, last_used_step = 0`
+	_, err := DB.ExecContext(ctx, query, userID, secret)
+	return err
+}
+
+// EnableTOTP confirms a pending TOTP enrollment
+func EnableTOTP(ctx context.Context, userID int64, step int64) error {
+	result, err := DB.ExecContext(ctx, "UPDATE user_totp SET enabled = 1, last_used_step = ? WHERE user_id = ?", step, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkTOTPStepUsed records the last accepted time step to block code replay.
+// Returns ErrNotFound if a concurrent request already consumed this step.
+func MarkTOTPStepUsed(ctx context.Context, userID int64, step int64) error {
+	query := "UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?"
+	result, err := DB.ExecContext(ctx, query, step, userID, step)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUserTOTP removes a user's TOTP enrollment and recovery codes (2FA reset)
+func DeleteUserTOTP(ctx context.Context, userID int64) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes discards existing recovery codes and stores the given hashes
+func ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode consumes an unused recovery code. Returns ErrNotFound if the
+// code does not exist or was already used.
+func UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	query := "UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = ? AND code_hash = ? AND used_at IS NULL"
+	result, err := DB.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes a user has left
+func CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// passwordHistoryKeep bounds the stored history per user, well above any sensible history_size
+const passwordHistoryKeep = 24
+
+// GetPasswordHash returns a user's current password hash ("" for accounts without a local password)
+func GetPasswordHash(ctx context.Context, userID int64) (string, error) {
+	var hash string
+	err := DB.QueryRowContext(ctx, "SELECT password_hash FROM users WHERE id = ?", userID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return hash, err
+}
+
+// SetUserPassword stores a new password hash and records it in the password history
+func SetUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	if err := UpdateByID(ctx, "users", userID, map[string]any{"password_hash": passwordHash}); err != nil {
+		return err
+	}
+	return addPasswordHistory(ctx, userID, passwordHash)
+}
+
+// RehashUserPassword replaces the stored hash of the same password (e.g. after a
+// bcrypt cost change) without adding a history entry
+func RehashUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	return UpdateByID(ctx, "users", userID, map[string]any{"password_hash": passwordHash})
+}
+
+// ListPasswordHistory returns a user's most recent password hashes, newest first
+func ListPasswordHistory(ctx context.Context, userID int64, limit int) ([]string, error) {
+	query := "SELECT password_hash FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?"
+	rows, err := DB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, h)
+	}
+
+	return hashes, rows.Err()
+}
+
+// addPasswordHistory records a password hash and prunes entries beyond passwordHistoryKeep
+func addPasswordHistory(ctx context.Context, userID int64, passwordHash string) error {
+	if _, err := Insert(ctx, "password_history", map[string]any{"user_id": userID, "password_hash": passwordHash}); err != nil {
+		return err
+	}
+
+	query := `
+	DELETE FROM password_history WHERE user_id = ? AND id NOT IN (
+		SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?
+	)`
+	_, err := DB.ExecContext(ctx, query, userID, userID, passwordHistoryKeep)
+	return err
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RateLimitState is the stored counter of one rate limit key (see package ratelimit).
+// Version implements optimistic locking so instances sharing the database never
+// overwrite each other's updates.
+type RateLimitState struct {
+	Key       string
+	Tokens    float64
+	Previous  float64
+	Stamp     time.Time
+	Version   int64
+	ExpiresAt time.Time
+}
+
+// GetRateLimitState retrieves the counter for a key
+func GetRateLimitState(ctx context.Context, key string) (RateLimitState, error) {
+	s := RateLimitState{Key: key}
+	var stamp, expiresAt int64
+
+	query := "SELECT tokens, previous, stamp, version, expires_at FROM rate_limits WHERE key = ?"
+	err := DB.QueryRowContext(ctx, query, key).Scan(&s.Tokens, &s.Previous, &stamp, &s.Version, &expiresAt)
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	if err != nil {
+		return RateLimitState{}, err
+	}
+
+	s.Stamp = time.Unix(0, stamp)
+	s.ExpiresAt = time.Unix(0, expiresAt)
+	return s, nil
+}
+
+// InsertRateLimitState stores the first counter for a key. It returns false
+// when another instance created it first.
+func InsertRateLimitState(ctx context.Context, s RateLimitState) (bool, error) {
+	query := `
+	INSERT INTO rate_limits (key, tokens, previous, stamp, version, expires_at)
+	VALUES (?, ?, ?, ?, 1, ?)
+	ON CONFLICT(key) DO NOTHING`
+	res, err := DB.ExecContext(ctx, query, s.Key, s.Tokens, s.Previous, s.Stamp.UnixNano(), s.ExpiresAt.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// UpdateRateLimitState saves a counter read at s.Version. It returns false when
+// another instance updated it in the meantime.
+func UpdateRateLimitState(ctx context.Context, s RateLimitState) (bool, error) {
+	query := `
+	UPDATE rate_limits SET tokens = ?, previous = ?, stamp = ?, version = version + 1, expires_at = ?
+	WHERE key = ? AND version = ?`
+	res, err := DB.ExecContext(ctx, query, s.Tokens, s.Previous, s.Stamp.UnixNano(), s.ExpiresAt.UnixNano(), s.Key, s.Version)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteExpiredRateLimits removes counters that have returned to their initial state
+func DeleteExpiredRateLimits(ctx context.Context, now time.Time) error {
+	_, err := DB.ExecContext(ctx, "DELETE FROM rate_limits WHERE expires_at < ?", now.UnixNano())
+	return err
+}
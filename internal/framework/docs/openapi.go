@@ -2,7 +2,9 @@ package docs
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/AlejandroMBJS/goBastion/internal/framework/middleware"
 	frameworkrouter "github.com/AlejandroMBJS/goBastion/internal/framework/router"
 )
 
@@ -68,6 +70,35 @@ var OpenAPIJSON = []byte(`{
           "password": { "type": "string" }
         }
       },
+      "TwoFactorCodeInput": {
+        "type": "object",
+        "required": ["code"],
+        "properties": {
+          "code": { "type": "string", "description": "6-digit TOTP code or recovery code" }
+        }
+      },
+      "TwoFactorVerifyInput": {
+        "type": "object",
+        "required": ["challenge_token", "code"],
+        "properties": {
+          "challenge_token": { "type": "string" },
+          "code": { "type": "string", "description": "6-digit TOTP code or recovery code" }
+        }
+      },
+      "APIKey": {
+        "type": "object",
+        "properties": {
+          "id": { "type": "integer" },
+          "name": { "type": "string" },
+          "prefix": { "type": "string", "description": "Public part of the key, used to identify it" },
+          "scopes": { "type": "array", "items": { "type": "string" } },
+          "expires_at": { "type": "string", "format": "date-time", "nullable": true },
+          "last_used_at": { "type": "string", "format": "date-time", "nullable": true },
+          "last_used_ip": { "type": "string" },
+          "revoked_at": { "type": "string", "format": "date-time" },
+          "created_at": { "type": "string", "format": "date-time" }
+        }
+      },
       "RefreshInput": {
         "type": "object",
         "required": ["refresh_token"],
@@ -154,7 +185,7 @@ var OpenAPIJSON = []byte(`{
         },
         "responses": {
           "200": {
-            "description": "Login successful",
+            "description": "Login successful, or a two-factor challenge when mfa_required is true",
             "content": {
               "application/json": {
                 "schema": {
@@ -163,7 +194,9 @@ var OpenAPIJSON = []byte(`{
                     "user": { "$ref": "#/components/schemas/User" },
                     "access_token": { "type": "string" },
                     "refresh_token": { "type": "string" },
-                    "token_type": { "type": "string" }
+                    "token_type": { "type": "string" },
+                    "mfa_required": { "type": "boolean" },
+                    "challenge_token": { "type": "string" }
                   }
                 }
               }
@@ -176,6 +209,14 @@ var OpenAPIJSON = []byte(`{
                 "schema": { "$ref": "#/components/schemas/Error" }
               }
             }
+          },
+          "429": {
+            "description": "Too many failed attempts; see the Retry-After header",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Error" }
+              }
+            }
           }
         }
       }
@@ -237,6 +278,334 @@ var OpenAPIJSON = []byte(`{
         }
       }
     },
+    "/api/v1/auth/password": {
+      "post": {
+        "summary": "Change the current user's password",
+        "description": "The new password must satisfy the password policy, must not appear in the breached password list and must not match one of the recent passwords. Not available with API keys.",
+        "tags": ["Authentication"],
+        "security": [{ "bearerAuth": [] }],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["current_password", "new_password"],
+                "properties": {
+                  "current_password": { "type": "string" },
+                  "new_password": { "type": "string" }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": { "description": "Password changed" },
+          "400": {
+            "description": "New password rejected by the policy or reused",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Error" }
+              }
+            }
+          },
+          "401": { "description": "Not authenticated or current password incorrect" },
+          "403": { "description": "Requested with an API key" }
+        }
+      }
+    },
+    "/api/v1/auth/2fa/setup": {
+      "post": {
+        "summary": "Start TOTP enrollment",
+        "tags": ["Two-Factor Authentication"],
+        "security": [{ "bearerAuth": [] }],
+        "responses": {
+          "200": {
+            "description": "Secret and otpauth URI to show as a QR code",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "secret": { "type": "string" },
+                    "otpauth_uri": { "type": "string" }
+                  }
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "Two-factor authentication already enabled",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Error" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/v1/auth/2fa/enable": {
+      "post": {
+        "summary": "Confirm TOTP enrollment",
+        "tags": ["Two-Factor Authentication"],
+        "security": [{ "bearerAuth": [] }],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": { "$ref": "#/components/schemas/TwoFactorCodeInput" }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Enabled; recovery codes are shown only once",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "enabled": { "type": "boolean" },
+                    "recovery_codes": { "type": "array", "items": { "type": "string" } }
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "Invalid code",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Error" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/v1/auth/2fa/verify": {
+      "post": {
+        "summary": "Complete a login that requires a second factor",
+        "tags": ["Two-Factor Authentication"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": { "$ref": "#/components/schemas/TwoFactorVerifyInput" }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Login successful",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/TokenResponse" }
+              }
+            }
+          },
+          "401": {
+            "description": "Invalid code or expired challenge",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Error" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/v1/auth/identities": {
+      "get": {
+        "summary": "List linked identity providers",
+        "tags": ["External Login"],
+        "security": [{ "bearerAuth": [] }],
+        "responses": {
+          "200": {
+            "description": "Linked identities and configured providers",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "identities": {
+                      "type": "array",
+                      "items": {
+                        "type": "object",
+                        "properties": {
+                          "id": { "type": "integer" },
+                          "user_id": { "type": "integer" },
+                          "provider": { "type": "string" },
+                          "email": { "type": "string" },
+                          "created_at": { "type": "string", "format": "date-time" }
+                        }
+                      }
+                    },
+                    "providers": {
+                      "type": "array",
+                      "items": {
+                        "type": "object",
+                        "properties": {
+                          "name": { "type": "string" },
+                          "display_name": { "type": "string" }
+                        }
+                      }
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "401": { "description": "Unauthorized" }
+        }
+      }
+    },
+    "/api/v1/auth/identities/{provider}": {
+      "post": {
+        "summary": "Start linking an identity provider to the current account",
+        "description": "Returns an authorization URL. Navigate the browser to it; the provider redirects back to /auth/oidc/{provider}/callback, which links the identity and redirects to return_to.",
+        "tags": ["External Login"],
+        "security": [{ "bearerAuth": [] }],
+        "parameters": [
+          { "name": "provider", "in": "path", "required": true, "schema": { "type": "string" } }
+        ],
+        "requestBody": {
+          "required": false,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "return_to": { "type": "string", "example": "/profile" }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Authorization URL",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "authorization_url": { "type": "string" }
+                  }
+                }
+              }
+            }
+          },
+          "401": { "description": "Unauthorized" },
+          "404": { "description": "Unknown provider" }
+        }
+      },
+      "delete": {
+        "summary": "Unlink an identity provider",
+        "tags": ["External Login"],
+        "security": [{ "bearerAuth": [] }],
+        "parameters": [
+          { "name": "provider", "in": "path", "required": true, "schema": { "type": "string" } }
+        ],
+        "responses": {
+          "200": { "description": "Provider unlinked" },
+          "401": { "description": "Unauthorized" },
+          "404": { "description": "Provider is not linked" },
+          "409": {
+            "description": "Refused: this is the account's only way to sign in",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Error" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/v1/auth/api-keys": {
+      "get": {
+        "summary": "List the current user's API keys",
+        "tags": ["API Keys"],
+        "security": [{ "bearerAuth": [] }],
+        "responses": {
+          "200": {
+            "description": "API keys (secrets are never returned)",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": { "$ref": "#/components/schemas/APIKey" }
+                }
+              }
+            }
+          },
+          "401": { "description": "Unauthorized" }
+        }
+      },
+      "post": {
+        "summary": "Create an API key",
+        "description": "The key is returned once and cannot be retrieved later. Requires a login session; API keys cannot create API keys. Send keys as 'Authorization: Bearer gbk_...'.",
+        "tags": ["API Keys"],
+        "security": [{ "bearerAuth": [] }],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["name"],
+                "properties": {
+                  "name": { "type": "string", "example": "ci-deploy" },
+                  "scopes": {
+                    "type": "array",
+                    "items": { "type": "string", "enum": ["read", "write", "admin"] },
+                    "example": ["read"]
+                  },
+                  "expires_in_days": { "type": "integer", "example": 30 }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "API key created",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "key": { "type": "string", "example": "gbk_1a2b3c4d5e6f_..." },
+                    "api_key": { "$ref": "#/components/schemas/APIKey" },
+                    "message": { "type": "string" }
+                  }
+                }
+              }
+            }
+          },
+          "400": { "description": "Invalid name, scopes or expiry" },
+          "401": { "description": "Unauthorized" },
+          "403": { "description": "Requested with an API key, or admin scope requested by a non-admin" },
+          "409": { "description": "Maximum number of active keys reached" }
+        }
+      }
+    },
+    "/api/v1/auth/api-keys/{id}": {
+      "delete": {
+        "summary": "Revoke an API key",
+        "tags": ["API Keys"],
+        "security": [{ "bearerAuth": [] }],
+        "parameters": [
+          { "name": "id", "in": "path", "required": true, "schema": { "type": "integer" } }
+        ],
+        "responses": {
+          "200": { "description": "API key revoked" },
+          "401": { "description": "Unauthorized" },
+          "404": { "description": "API key not found" }
+        }
+      }
+    },
     "/api/v1/users": {
       "get": {
         "summary": "List all users",
@@ -431,12 +800,17 @@ func HandlerUI(w http.ResponseWriter, r *http.Request, params map[string]string)
 </body>
 </html>`
 
+	// Allow the Swagger UI scripts under the Content-Security-Policy nonce
+	if nonce := middleware.CSPNonce(r.Context()); nonce != "" {
+		html = strings.ReplaceAll(html, "<script", `<script nonce="`+nonce+`"`)
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
 
 // RegisterRoutes registers documentation routes
 func RegisterRoutes(r *frameworkrouter.Router) {
-	r.Handle("GET", "/docs/openapi.json", HandlerJSON)
-	r.Handle("GET", "/docs", HandlerUI)
+	r.Handle("GET", "/docs/openapi.json", HandlerJSON, frameworkrouter.Public())
+	r.Handle("GET", "/docs", HandlerUI, frameworkrouter.Public())
 }
@@ -0,0 +1,279 @@
+// Package form fills structs from requests and validates them by tag, so JSON
+// and HTML handlers share one input type and one set of rules:
+//
+//	type SignupInput struct {
+//	    Name    string `json:"name" validate:"required,min=2,max=100"`
+//	    Email   string `json:"email" validate:"required,email"`
+//	    Page    int    `query:"page"`
+//	    TeamID  int    `path:"id"`
+//	}
+//
+//	var input SignupInput
+//	if err := form.Bind(r, params, &input); err != nil {
+//	    writeJSON(w, http.StatusBadRequest, form.JSONError(r.Context(), err))
+//	    return
+//	}
+//
+// HTML handlers redisplay the form with form.New(r, err), whose State gives
+// templates the submitted values and each field's error.
+package form
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/i18n"
+)
+
+// MaxMemory is how much of a multipart body is kept in memory; larger files
+// go to temporary files
+const MaxMemory = 32 << 20
+
+var (
+	// ErrInvalidJSON is returned, wrapped, when a JSON body can't be decoded
+	ErrInvalidJSON = i18n.NewError("form.invalid_json", "Invalid JSON")
+	// ErrInvalidForm is returned, wrapped, when a form body can't be parsed
+	ErrInvalidForm = i18n.NewError("form.invalid_form", "Invalid form data")
+)
+
+// Validator is implemented by inputs with checks tags can't express. Bind
+// calls Validate once the tags pass; return a *ValidationError (see Invalid)
+// to tie the error to a field.
+type Validator interface {
+	Validate() error
+}
+
+var (
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// Bind fills dst, a pointer to a struct, from r and validates it.
+//
+// Form and multipart bodies (and the query string of GET and HEAD requests)
+// fill fields by their form tag, then their json tag name, then the field
+// name; *multipart.FileHeader and []*multipart.FileHeader fields receive
+// uploads. Any other body is decoded as JSON. Fields tagged query:"name" are
+// read from the query string and path:"name" from the route's params,
+// whatever the body. A form:"-" or json:"-" tag skips a field.
+//
+// Values that don't convert to their field, failed validate tags and errors
+// from dst's Validate method come back together as a *ValidationError. A
+// body that can't be read is ErrInvalidJSON or ErrInvalidForm, wrapped.
+func Bind(r *http.Request, params map[string]string, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("form: Bind needs a pointer to a struct, got %T", dst))
+	}
+	v := rv.Elem()
+	errs := &ValidationError{}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(MaxMemory); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidForm, err)
+		}
+		bindValues(v, "form", r.MultipartForm.Value, r.MultipartForm.File, errs)
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidForm, err)
+		}
+		bindValues(v, "form", r.PostForm, nil, errs)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		bindValues(v, "form", r.URL.Query(), nil, errs)
+	default:
+		if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		}
+	}
+
+	bindValues(v, "query", r.URL.Query(), nil, errs)
+	path := make(map[string][]string, len(params))
+	for k, p := range params {
+		path[k] = []string{p}
+	}
+	bindValues(v, "path", path, nil, errs)
+
+	if err := Validate(dst); err != nil {
+		errs.merge(err.(*ValidationError))
+	}
+	if len(errs.Fields) > 0 {
+		return errs
+	}
+
+	if validator, ok := dst.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// bindValues sets the fields of v that source (form, query or path) names
+// from values and files. Fields with a query or path tag are only set from
+// that source, and others only from the form.
+func bindValues(v reflect.Value, source string, values map[string][]string, files map[string][]*multipart.FileHeader, errs *ValidationError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("form") == "" {
+			bindValues(v.Field(i), source, values, files, errs)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name, from := fieldName(f)
+		if name == "" || from != source {
+			continue
+		}
+
+		field := v.Field(i)
+		if f.Type == fileHeaderType || f.Type == reflect.SliceOf(fileHeaderType) {
+			if fh := files[name]; len(fh) > 0 {
+				if f.Type == fileHeaderType {
+					field.Set(reflect.ValueOf(fh[0]))
+				} else {
+					field.Set(reflect.ValueOf(fh))
+				}
+			}
+			continue
+		}
+
+		vals, ok := values[name]
+		if !ok {
+			continue
+		}
+		if err := setField(field, vals); err != nil {
+			errs.add(name, err)
+		}
+	}
+}
+
+// fieldName returns the name a field is bound and reported by, and where its
+// value comes from: "query", "path" or "form" (which covers JSON too)
+func fieldName(f reflect.StructField) (name, source string) {
+	if n, ok := f.Tag.Lookup("query"); ok {
+		return n, "query"
+	}
+	if n, ok := f.Tag.Lookup("path"); ok {
+		return n, "path"
+	}
+	if n := f.Tag.Get("form"); n != "" {
+		if n == "-" {
+			return "", "form"
+		}
+		return n, "form"
+	}
+	if n, _, _ := strings.Cut(f.Tag.Get("json"), ","); n != "" {
+		if n == "-" {
+			return "", "form"
+		}
+		return n, "form"
+	}
+	return f.Name, "form"
+}
+
+// setField converts vals to field's type: the first value for single
+// fields, all of them for slices
+func setField(field reflect.Value, vals []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setValue(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	return setValue(field, vals[0])
+}
+
+// setValue converts one value. Empty strings leave numbers, dates and
+// pointers unset, as an empty input means "no value".
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		if s == "" {
+			v.Set(reflect.Zero(timeType))
+			return nil
+		}
+		for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", time.RFC3339} {
+			if t, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return rule("date", "{field} must be a valid date")
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "on", "true", "1", "yes":
+			v.SetBool(true)
+		case "", "off", "false", "0", "no":
+			v.SetBool(false)
+		default:
+			return rule("boolean", "{field} must be true or false")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return rule("whole_number", "{field} must be a whole number")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return rule("whole_number", "{field} must be a whole number")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), v.Type().Bits())
+		if err != nil {
+			return rule("number", "{field} must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		panic(fmt.Sprintf("form: can't bind a field of type %s", v.Type()))
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package form
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type base struct {
+	Name string `json:"name" validate:"required,min=2"`
+}
+
+type signup struct {
+	base
+	Email   string    `json:"email" validate:"required,email"`
+	Age     int       `json:"age" validate:"min=18"`
+	Score   *float64  `json:"score"`
+	Tags    []string  `form:"tag" json:"tags"`
+	Agree   bool      `json:"agree"`
+	Born    time.Time `json:"born"`
+	Secret  string    `json:"-"`
+	Page    int       `query:"page"`
+	TeamID  int       `path:"id"`
+	Confirm string    `form:"confirm" json:"confirm" validate:"eqfield=Email"`
+}
+
+func TestBindForm(t *testing.T) {
+	body := url.Values{
+		"name":    {"Ana"},
+		"email":   {"ana@example.com"},
+		"confirm": {"ana@example.com"},
+		"age":     {"30"},
+		"score":   {"9.5"},
+		"tag":     {"a", "b"},
+		"agree":   {"on"},
+		"born":    {"1994-05-01"},
+		"Secret":  {"x"},
+		"page":    {"99"}, // Query fields don't come from the body
+	}
+	r := httptest.NewRequest("POST", "/teams/7/signup?page=2", strings.NewReader(body.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var in signup
+	if err := Bind(r, map[string]string{"id": "7"}, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Name != "Ana" || in.Email != "ana@example.com" || in.Age != 30 || in.Score == nil || *in.Score != 9.5 {
+		t.Errorf("Bind() = %+v", in)
+	}
+	if len(in.Tags) != 2 || in.Tags[1] != "b" || !in.Agree || in.Born.Year() != 1994 {
+		t.Errorf("Bind() = %+v", in)
+	}
+	if in.Secret != "" || in.Page != 2 || in.TeamID != 7 {
+		t.Errorf("Secret, Page, TeamID = %q, %d, %d", in.Secret, in.Page, in.TeamID)
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	r := httptest.NewRequest("POST", "/teams/3/signup", strings.NewReader(`{"name": "Ana", "email": "ana@example.com", "tags": ["x"], "agree": true}`))
+
+	var in signup
+	if err := Bind(r, map[string]string{"id": "3"}, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Name != "Ana" || len(in.Tags) != 1 || !in.Agree || in.TeamID != 3 {
+		t.Errorf("Bind() = %+v", in)
+	}
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{"name": `))
+	err := Bind(r, nil, &in)
+	if !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Bind(truncated JSON) = %v, want ErrInvalidJSON", err)
+	}
+	if got := Message(context.Background(), err); got != "Invalid JSON" {
+		t.Errorf("Message() = %q", got)
+	}
+}
+
+func TestBindQuery(t *testing.T) {
+	var in struct {
+		Q     string `form:"q"`
+		Limit int    `form:"limit"`
+	}
+	r := httptest.NewRequest("GET", "/search?q=go&limit=5", nil)
+	if err := Bind(r, nil, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Q != "go" || in.Limit != 5 {
+		t.Errorf("Bind() = %+v", in)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "Report")
+	fw, _ := mw.CreateFormFile("file", "report.txt")
+	io.WriteString(fw, "contents")
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/upload", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var in struct {
+		Title string                  `form:"title" validate:"required"`
+		File  *multipart.FileHeader   `form:"file" validate:"required"`
+		More  []*multipart.FileHeader `form:"more"`
+	}
+	if err := Bind(r, nil, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Title != "Report" || in.File == nil || in.File.Filename != "report.txt" || in.More != nil {
+		t.Errorf("Bind() = %+v", in)
+	}
+	if got := New(r, nil).Value("title"); got != "Report" {
+		t.Errorf("State.Value(title) = %q", got)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	body := url.Values{"name": {"A"}, "email": {"nope"}, "age": {"old"}, "confirm": {"other@example.com"}, "agree": {"maybe"}}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var in signup
+	err := Bind(r, nil, &in)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Bind() = %v, want a *ValidationError", err)
+	}
+	want := map[string]string{
+		"age":     "age must be a whole number",
+		"agree":   "agree must be true or false",
+		"name":    "name must be at least 2 characters long",
+		"email":   "email must be a valid email address",
+		"confirm": "confirm must match email",
+	}
+	got := verr.Messages(context.Background())
+	if len(got) != len(want) {
+		t.Errorf("Messages() = %v", got)
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("Messages()[%s] = %q, want %q", field, got[field], msg)
+		}
+	}
+}
+
+type withCheck struct {
+	Password string `json:"password" validate:"required"`
+}
+
+func (w withCheck) Validate() error {
+	if strings.Contains(w.Password, "password") {
+		return Invalid("password", errors.New("password is too easy to guess"))
+	}
+	return nil
+}
+
+func TestBindValidator(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"password": "mypassword"}`))
+	var in withCheck
+	err := Bind(r, nil, &in)
+	if got := JSONError(context.Background(), err); got["error"] != "password is too easy to guess" {
+		t.Errorf("JSONError() = %v", got)
+	}
+
+	// Validate isn't called until the tags pass
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+	in = withCheck{}
+	err = Bind(r, nil, &in)
+	body := JSONError(context.Background(), err)
+	if body["error"] != "password is required" {
+		t.Errorf("JSONError() = %v", body)
+	}
+	if errs, _ := body["errors"].(map[string]string); errs["password"] != "password is required" {
+		t.Errorf("JSONError() errors = %v", body["errors"])
+	}
+}
+
+func TestState(t *testing.T) {
+	body := url.Values{"email": {"nope"}, "role": {"admin"}, "perm": {"read", "write"}}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var in struct {
+		Email string   `form:"email" validate:"required,email"`
+		Role  string   `form:"role"`
+		Perm  []string `form:"perm"`
+	}
+	s := New(r, Bind(r, nil, &in))
+	if s.Value("email") != "nope" || !s.Has("perm", "write") || s.Has("role", "user") {
+		t.Errorf("State values = %v", s.values)
+	}
+	if s.Error("email") != "email must be a valid email address" || !s.HasError("email") || s.HasError("role") {
+		t.Errorf("State errors = %v", s.Errors())
+	}
+	if s.Message() != "" || s.Valid() {
+		t.Errorf("Message() = %q, Valid() = %v", s.Message(), s.Valid())
+	}
+
+	// Errors that aren't about a field are the form's message
+	if s := New(r, errors.New("Failed to save")); s.Message() != "Failed to save" || s.Valid() {
+		t.Errorf("Message() = %q", s.Message())
+	}
+
+	// A nil State is an empty form
+	var empty *State
+	if empty.Value("email") != "" || empty.Has("role", "admin") || empty.Error("email") != "" || !empty.Valid() {
+		t.Error("nil State isn't empty")
+	}
+	if s := Values(url.Values{"role": {"user"}}); !s.Has("role", "user") || !s.Valid() {
+		t.Error("Values() doesn't prefill the form")
+	}
+}
+
+func TestBindPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Bind(non-pointer) didn't panic")
+		}
+	}()
+	var in signup
+	Bind(httptest.NewRequest("GET", "/", nil), nil, in)
+}
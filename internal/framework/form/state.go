@@ -0,0 +1,123 @@
+package form
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// State is what a template needs to redisplay a form: the values that were
+// submitted and the error of each field. Pass it as .Form:
+//
+//	<input name="email" value="@.Form.Value("email")">
+//	go:: if .Form.HasError "email"
+//	<p class="error">@.Form.Error("email")</p>
+//	::end
+//
+// A nil *State is an empty form, so pages can pass one before anything is
+// submitted.
+type State struct {
+	values  url.Values
+	errors  map[string]string
+	message string
+}
+
+// New returns the State of the form r submitted, with the messages of err in
+// the request's locale. err is usually from Bind: a *ValidationError fills the
+// field errors, and any other error becomes the form's Message.
+func New(r *http.Request, err error) *State {
+	s := &State{values: url.Values{}, errors: map[string]string{}}
+	switch {
+	case r.MultipartForm != nil:
+		s.values = r.MultipartForm.Value
+	case r.PostForm != nil:
+		s.values = r.PostForm
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		s.values = r.URL.Query()
+	}
+
+	if err == nil {
+		return s
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		s.errors = verr.Messages(r.Context())
+	} else {
+		s.message = Message(r.Context(), err)
+	}
+	return s
+}
+
+// Values returns a State with initial values, for edit forms that start
+// from a stored record
+func Values(values url.Values) *State {
+	return &State{values: values, errors: map[string]string{}}
+}
+
+// Value returns the submitted value of a field
+func (s *State) Value(name string) string {
+	if s == nil {
+		return ""
+	}
+	return s.values.Get(name)
+}
+
+// All returns every submitted value of a field, e.g. of a multiple select
+func (s *State) All(name string) []string {
+	if s == nil {
+		return nil
+	}
+	return s.values[name]
+}
+
+// Has reports whether value was submitted for the field, to keep checkboxes
+// checked and options selected:
+//
+//	<option value="admin"
+//	go:: if .Form.Has "role" "admin"
+//	selected
+//	::end
+//	>Admin</option>
+func (s *State) Has(name, value string) bool {
+	for _, v := range s.All(name) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Error returns the message of a field's error, or ""
+func (s *State) Error(name string) string {
+	if s == nil {
+		return ""
+	}
+	return s.errors[name]
+}
+
+// HasError reports whether a field has an error
+func (s *State) HasError(name string) bool {
+	return s.Error(name) != ""
+}
+
+// Errors returns the message of each field with an error, by field name
+func (s *State) Errors() map[string]string {
+	if s == nil {
+		return nil
+	}
+	return s.errors
+}
+
+// Message returns the error that isn't about a field, such as a failed
+// save, to show above the form
+func (s *State) Message() string {
+	if s == nil {
+		return ""
+	}
+	return s.message
+}
+
+// Valid reports whether the form has no errors
+func (s *State) Valid() bool {
+	return s.Message() == "" && len(s.Errors()) == 0
+}
@@ -0,0 +1,341 @@
+package form
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/i18n"
+)
+
+// Validate checks v, a struct or a pointer to one, against the validate tags
+// of its fields and returns a *ValidationError listing every failure, or nil.
+// Rules are separated by commas:
+//
+//	required      not empty; strings must have more than spaces
+//	email         a plain address, like ana@example.com
+//	url           an absolute http or https URL
+//	min=N, max=N  the length of a string (in characters, ignoring surrounding
+//	              spaces) or slice, or the value of a number
+//	oneof=a b c   one of the space-separated values; for a slice, every item
+//	eqfield=F     equal to the struct's field F, e.g. a password confirmation
+//
+// Rules other than required pass on empty values, so optional fields only
+// need checking when they are filled in. An unknown rule panics, as it is a
+// mistake in the struct.
+func Validate(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("form: Validate needs a struct, got %T", v))
+	}
+	errs := &ValidationError{}
+	validateStruct(rv, rv, errs)
+	if len(errs.Fields) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateStruct checks the fields of v, looking eqfield fields up in root
+func validateStruct(root, v reflect.Value, errs *ValidationError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("validate") == "" {
+			validateStruct(root, v.Field(i), errs)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name, _ := fieldName(f)
+		if name == "" {
+			name = f.Name
+		}
+		if err := check(root, v.Field(i), tag); err != nil {
+			errs.add(name, err)
+		}
+	}
+}
+
+// check returns the error of the first rule in tag that field fails
+func check(root, field reflect.Value, tag string) error {
+	for _, r := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(r), "=")
+		if name == "required" {
+			if isEmpty(field) {
+				return rule("required", "{field} is required")
+			}
+			continue
+		}
+		if isEmpty(field) {
+			continue
+		}
+
+		value := reflect.Indirect(field)
+		switch name {
+		case "email":
+			s := value.String()
+			if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+				return rule("email", "{field} must be a valid email address")
+			}
+		case "url":
+			u, err := url.Parse(value.String())
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return rule("url", "{field} must be a valid URL")
+			}
+		case "min", "max":
+			if err := checkSize(value, name, arg); err != nil {
+				return err
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			values := []reflect.Value{value}
+			if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+				values = values[:0]
+				for i := 0; i < value.Len(); i++ {
+					values = append(values, value.Index(i))
+				}
+			}
+			for _, v := range values {
+				if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+					return rule("oneof", "{field} must be one of: {values}", "values", strings.Join(options, ", "))
+				}
+			}
+		case "eqfield":
+			other := root.FieldByName(arg)
+			if !other.IsValid() {
+				panic(fmt.Sprintf("form: eqfield=%s: no such field", arg))
+			}
+			if !reflect.DeepEqual(field.Interface(), other.Interface()) {
+				otherField, _ := root.Type().FieldByName(arg)
+				otherName, _ := fieldName(otherField)
+				return rule("eqfield", "{field} must match {other}", "other", label(otherName))
+			}
+		default:
+			panic(fmt.Sprintf("form: unknown validation rule %q", name))
+		}
+	}
+	return nil
+}
+
+// checkSize checks a min or max rule against a string's length, a slice's
+// length or a number's value
+func checkSize(v reflect.Value, name, arg string) error {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic(fmt.Sprintf("form: %s=%s: not a number", name, arg))
+	}
+
+	var size float64
+	var key string
+	switch v.Kind() {
+	case reflect.String:
+		size, key = float64(utf8.RuneCountInString(strings.TrimSpace(v.String()))), "_length"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		size, key = float64(v.Len()), "_items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = v.Float()
+	default:
+		panic(fmt.Sprintf("form: %s can't check a %s", name, v.Type()))
+	}
+
+	switch {
+	case name == "min" && size < limit:
+		return rule("min"+key, minMessages[key], "min", arg)
+	case name == "max" && size > limit:
+		return rule("max"+key, maxMessages[key], "max", arg)
+	}
+	return nil
+}
+
+var (
+	minMessages = map[string]string{
+		"_length": "{field} must be at least {min} characters long",
+		"_items":  "{field} must have at least {min} items",
+		"":        "{field} must be at least {min}",
+	}
+	maxMessages = map[string]string{
+		"_length": "{field} must not exceed {max} characters",
+		"_items":  "{field} must not have more than {max} items",
+		"":        "{field} must not be more than {max}",
+	}
+)
+
+// isEmpty reports whether v holds no value: a blank string, an empty slice
+// or map, a nil pointer or a zero value
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// rule returns the error of a failed rule, with the validation.<name>
+// message key. Its {field} placeholder is filled in by ValidationError.
+func rule(name, message string, args ...any) *i18n.Error {
+	return i18n.NewError("validation."+name, message, args...)
+}
+
+// label is a field name in a message. It reads as the name with spaces for
+// underscores ("confirm password"), or as fields.<name> from the locale's
+// catalog when FieldError.Message translates it.
+type label string
+
+func (l label) String() string {
+	return strings.ReplaceAll(string(l), "_", " ")
+}
+
+// translate returns the label in the locale of ctx
+func (l label) translate(ctx context.Context) string {
+	key := "fields." + string(l)
+	if s := i18n.T(ctx, key); s != key {
+		return s
+	}
+	return l.String()
+}
+
+// FieldError is the error of one field, named as in the request
+// ("confirm_password")
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string { return e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// Message returns the error in the locale of ctx (see i18n.Message), with
+// field names translated by the catalog's fields.<name> keys when present
+func (e *FieldError) Message(ctx context.Context) string {
+	ie, ok := e.Err.(*i18n.Error)
+	if !ok {
+		return i18n.Message(ctx, e.Err)
+	}
+	args := make([]any, len(ie.Args))
+	for i, a := range ie.Args {
+		if l, ok := a.(label); ok {
+			a = l.translate(ctx)
+		}
+		args[i] = a
+	}
+	return i18n.Message(ctx, i18n.NewError(ie.Key, ie.Message, args...))
+}
+
+// ValidationError lists the fields of an input that failed, in the order of
+// the struct
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Invalid returns a *ValidationError for one field, for Validate methods and
+// handlers that find a problem after binding:
+//
+//	return form.Invalid("email", errors.New("email is already registered"))
+//
+// An *i18n.Error can use the {field} placeholder for the field's name.
+func Invalid(field string, err error) *ValidationError {
+	e := &ValidationError{}
+	e.add(field, err)
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the field errors, so errors.Is and errors.As see them
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+	return errs
+}
+
+// Field returns the first error of the named field, or nil
+func (e *ValidationError) Field(name string) *FieldError {
+	for _, f := range e.Fields {
+		if f.Field == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Messages returns the first error of each field, by field name, in the
+// locale of ctx
+func (e *ValidationError) Messages(ctx context.Context) map[string]string {
+	msgs := make(map[string]string, len(e.Fields))
+	for _, f := range e.Fields {
+		if _, ok := msgs[f.Field]; !ok {
+			msgs[f.Field] = f.Message(ctx)
+		}
+	}
+	return msgs
+}
+
+// add records err for field, giving *i18n.Errors the field's name
+func (e *ValidationError) add(field string, err error) {
+	if ie, ok := err.(*i18n.Error); ok {
+		err = i18n.NewError(ie.Key, ie.Message, append([]any{"field", label(field)}, ie.Args...)...)
+	}
+	e.Fields = append(e.Fields, &FieldError{Field: field, Err: err})
+}
+
+// merge adds the errors of other for fields that have none yet
+func (e *ValidationError) merge(other *ValidationError) {
+	for _, f := range other.Fields {
+		if e.Field(f.Field) == nil {
+			e.Fields = append(e.Fields, f)
+		}
+	}
+}
+
+// Message returns the message of an error from Bind in the locale of ctx:
+// the first field's error for a *ValidationError, as for i18n.Message
+// otherwise
+func Message(ctx context.Context, err error) string {
+	var verr *ValidationError
+	if errors.As(err, &verr) && len(verr.Fields) > 0 {
+		return verr.Fields[0].Message(ctx)
+	}
+	return i18n.Message(ctx, err)
+}
+
+// JSONError returns the body of a 400 response for an error from Bind:
+// {"error": message} with the first message, plus {"errors": {field:
+// message}} when fields failed. Messages are in the locale of ctx.
+func JSONError(ctx context.Context, err error) map[string]any {
+	body := map[string]any{"error": Message(ctx, err)}
+	var verr *ValidationError
+	if errors.As(err, &verr) && len(verr.Fields) > 0 {
+		body["errors"] = verr.Messages(ctx)
+	}
+	return body
+}
@@ -0,0 +1,172 @@
+package form
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/i18n"
+)
+
+func TestValidateRules(t *testing.T) {
+	n := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name  string
+		input any
+		want  string // The error, or "" for none
+	}{
+		{"required", struct {
+			Name string `json:"name" validate:"required"`
+		}{"  "}, "name is required"},
+		{"required pointer", struct {
+			Score *float64 `json:"score" validate:"required"`
+		}{}, "score is required"},
+		{"required zero is allowed when set", struct {
+			Score *float64 `json:"score" validate:"required"`
+		}{n(0)}, ""},
+		{"optional", struct {
+			Site string `json:"site" validate:"url,min=10"`
+		}{""}, ""},
+		{"email", struct {
+			Email string `json:"email" validate:"email"`
+		}{"Ana <ana@example.com>"}, "email must be a valid email address"},
+		{"email ok", struct {
+			Email string `json:"email" validate:"email"`
+		}{"ana@example.com"}, ""},
+		{"url", struct {
+			Site string `json:"site" validate:"url"`
+		}{"javascript:alert(1)"}, "site must be a valid URL"},
+		{"min length counts runes", struct {
+			Name string `json:"name" validate:"min=3"`
+		}{"Añí"}, ""},
+		{"max length", struct {
+			Name string `json:"name" validate:"max=3"`
+		}{"Anabel"}, "name must not exceed 3 characters"},
+		{"min value", struct {
+			Age int `json:"age" validate:"min=18"`
+		}{16}, "age must be at least 18"},
+		{"max value", struct {
+			Price float64 `json:"price" validate:"max=9.5"`
+		}{10}, "price must not be more than 9.5"},
+		{"min items", struct {
+			Tags []string `json:"tags" validate:"min=2"`
+		}{[]string{"a"}}, "tags must have at least 2 items"},
+		{"oneof", struct {
+			Role string `json:"role" validate:"oneof=user admin"`
+		}{"root"}, "role must be one of: user, admin"},
+		{"oneof number", struct {
+			Level int `json:"level" validate:"oneof=1 2 3"`
+		}{2}, ""},
+		{"oneof items", struct {
+			Scopes []string `json:"scopes" validate:"oneof=read write"`
+		}{[]string{"read", "root"}}, "scopes must be one of: read, write"},
+		{"oneof items ok", struct {
+			Scopes []string `json:"scopes" validate:"oneof=read write"`
+		}{[]string{"write", "read"}}, ""},
+		{"eqfield", struct {
+			Password        string `json:"password"`
+			ConfirmPassword string `json:"confirm_password" validate:"eqfield=Password"`
+		}{"secret", "secreto"}, "confirm password must match password"},
+		{"first failing rule only", struct {
+			Email string `json:"email" validate:"required,email,max=5"`
+		}{"not an email"}, "email must be a valid email address"},
+		{"field name", struct {
+			FullName string `validate:"required"`
+		}{}, "FullName is required"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.input)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s: Validate() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAllFields(t *testing.T) {
+	err := Validate(&struct {
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"required"`
+	}{})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 2 || verr.Fields[0].Field != "name" {
+		t.Fatalf("Validate() = %#v", err)
+	}
+	if got := err.Error(); got != "name is required; email is required" {
+		t.Errorf("Error() = %q", got)
+	}
+	if verr.Field("email") == nil || verr.Field("role") != nil {
+		t.Error("Field() doesn't find the field's error")
+	}
+}
+
+func TestValidatePanics(t *testing.T) {
+	for _, input := range []any{
+		struct {
+			Name string `validate:"lowercase"`
+		}{"x"},
+		struct {
+			Name string `validate:"min=two"`
+		}{"x"},
+		struct {
+			Name string `validate:"eqfield=Missing"`
+		}{"x"},
+		"not a struct",
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Validate(%#v) didn't panic", input)
+				}
+			}()
+			Validate(input)
+		}()
+	}
+}
+
+func TestMessagesTranslated(t *testing.T) {
+	b, err := i18n.Load(fstest.MapFS{"es.json": {Data: []byte(`{
+		"fields": {"confirm_password": "confirmación", "password": "contraseña"},
+		"validation": {
+			"required": "El campo {field} es obligatorio",
+			"eqfield": "El campo {field} debe coincidir con {other}"
+		},
+		"form": {"invalid_json": "JSON no válido"}
+	}`)}}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := i18n.NewContext(context.Background(), b.Localizer("es"))
+
+	err = Validate(struct {
+		Name            string `json:"name" validate:"required"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirm_password" validate:"eqfield=Password"`
+	}{Password: "a", ConfirmPassword: "b"})
+	want := map[string]string{
+		"name":             "El campo name es obligatorio", // No label in the catalog
+		"confirm_password": "El campo confirmación debe coincidir con contraseña",
+	}
+	got := err.(*ValidationError).Messages(ctx)
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("Messages()[%s] = %q, want %q", field, got[field], msg)
+		}
+	}
+
+	if got := Message(ctx, errors.Join(ErrInvalidJSON)); got != "JSON no válido" {
+		t.Errorf("Message(ErrInvalidJSON) = %q", got)
+	}
+
+	// Errors given to Invalid keep their own text, and can use {field}
+	err = Invalid("email", i18n.NewError("users.taken", "{field} {email} is taken", "email", "ana@example.com"))
+	if got := Message(ctx, err); !strings.HasPrefix(got, "email ana@example.com") {
+		t.Errorf("Message(Invalid) = %q", got)
+	}
+}
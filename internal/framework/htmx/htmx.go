@@ -0,0 +1,139 @@
+// Package htmx reads the headers HTMX sends and sets the response headers it
+// acts on, so handlers don't spell them out by hand.
+//
+//	if htmx.IsRequest(r) {
+//	    htmx.Trigger(w, "message-sent")          // Fire an event in the browser
+//	    htmx.PushURL(w, "/chat/"+room)           // Update the address bar
+//	}
+//	htmx.Redirect(w, "/login")                   // Full-page navigation
+//
+// To render only part of a page for HTMX requests, see view.Engine.RenderHTMX;
+// for out-of-band swaps, view.Engine.RenderOOB. Responses that differ for HTMX
+// requests need middleware.VaryHTMX, so caches keep both versions apart.
+package htmx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// IsRequest reports whether r was made by HTMX (HX-Request: true)
+func IsRequest(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// IsBoosted reports whether r comes from an hx-boost link or form, which
+// swaps the whole <body> and so needs the full page
+func IsBoosted(r *http.Request) bool {
+	return r.Header.Get("HX-Boosted") == "true"
+}
+
+// IsHistoryRestore reports whether HTMX is reloading a page missing from its
+// history cache, which needs the full page too
+func IsHistoryRestore(r *http.Request) bool {
+	return r.Header.Get("HX-History-Restore-Request") == "true"
+}
+
+// WantsFragment reports whether r should get a fragment rather than the full
+// page: an HTMX request that is neither boosted nor a history restore
+func WantsFragment(r *http.Request) bool {
+	return IsRequest(r) && !IsBoosted(r) && !IsHistoryRestore(r)
+}
+
+// Target returns the id of the element the response will be swapped into
+// (HX-Target), if it has one
+func Target(r *http.Request) string {
+	return r.Header.Get("HX-Target")
+}
+
+// CurrentURL returns the URL of the page that made the request (HX-Current-URL)
+func CurrentURL(r *http.Request) string {
+	return r.Header.Get("HX-Current-URL")
+}
+
+// Trigger fires events in the browser once the response is received
+// (HX-Trigger). Listen with hx-trigger="message-sent from:body" or
+// document.body.addEventListener("message-sent", ...).
+func Trigger(w http.ResponseWriter, events ...string) {
+	addEvents(w, "HX-Trigger", events)
+}
+
+// TriggerAfterSwap is Trigger, but the events fire after the swap
+// (HX-Trigger-After-Swap)
+func TriggerAfterSwap(w http.ResponseWriter, events ...string) {
+	addEvents(w, "HX-Trigger-After-Swap", events)
+}
+
+// TriggerAfterSettle is Trigger, but the events fire after the settle step
+// (HX-Trigger-After-Settle)
+func TriggerAfterSettle(w http.ResponseWriter, events ...string) {
+	addEvents(w, "HX-Trigger-After-Settle", events)
+}
+
+// TriggerDetail fires events that carry data, found in event.detail. It
+// replaces the events set by Trigger.
+//
+//	htmx.TriggerDetail(w, map[string]any{"toast": map[string]string{"level": "info", "text": "Saved"}})
+func TriggerDetail(w http.ResponseWriter, events map[string]any) error {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("htmx: %w", err)
+	}
+	w.Header().Set("HX-Trigger", string(b))
+	return nil
+}
+
+// addEvents appends events to a comma-separated header, so several calls add up
+func addEvents(w http.ResponseWriter, header string, events []string) {
+	if len(events) == 0 {
+		return
+	}
+	list := strings.Join(events, ", ")
+	if current := w.Header().Get(header); current != "" {
+		list = current + ", " + list
+	}
+	w.Header().Set(header, list)
+}
+
+// Redirect makes HTMX navigate to url with a full page load (HX-Redirect).
+// HTMX doesn't follow 3xx responses into a new page, so use this instead of
+// http.Redirect for HTMX requests.
+func Redirect(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Redirect", url)
+}
+
+// Location navigates to url without a full page load, as if an hx-boost link
+// had been followed (HX-Location)
+func Location(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Location", url)
+}
+
+// Refresh makes the browser reload the page (HX-Refresh)
+func Refresh(w http.ResponseWriter) {
+	w.Header().Set("HX-Refresh", "true")
+}
+
+// PushURL adds url to the browser's history (HX-Push-Url)
+func PushURL(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Push-Url", url)
+}
+
+// ReplaceURL replaces the current URL in the address bar without adding a
+// history entry (HX-Replace-Url)
+func ReplaceURL(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Replace-Url", url)
+}
+
+// Retarget swaps the response into the element matching selector instead of
+// the request's target (HX-Retarget)
+func Retarget(w http.ResponseWriter, selector string) {
+	w.Header().Set("HX-Retarget", selector)
+}
+
+// Reswap overrides how the response is swapped, e.g. "outerHTML" or "none"
+// (HX-Reswap)
+func Reswap(w http.ResponseWriter, swap string) {
+	w.Header().Set("HX-Reswap", swap)
+}
@@ -0,0 +1,74 @@
+package htmx
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if IsRequest(r) || WantsFragment(r) {
+		t.Error("A plain request isn't an HTMX one")
+	}
+
+	r.Header.Set("HX-Request", "true")
+	r.Header.Set("HX-Target", "rows")
+	r.Header.Set("HX-Current-URL", "http://localhost/users")
+	if !IsRequest(r) || !WantsFragment(r) || Target(r) != "rows" || CurrentURL(r) != "http://localhost/users" {
+		t.Error("Expected an HTMX request for a fragment of #rows")
+	}
+
+	r.Header.Set("HX-Boosted", "true")
+	if !IsBoosted(r) || WantsFragment(r) {
+		t.Error("Boosted requests want the full page")
+	}
+	r.Header.Del("HX-Boosted")
+	r.Header.Set("HX-History-Restore-Request", "true")
+	if !IsHistoryRestore(r) || WantsFragment(r) {
+		t.Error("History restores want the full page")
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	Trigger(w, "saved")
+	Trigger(w, "closeModal", "refresh")
+	Trigger(w)
+	TriggerAfterSwap(w, "swapped")
+	TriggerAfterSettle(w, "settled")
+	Redirect(w, "/login")
+	Location(w, "/users")
+	Refresh(w)
+	PushURL(w, "/users?page=2")
+	ReplaceURL(w, "/users")
+	Retarget(w, "#errors")
+	Reswap(w, "outerHTML")
+
+	want := map[string]string{
+		"HX-Trigger":              "saved, closeModal, refresh",
+		"HX-Trigger-After-Swap":   "swapped",
+		"HX-Trigger-After-Settle": "settled",
+		"HX-Redirect":             "/login",
+		"HX-Location":             "/users",
+		"HX-Refresh":              "true",
+		"HX-Push-Url":             "/users?page=2",
+		"HX-Replace-Url":          "/users",
+		"HX-Retarget":             "#errors",
+		"HX-Reswap":               "outerHTML",
+	}
+	for header, value := range want {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+
+	if err := TriggerDetail(w, map[string]any{"toast": map[string]string{"text": "Saved <ok>"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Header().Get("HX-Trigger"); got != `{"toast":{"text":"Saved \u003cok\u003e"}}` {
+		t.Errorf("HX-Trigger = %q", got)
+	}
+	if err := TriggerDetail(w, map[string]any{"bad": func() {}}); err == nil {
+		t.Error("Expected an error for a value JSON can't encode")
+	}
+}
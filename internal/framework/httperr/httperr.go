@@ -0,0 +1,166 @@
+// Package httperr writes error responses in the format the client asked for.
+//
+// API clients get RFC 7807 problem details (application/problem+json); browsers
+// get an HTML error page rendered with view.Engine.RenderError. Browsers that
+// hit a protected page without a session are redirected to the login page with
+// a return URL instead of seeing a 401.
+//
+// The choice is made from the Accept header: a request that prefers text/html
+// (what browsers send on navigation) or comes from HTMX gets HTML; anything
+// else, including a missing Accept or */*, gets JSON.
+//
+// USAGE:
+//
+//	httperr.Configure(tmplEngine, "/login")
+//
+//	httperr.Write(w, r, http.StatusForbidden, "Insufficient permissions")
+//
+// Problem responses keep an "error" member with the detail so clients written
+// against the older {"error": "..."} bodies keep working.
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/htmx"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Extension members
+	Error     string `json:"error,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+var (
+	mu        sync.RWMutex
+	views     *view.Engine
+	loginPath = "/login"
+)
+
+// Configure sets the engine used for HTML error pages and the login page HTML
+// requests are sent to on 401. An empty loginPath disables the redirect.
+func Configure(engine *view.Engine, login string) {
+	mu.Lock()
+	defer mu.Unlock()
+	views = engine
+	loginPath = login
+}
+
+// Write sends an error with the given status and detail, negotiated on Accept
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, r, Problem{Status: status, Detail: detail})
+}
+
+// WriteProblem sends p as problem+json or as an HTML page. Missing Type and
+// Title are filled in from the status.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	if !WantsHTML(r) {
+		p.Error = p.Detail
+		if p.Error == "" {
+			p.Error = p.Title
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(p.Status)
+		json.NewEncoder(w).Encode(p)
+		return
+	}
+
+	mu.RLock()
+	engine, login := views, loginPath
+	mu.RUnlock()
+
+	if p.Status == http.StatusUnauthorized && login != "" {
+		redirectToLogin(w, r, login)
+		return
+	}
+
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+	// RenderError doesn't use the engine's templates, so it also works before Configure
+	engine.RenderError(w, p.Status, message)
+}
+
+// redirectToLogin sends the browser to the login page, remembering where it
+// was going. HTMX requests get an HX-Redirect header since HTMX doesn't follow
+// redirects for full-page navigation.
+func redirectToLogin(w http.ResponseWriter, r *http.Request, login string) {
+	returnTo := ""
+	if htmx.IsRequest(r) {
+		if current, err := url.Parse(htmx.CurrentURL(r)); err == nil {
+			returnTo = current.RequestURI()
+		}
+	} else if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		returnTo = r.URL.RequestURI()
+	}
+
+	target := login
+	if returnTo != "" && returnTo != "/" {
+		target += "?return_to=" + url.QueryEscape(returnTo)
+	}
+
+	if htmx.IsRequest(r) {
+		htmx.Redirect(w, target)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// WantsHTML reports whether the client prefers an HTML response
+func WantsHTML(r *http.Request) bool {
+	if htmx.IsRequest(r) {
+		return true
+	}
+
+	var htmlQ, jsonQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, q := parseMediaRange(part)
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			htmlQ = max(htmlQ, q)
+		case "application/json", "application/problem+json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return htmlQ > 0 && htmlQ >= jsonQ
+}
+
+// parseMediaRange splits "text/html;q=0.9" into its media type and quality
+func parseMediaRange(s string) (string, float64) {
+	mediaType, params, _ := strings.Cut(s, ";")
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(name, "q") {
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType)), q
+}
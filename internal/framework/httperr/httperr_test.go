@@ -0,0 +1,93 @@
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func TestWantsHTML(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{browserAccept, true},
+		{"application/json, text/html;q=0.5", false},
+		{"text/html;q=0", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		if got := WantsHTML(r); got != tt.want {
+			t.Errorf("WantsHTML(Accept: %q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestWriteProblemJSON(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/users", nil)
+	w := httptest.NewRecorder()
+	Write(w, r, http.StatusForbidden, "Insufficient permissions")
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected problem+json, got %q", ct)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if p.Title != "Forbidden" || p.Status != 403 || p.Detail != "Insufficient permissions" || p.Instance != "/api/v1/users" {
+		t.Errorf("Unexpected problem: %+v", p)
+	}
+	if p.Error != p.Detail {
+		t.Errorf("Expected legacy error member to carry the detail, got %q", p.Error)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	Configure(nil, "/login")
+
+	r := httptest.NewRequest("GET", "/admin/users?page=2", nil)
+	r.Header.Set("Accept", browserAccept)
+	w := httptest.NewRecorder()
+	Write(w, r, http.StatusUnauthorized, "Authentication required")
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect to login, got %d", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/login?return_to=%2Fadmin%2Fusers%3Fpage%3D2" {
+		t.Errorf("Unexpected login redirect %q", loc)
+	}
+
+	r = httptest.NewRequest("GET", "/admin", nil)
+	r.Header.Set("HX-Request", "true")
+	r.Header.Set("HX-Current-URL", "http://localhost:8080/chat/general")
+	w = httptest.NewRecorder()
+	Write(w, r, http.StatusUnauthorized, "Authentication required")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("HX-Redirect") != "/login?return_to=%2Fchat%2Fgeneral" {
+		t.Errorf("Expected HX-Redirect to login, got %d %q", w.Code, w.Header().Get("HX-Redirect"))
+	}
+
+	r = httptest.NewRequest("GET", "/admin", nil)
+	r.Header.Set("Accept", browserAccept)
+	w = httptest.NewRecorder()
+	Write(w, r, http.StatusForbidden, "Insufficient permissions")
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "Insufficient permissions") {
+		t.Errorf("Expected HTML error page, got %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected text/html, got %q", ct)
+	}
+}
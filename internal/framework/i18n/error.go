@@ -0,0 +1,21 @@
+package i18n
+
+// Error is an error whose message can be translated. Error() returns the
+// English message, so it reads well where nothing translates it; Message and
+// Localizer.Error look Key up in the request's locale instead.
+//
+//	return i18n.NewError("users.email_taken", "{email} is already registered", "email", input.Email)
+type Error struct {
+	Key     string
+	Message string // English text, with {name} placeholders
+	Args    []any  // Key/value pairs for the placeholders
+}
+
+// NewError returns an *Error for the message key
+func NewError(key, message string, args ...any) *Error {
+	return &Error{Key: key, Message: message, Args: args}
+}
+
+func (e *Error) Error() string {
+	return interpolate(e.Message, argMap(e.Args))
+}
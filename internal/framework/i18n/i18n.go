@@ -0,0 +1,367 @@
+// Package i18n translates user-facing messages.
+//
+// Messages live in one catalog per locale under locales/, either JSON
+// (locales/es.json) or gettext PO (locales/es.po), named after the language
+// tag. A JSON catalog maps keys to messages; nested objects group keys
+// ("auth": {"login": ...} is "auth.login"), and an object of plural forms
+// (zero, one, two, few, many, other) is chosen by the "count" argument:
+//
+//	{
+//	    "auth.welcome": "Welcome back, {name}!",
+//	    "chat.messages": {"zero": "No messages", "one": "{count} message", "other": "{count} messages"}
+//	}
+//
+// Arguments are key/value pairs that fill the {name} placeholders:
+//
+//	l := bundle.Localizer("es-MX")
+//	l.T("auth.welcome", "name", user.Name)
+//	l.T("chat.messages", "count", len(messages))
+//
+// A missing message falls back to the language ("es" for "es-MX"), then to
+// the bundle's default locale, and finally to the key itself.
+//
+// Requests get a Localizer from middleware.Locale, which picks the locale
+// from the lang query parameter, the lang cookie or Accept-Language.
+// Handlers reach it with FromContext, and templates with @t("key", ...).
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// message is a translation: a single text, or one per plural category
+type message struct {
+	text  string
+	forms map[string]string
+}
+
+// catalog holds the messages of one locale
+type catalog struct {
+	tag      string
+	messages map[string]message
+}
+
+// Bundle holds the catalogs of every locale the application is translated to
+type Bundle struct {
+	catalogs map[string]*catalog // By lowercase tag
+	fallback string
+}
+
+// NewBundle returns an empty bundle whose default locale is fallback, e.g.
+// "en-US". Add catalogs with AddJSON and AddPO, or use Load.
+func NewBundle(fallback string) *Bundle {
+	return &Bundle{catalogs: map[string]*catalog{}, fallback: fallback}
+}
+
+// Load reads every .json and .po catalog at the root of fsys, named after
+// their locale (es.json, pt-BR.po). A missing directory is an empty bundle.
+func Load(fsys fs.FS, fallback string) (*Bundle, error) {
+	b := NewBundle(fallback)
+	entries, err := fs.ReadDir(fsys, ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locales: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := path.Ext(name)
+		if entry.IsDir() || (ext != ".json" && ext != ".po") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locales: %w", err)
+		}
+		tag := strings.TrimSuffix(name, ext)
+		if ext == ".json" {
+			err = b.AddJSON(tag, data)
+		} else {
+			err = b.AddPO(tag, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return b, nil
+}
+
+// AddJSON adds the messages of a JSON catalog to the locale tag
+func (b *Bundle) AddJSON(tag string, data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	c := b.catalog(tag)
+	return flatten(c.messages, "", raw)
+}
+
+// flatten adds the messages of a JSON object, joining nested keys with dots
+func flatten(messages map[string]message, prefix string, raw map[string]any) error {
+	for key, value := range raw {
+		key = prefix + key
+		switch v := value.(type) {
+		case string:
+			messages[key] = message{text: v}
+		case map[string]any:
+			if forms, ok := pluralForms(v); ok {
+				messages[key] = message{forms: forms}
+			} else if err := flatten(messages, key+".", v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: a message must be a string or an object, not %T", key, value)
+		}
+	}
+	return nil
+}
+
+// pluralForms reports whether an object is a set of plural forms: strings
+// under plural categories only, "other" among them
+func pluralForms(raw map[string]any) (map[string]string, bool) {
+	if _, ok := raw["other"]; !ok {
+		return nil, false
+	}
+	forms := make(map[string]string, len(raw))
+	for category, value := range raw {
+		s, ok := value.(string)
+		if !ok || !pluralCategories[category] {
+			return nil, false
+		}
+		forms[category] = s
+	}
+	return forms, true
+}
+
+func (b *Bundle) catalog(tag string) *catalog {
+	key := strings.ToLower(tag)
+	c, ok := b.catalogs[key]
+	if !ok {
+		c = &catalog{tag: tag, messages: map[string]message{}}
+		b.catalogs[key] = c
+	}
+	return c
+}
+
+// Locales returns the tags of the locales with a catalog, sorted
+func (b *Bundle) Locales() []string {
+	tags := make([]string, 0, len(b.catalogs))
+	for _, c := range b.catalogs {
+		tags = append(tags, c.tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Match returns the first of tags, in order of preference, that the bundle
+// has a catalog for, itself or through its language: "es-MX" matches an "es"
+// catalog. It returns "" when none does.
+func (b *Bundle) Match(tags ...string) string {
+	for _, tag := range tags {
+		if _, ok := b.catalogs[strings.ToLower(tag)]; ok {
+			return tag
+		}
+		if _, ok := b.catalogs[strings.ToLower(language(tag))]; ok {
+			return tag
+		}
+	}
+	return ""
+}
+
+// Localizer returns the translator for a locale. Messages it lacks come from
+// the language's catalog, then from the default locale's.
+func (b *Bundle) Localizer(tag string) *Localizer {
+	if tag == "" {
+		tag = b.fallback
+	}
+	l := &Localizer{tag: tag}
+	seen := map[*catalog]bool{}
+	for _, t := range []string{tag, language(tag), b.fallback, language(b.fallback)} {
+		if c, ok := b.catalogs[strings.ToLower(t)]; ok && !seen[c] {
+			seen[c] = true
+			l.catalogs = append(l.catalogs, c)
+		}
+	}
+	return l
+}
+
+// language returns the language of a tag: "pt" for "pt-BR"
+func language(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// Localizer translates messages into one locale. A nil *Localizer returns
+// the keys (and errors' own text), with the arguments filled in.
+type Localizer struct {
+	tag      string
+	catalogs []*catalog
+}
+
+// Locale returns the locale's tag, e.g. "es-MX"
+func (l *Localizer) Locale() string {
+	if l == nil {
+		return ""
+	}
+	return l.tag
+}
+
+// T translates the message key. args are key/value pairs, or a single map,
+// that fill its {name} placeholders; "count" also picks the plural form.
+func (l *Localizer) T(key string, args ...any) string {
+	return l.translate(key, key, args)
+}
+
+// Error returns err's message in the locale when it is an *Error, and
+// err.Error() otherwise
+func (l *Localizer) Error(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return l.translate(e.Key, e.Message, e.Args)
+	}
+	return err.Error()
+}
+
+// translate looks key up, using fallback as the text when no catalog has it
+func (l *Localizer) translate(key, fallback string, args []any) string {
+	values := argMap(args)
+	if l != nil {
+		for _, c := range l.catalogs {
+			if m, ok := c.messages[key]; ok {
+				return interpolate(m.pick(c.tag, values["count"]), values)
+			}
+		}
+	}
+	return interpolate(fallback, values)
+}
+
+// pick returns the text for a count, by the plural rules of the language
+func (m message) pick(tag string, count any) string {
+	if m.forms == nil {
+		return m.text
+	}
+	n, ok := toFloat(count)
+	if ok && n == 0 {
+		if s, ok := m.forms["zero"]; ok {
+			return s
+		}
+	}
+	if ok {
+		if s, ok := m.forms[pluralRule(tag).category(n)]; ok {
+			return s
+		}
+	}
+	return m.forms["other"]
+}
+
+// argMap turns key/value pairs, or a single map, into placeholder values
+func argMap(args []any) map[string]any {
+	values := map[string]any{}
+	if len(args) == 1 {
+		if m, ok := args[0].(map[string]any); ok {
+			return m
+		}
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		values[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return values
+}
+
+// interpolate replaces each {name} whose value is given; others stay as they are
+func interpolate(s string, values map[string]any) string {
+	if len(values) == 0 || !strings.Contains(s, "{") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		name := s[start+1 : start+end]
+		if v, ok := values[name]; ok {
+			b.WriteString(s[:start])
+			b.WriteString(fmt.Sprint(v))
+		} else {
+			b.WriteString(s[:start+end+1])
+		}
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// toFloat converts a count to a number: any Go number, or a numeric string
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l
+func NewContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the request's Localizer (see middleware.Locale), or nil,
+// which translates nothing
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(contextKey{}).(*Localizer)
+	return l
+}
+
+// T translates key into the request's locale
+func T(ctx context.Context, key string, args ...any) string {
+	return FromContext(ctx).T(key, args...)
+}
+
+// Message returns err's message in the request's locale (see Localizer.Error)
+func Message(ctx context.Context, err error) string {
+	return FromContext(ctx).Error(err)
+}
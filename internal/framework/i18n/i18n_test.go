@@ -0,0 +1,186 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b, err := Load(fstest.MapFS{
+		"en.json": {Data: []byte(`{
+			"greeting": "Hello, {name}!",
+			"only_en": "English only",
+			"inbox": {
+				"title": "Inbox",
+				"count": {"zero": "No messages", "one": "{count} message", "other": "{count} messages"}
+			}
+		}`)},
+		"es.json": {Data: []byte(`{
+			"greeting": "¡Hola, {name}!",
+			"inbox": {"count": {"one": "{count} mensaje", "other": "{count} mensajes"}}
+		}`)},
+		"es-MX.json": {Data: []byte(`{"greeting": "¡Qué onda, {name}!"}`)},
+		"fr.json":    {Data: []byte(`{"inbox": {"count": {"one": "{count} message", "other": "{count} messages"}}}`)},
+		"README.md":  {Data: []byte("not a catalog")},
+	}, "en-US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestTranslate(t *testing.T) {
+	b := testBundle(t)
+
+	tests := []struct {
+		locale string
+		key    string
+		args   []any
+		want   string
+	}{
+		{"en", "greeting", []any{"name", "Ana"}, "Hello, Ana!"},
+		{"es", "greeting", []any{"name", "Ana"}, "¡Hola, Ana!"},
+		{"es-MX", "greeting", []any{"name", "Ana"}, "¡Qué onda, Ana!"},
+		{"es-AR", "greeting", []any{"name", "Ana"}, "¡Hola, Ana!"},              // Through the language
+		{"es-MX", "inbox.title", nil, "Inbox"},                                  // Through the default locale
+		{"de", "greeting", []any{map[string]any{"name": "Ana"}}, "Hello, Ana!"}, // A map of arguments
+		{"en", "greeting", nil, "Hello, {name}!"},                               // Missing arguments stay
+		{"en", "missing.key", []any{"x", 1}, "missing.key"},                     // Unknown keys are returned
+		{"en", "inbox.count", []any{"count", 0}, "No messages"},                 // zero wins for 0
+		{"en", "inbox.count", []any{"count", 1}, "1 message"},
+		{"en", "inbox.count", []any{"count", int64(5)}, "5 messages"},
+		{"es", "inbox.count", []any{"count", 0}, "0 mensajes"}, // No zero form
+		{"fr", "inbox.count", []any{"count", 0}, "0 message"},  // French 0 is singular
+		{"fr", "inbox.count", []any{"count", 1.5}, "1.5 message"},
+		{"en", "inbox.count", nil, "{count} messages"}, // No count: other
+	}
+	for _, tt := range tests {
+		if got := b.Localizer(tt.locale).T(tt.key, tt.args...); got != tt.want {
+			t.Errorf("%s T(%q, %v) = %q, want %q", tt.locale, tt.key, tt.args, got, tt.want)
+		}
+	}
+
+	if got := b.Localizer("").Locale(); got != "en-US" {
+		t.Errorf("Localizer(\"\").Locale() = %q, want the default locale", got)
+	}
+	if got := b.Locales(); fmt.Sprint(got) != "[en es es-MX fr]" {
+		t.Errorf("Locales() = %v", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{[]string{"es-MX"}, "es-MX"},
+		{[]string{"ES-mx"}, "ES-mx"},
+		{[]string{"pt-BR", "es-CO"}, "es-CO"},
+		{[]string{"pt", "ja"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := b.Match(tt.tags...); got != tt.want {
+			t.Errorf("Match(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestPluralRules(t *testing.T) {
+	tests := []struct {
+		tag  string
+		n    float64
+		want string
+	}{
+		{"en", 1, "one"}, {"en", 0, "other"}, {"en", 2, "other"},
+		{"pt-BR", 0, "one"}, {"pt", 2, "other"},
+		{"ru", 1, "one"}, {"ru", 21, "one"}, {"ru", 11, "many"},
+		{"ru", 3, "few"}, {"ru", 13, "many"}, {"ru", 24, "few"}, {"ru", 5, "many"},
+		{"pl", 21, "many"}, {"pl", 22, "few"}, {"pl", 1, "one"},
+		{"ja", 1, "other"},
+	}
+	for _, tt := range tests {
+		if got := pluralRule(tt.tag).category(tt.n); got != tt.want {
+			t.Errorf("%s plural(%v) = %q, want %q", tt.tag, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	b := testBundle(t)
+	if err := b.AddJSON("es", []byte(`{"validation.min": "debe tener al menos {min} caracteres"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := fmt.Errorf("register: %w", NewError("validation.min", "must be at least {min} characters long", "min", 2))
+	if got := err.Error(); got != "register: must be at least 2 characters long" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := b.Localizer("es").Error(err); got != "debe tener al menos 2 caracteres" {
+		t.Errorf("es Error() = %q", got)
+	}
+	if got := b.Localizer("en").Error(err); got != "must be at least 2 characters long" {
+		t.Errorf("en Error() = %q, want the English message", got)
+	}
+	if got := b.Localizer("es").Error(errors.New("plain")); got != "plain" {
+		t.Errorf("Error(plain) = %q", got)
+	}
+
+	// Without a Localizer in the context, messages are English
+	if got := Message(context.Background(), err); got != "must be at least 2 characters long" {
+		t.Errorf("Message() = %q", got)
+	}
+	ctx := NewContext(context.Background(), b.Localizer("es"))
+	if got := Message(ctx, err); got != "debe tener al menos 2 caracteres" {
+		t.Errorf("Message() = %q", got)
+	}
+	if got := T(ctx, "greeting", "name", "Ana"); got != "¡Hola, Ana!" {
+		t.Errorf("T() = %q", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(fstest.MapFS{"es.json": {Data: []byte(`{"a": 1}`)}}, "en"); err == nil {
+		t.Error("Expected an error for a number message")
+	}
+	if _, err := Load(fstest.MapFS{"es.json": {Data: []byte(`{`)}}, "en"); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+	b, err := Load(os.DirFS(t.TempDir()+"/missing"), "en")
+	if err != nil || len(b.Locales()) != 0 {
+		t.Errorf("Load(missing) = %v, %v; want an empty bundle", b.Locales(), err)
+	}
+}
+
+// Every shipped locale translates every English message
+func TestShippedLocales(t *testing.T) {
+	b, err := Load(os.DirFS("../../../locales"), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	en := b.catalogs["en"]
+	if en == nil {
+		t.Fatal("No English catalog")
+	}
+	for _, tag := range b.Locales() {
+		c := b.catalogs[strings.ToLower(tag)]
+		var missing []string
+		for key := range en.messages {
+			if _, ok := c.messages[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		sort.Strings(missing)
+		for _, key := range missing {
+			t.Errorf("%s: no translation for %s", tag, key)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"math"
+	"strings"
+)
+
+// pluralCategories are the CLDR plural categories a JSON catalog can use
+var pluralCategories = map[string]bool{
+	"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true,
+}
+
+// plural is a language's plural rule: the categories it uses, in the order
+// of a PO file's msgstr[n], and the one a count falls in
+type plural struct {
+	categories []string
+	category   func(n float64) string
+}
+
+var (
+	// English, Spanish, German...: 1 is singular
+	pluralOne = plural{[]string{"one", "other"}, func(n float64) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}}
+
+	// French, Portuguese: 0 and 1 are singular
+	pluralZeroOne = plural{[]string{"one", "other"}, func(n float64) string {
+		if n >= 0 && n < 2 {
+			return "one"
+		}
+		return "other"
+	}}
+
+	// Russian, Ukrainian: 1, 21, 31... / 2-4, 22-24... / the rest
+	pluralSlavic = plural{[]string{"one", "few", "many"}, func(n float64) string {
+		i := int64(math.Abs(n))
+		switch {
+		case i%10 == 1 && i%100 != 11:
+			return "one"
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return "few"
+		}
+		return "many"
+	}}
+
+	// Polish: like Russian, except only 1 is singular
+	pluralPolish = plural{[]string{"one", "few", "many"}, func(n float64) string {
+		i := int64(math.Abs(n))
+		switch {
+		case i == 1:
+			return "one"
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return "few"
+		}
+		return "many"
+	}}
+
+	// Japanese, Chinese, Korean...: no plural forms
+	pluralNone = plural{[]string{"other"}, func(float64) string { return "other" }}
+)
+
+var pluralRules = map[string]plural{
+	"fr": pluralZeroOne, "pt": pluralZeroOne,
+	"ru": pluralSlavic, "uk": pluralSlavic, "be": pluralSlavic,
+	"pl": pluralPolish,
+	"ja": pluralNone, "zh": pluralNone, "ko": pluralNone, "vi": pluralNone, "th": pluralNone, "id": pluralNone,
+}
+
+// pluralRule returns the plural rule of a locale's language, "1 is singular"
+// for those not listed
+func pluralRule(tag string) plural {
+	if p, ok := pluralRules[strings.ToLower(language(tag))]; ok {
+		return p
+	}
+	return pluralOne
+}
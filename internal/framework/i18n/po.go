@@ -0,0 +1,126 @@
+package i18n
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AddPO adds the messages of a gettext PO catalog to the locale tag. msgid is
+// the message key. Plural messages (msgid_plural) map msgstr[0], msgstr[1]...
+// to the language's plural categories in CLDR order, e.g. one, few and many
+// in Russian; the Plural-Forms header is not read. Fuzzy and untranslated
+// entries are skipped, so those keys fall back to another catalog.
+func (b *Bundle) AddPO(tag string, data []byte) error {
+	c := b.catalog(tag)
+	categories := pluralRule(tag).categories
+
+	var (
+		id, field   string
+		plural      bool
+		fuzzy       bool
+		strs        = map[int]string{}
+		target      *string
+		lineNo      int
+		targetIndex = -1
+	)
+	// entry stores the entry read so far and starts a new one
+	entry := func() {
+		if id != "" && !fuzzy {
+			if plural {
+				forms := map[string]string{}
+				for i, s := range strs {
+					if i < len(categories) && s != "" {
+						forms[categories[i]] = s
+					}
+				}
+				if len(forms) == len(categories) {
+					if _, ok := forms["other"]; !ok {
+						forms["other"] = forms[categories[len(categories)-1]]
+					}
+					c.messages[id] = message{forms: forms}
+				}
+			} else if s := strs[0]; s != "" {
+				c.messages[id] = message{text: s}
+			}
+		}
+		id, field, plural, fuzzy = "", "", false, false
+		strs = map[int]string{}
+		target, targetIndex = nil, -1
+	}
+
+	var value string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				if field != "" {
+					entry()
+				}
+				fuzzy = true
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			// A continuation of the previous string
+			s, err := strconv.Unquote(line)
+			if err != nil || target == nil {
+				return fmt.Errorf("line %d: unexpected %s", lineNo, line)
+			}
+			*target += s
+			if targetIndex >= 0 {
+				strs[targetIndex] = *target
+			}
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		s, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return fmt.Errorf("line %d: invalid string %s", lineNo, rest)
+		}
+
+		switch {
+		case keyword == "msgctxt":
+			return fmt.Errorf("line %d: msgctxt is not supported; use distinct keys", lineNo)
+		case keyword == "msgid":
+			if field == "msgstr" {
+				entry()
+			}
+			id, field = s, "msgid"
+			target, targetIndex = &id, -1
+		case keyword == "msgid_plural":
+			plural = true
+			value = s
+			target, targetIndex = &value, -1
+		case keyword == "msgstr":
+			strs[0] = s
+			value = s
+			field = "msgstr"
+			target, targetIndex = &value, 0
+		case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+			n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+			if err != nil || n < 0 {
+				return fmt.Errorf("line %d: invalid %s", lineNo, keyword)
+			}
+			strs[n] = s
+			value = s
+			field = "msgstr"
+			target, targetIndex = &value, n
+		default:
+			return fmt.Errorf("line %d: unknown keyword %s", lineNo, keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	entry()
+	return nil
+}
@@ -0,0 +1,83 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddPO(t *testing.T) {
+	b := NewBundle("en")
+	err := b.AddPO("ru", []byte(`# Russian translation
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+#: templates/home.html:3
+msgid "greeting"
+msgstr "Привет, {name}!"
+
+msgid "long"
+msgstr ""
+"first "
+"second"
+
+msgid "files"
+msgid_plural "files"
+msgstr[0] "{count} файл"
+msgstr[1] "{count} файла"
+msgstr[2] "{count} файлов"
+
+#, fuzzy
+msgid "unsure"
+msgstr "Может быть"
+
+msgid "untranslated"
+msgstr ""
+
+msgid "quote"
+msgstr "Say \"hi\"\tnow"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := b.Localizer("ru")
+	tests := []struct {
+		key  string
+		args []any
+		want string
+	}{
+		{"greeting", []any{"name", "Ана"}, "Привет, Ана!"},
+		{"long", nil, "first second"},
+		{"files", []any{"count", 1}, "1 файл"},
+		{"files", []any{"count", 3}, "3 файла"},
+		{"files", []any{"count", 11}, "11 файлов"},
+		{"unsure", nil, "unsure"},             // Fuzzy entries are skipped
+		{"untranslated", nil, "untranslated"}, // So are empty ones
+		{"quote", nil, "Say \"hi\"\tnow"},
+	}
+	for _, tt := range tests {
+		if got := l.T(tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%q, %v) = %q, want %q", tt.key, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestAddPOErrors(t *testing.T) {
+	tests := []struct {
+		po   string
+		want string
+	}{
+		{"msgid \"a\"\nmsgstr b", "line 2: invalid string"},
+		{"msgid \"a\"\nmsgtxt \"b\"", "line 2: unknown keyword msgtxt"},
+		{"\"orphan\"", "line 1: unexpected"},
+		{"msgctxt \"menu\"\nmsgid \"a\"\nmsgstr \"b\"", "msgctxt is not supported"},
+	}
+	for _, tt := range tests {
+		err := NewBundle("en").AddPO("es", []byte(tt.po))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("AddPO(%q) error = %v, want %q", tt.po, err, tt.want)
+		}
+	}
+}
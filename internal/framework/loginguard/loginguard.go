@@ -0,0 +1,296 @@
+// Package loginguard protects login endpoints against brute-force attacks.
+//
+// Failed attempts are tracked per account (email) and per client IP. Each
+// failure adds an exponential backoff delay before the next attempt is
+// accepted, and once a threshold is reached the account or IP is locked for
+// a configurable duration. Locked accounts can be unlocked by an admin or via
+// a signed unlock link delivered to the account owner.
+//
+// USAGE:
+//
+//	loginguard.Configure(cfg.LoginProtection, cfg.Security.JWTSecret, cfg.App.BaseURL)
+//
+//	if err := loginguard.Check(ctx, email, ip); err != nil {
+//	    // reject with 429 and Retry-After
+//	}
+//	user, hash, err := db.GetUserByEmail(ctx, email)
+//	if err != nil {
+//	    loginguard.CompareWithDummy(password) // uniform timing
+//	    loginguard.RecordFailure(ctx, email, ip)
+//	}
+//
+// When login protection is disabled (or Configure was never called) every
+// function is a no-op, so handlers can call them unconditionally.
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/passwords"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
+)
+
+// Security event types recorded by the guard
+const (
+	EventAccountLocked   = "account_locked"
+	EventAccountUnlocked = "account_unlocked"
+	EventIPLocked        = "ip_locked"
+)
+
+// ThrottledError is returned by Check when an attempt must be rejected
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // true for a lockout, false for a backoff delay
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, try again later"
+	}
+	return "please wait before trying again"
+}
+
+// RetryAfterSeconds returns the delay rounded up to whole seconds, for the Retry-After header
+func (e *ThrottledError) RetryAfterSeconds() int {
+	secs := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
+
+// UnlockNotifier delivers an unlock link to the owner of a locked account
+type UnlockNotifier func(ctx context.Context, email, unlockURL string)
+
+var (
+	mu        sync.RWMutex
+	settings  config.LoginProtectionConfig
+	jwtSecret string
+	baseURL   string
+	notifier  UnlockNotifier = logUnlockLink
+
+	// now is replaceable in tests
+	now = time.Now
+)
+
+// Configure sets the protection settings. Call it once at startup.
+func Configure(cfg config.LoginProtectionConfig, secret, appBaseURL string) {
+	mu.Lock()
+	defer mu.Unlock()
+	settings = cfg
+	jwtSecret = secret
+	baseURL = strings.TrimRight(appBaseURL, "/")
+}
+
+// SetUnlockNotifier sets how unlock links are delivered (e.g. with an email sender).
+// The default notifier only logs that a link was issued, as the link itself
+// unlocks the account, so owners get no link until one is set.
+func SetUnlockNotifier(fn UnlockNotifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifier = fn
+}
+
+func current() (config.LoginProtectionConfig, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return settings, settings.Enabled
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns a *ThrottledError if the account or IP is locked or still in backoff
+func Check(ctx context.Context, email, ip string) error {
+	cfg, enabled := current()
+	if !enabled {
+		return nil
+	}
+
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		f, err := db.GetLoginFailure(ctx, key)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := throttle(cfg, f, now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// throttle decides whether a failure record blocks an attempt at time t
+func throttle(cfg config.LoginProtectionConfig, f db.LoginFailure, t time.Time) error {
+	if t.Before(f.LockedUntil) {
+		return &ThrottledError{RetryAfter: f.LockedUntil.Sub(t), Locked: true}
+	}
+	if f.Failures == 0 {
+		return nil
+	}
+	next := f.LastFailureAt.Add(backoff(cfg, f.Failures))
+	if t.Before(next) {
+		return &ThrottledError{RetryAfter: next.Sub(t)}
+	}
+	return nil
+}
+
+// backoff returns base * 2^(failures-1), capped at BackoffMaxSeconds
+func backoff(cfg config.LoginProtectionConfig, failures int) time.Duration {
+	if cfg.BackoffBaseSeconds <= 0 || failures <= 0 {
+		return 0
+	}
+	delay := time.Duration(cfg.BackoffBaseSeconds) * time.Second
+	limit := time.Duration(cfg.BackoffMaxSeconds) * time.Second
+	for i := 1; i < failures && (limit <= 0 || delay < limit); i++ {
+		delay *= 2
+	}
+	if limit > 0 && delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// RecordFailure counts a failed attempt and locks the account or IP once its threshold is reached
+func RecordFailure(ctx context.Context, email, ip string) {
+	cfg, enabled := current()
+	if !enabled {
+		return
+	}
+
+	t := now()
+	window := time.Duration(cfg.LockoutMinutes) * time.Minute
+	lockUntil := t.Add(window)
+
+	failures, err := db.IncrementLoginFailure(ctx, accountKey(email), t, window)
+	if err != nil {
+		log.Printf("loginguard: failed to record failure for %s: %v", email, err)
+	} else if cfg.MaxAttemptsPerAccount > 0 && failures >= cfg.MaxAttemptsPerAccount {
+		if err := db.LockLoginKey(ctx, accountKey(email), lockUntil); err != nil {
+			log.Printf("loginguard: failed to lock account %s: %v", email, err)
+		} else {
+			recordEvent(ctx, EventAccountLocked, email, ip, fmt.Sprintf("%d failed attempts, locked for %d minutes", failures, cfg.LockoutMinutes))
+			sendUnlockLink(ctx, cfg, email)
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+	failures, err = db.IncrementLoginFailure(ctx, ipKey(ip), t, window)
+	if err != nil {
+		log.Printf("loginguard: failed to record failure for %s: %v", ip, err)
+	} else if cfg.MaxAttemptsPerIP > 0 && failures >= cfg.MaxAttemptsPerIP {
+		if err := db.LockLoginKey(ctx, ipKey(ip), lockUntil); err != nil {
+			log.Printf("loginguard: failed to lock IP %s: %v", ip, err)
+		} else {
+			recordEvent(ctx, EventIPLocked, ip, ip, fmt.Sprintf("%d failed attempts, locked for %d minutes", failures, cfg.LockoutMinutes))
+		}
+	}
+}
+
+// RecordSuccess clears the failure history of an account after a successful login.
+// The IP counter is left untouched so one valid account cannot reset it.
+func RecordSuccess(ctx context.Context, email string) {
+	if _, enabled := current(); !enabled {
+		return
+	}
+	if err := db.ClearLoginFailures(ctx, accountKey(email)); err != nil {
+		log.Printf("loginguard: failed to clear failures for %s: %v", email, err)
+	}
+}
+
+// Unlock removes a lockout from an account. actor describes who unlocked it
+// (e.g. "admin:1" or "unlock link") and is stored in the security event.
+func Unlock(ctx context.Context, email, actor string) error {
+	if err := db.ClearLoginFailures(ctx, accountKey(email)); err != nil {
+		return err
+	}
+	recordEvent(ctx, EventAccountUnlocked, email, "", "unlocked by "+actor)
+	return nil
+}
+
+// LockedUntil returns when the account lockout ends, or the zero time if it is not locked
+func LockedUntil(ctx context.Context, email string) (time.Time, error) {
+	f, err := db.GetLoginFailure(ctx, accountKey(email))
+	if errors.Is(err, db.ErrNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !now().Before(f.LockedUntil) {
+		return time.Time{}, nil
+	}
+	return f.LockedUntil, nil
+}
+
+// UnlockWithToken validates an emailed unlock token and unlocks the account it was issued for
+func UnlockWithToken(ctx context.Context, token string) (string, error) {
+	mu.RLock()
+	secret := jwtSecret
+	mu.RUnlock()
+
+	email, err := security.ParseUnlockToken(secret, token)
+	if err != nil {
+		return "", err
+	}
+	return email, Unlock(ctx, email, "unlock link")
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// CompareWithDummy runs a bcrypt comparison against a fixed hash. Call it when the
+// account does not exist so the response takes as long as a real password check
+// and cannot be used to discover which emails are registered.
+func CompareWithDummy(password string) {
+	dummyHashOnce.Do(func() {
+		// Same cost as real hashes, so the comparison takes as long
+		dummyHash, _ = passwords.Hash("goBastion-dummy-password")
+	})
+	passwords.Compare(dummyHash, password)
+}
+
+func sendUnlockLink(ctx context.Context, cfg config.LoginProtectionConfig, email string) {
+	mu.RLock()
+	secret, base, notify := jwtSecret, baseURL, notifier
+	mu.RUnlock()
+
+	token, err := security.GenerateUnlockToken(secret, email, cfg.UnlockTokenMinutes)
+	if err != nil {
+		log.Printf("loginguard: failed to generate unlock token for %s: %v", email, err)
+		return
+	}
+	if notify != nil {
+		notify(ctx, email, base+"/unlock?token="+url.QueryEscape(token))
+	}
+}
+
+func recordEvent(ctx context.Context, eventType, subject, ip, detail string) {
+	if err := db.RecordSecurityEvent(ctx, eventType, subject, ip, detail); err != nil {
+		log.Printf("loginguard: failed to record %s event: %v", eventType, err)
+	}
+}
+
+func logUnlockLink(ctx context.Context, email, unlockURL string) {
+	log.Printf("Account %s locked after repeated failed logins. An unlock link was issued but not delivered: set loginguard.SetUnlockNotifier", email)
+}
@@ -0,0 +1,59 @@
+package loginguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+)
+
+func TestBackoff(t *testing.T) {
+	cfg := config.LoginProtectionConfig{BackoffBaseSeconds: 1, BackoffMaxSeconds: 30}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 1 * time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 16 * time.Second},
+		{6, 30 * time.Second},
+		{50, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(cfg, tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	cfg := config.LoginProtectionConfig{BackoffBaseSeconds: 1, BackoffMaxSeconds: 30}
+	base := time.Unix(1_700_000_000, 0)
+
+	// Locked records are rejected until the lockout ends
+	locked := db.LoginFailure{LockedUntil: base.Add(10 * time.Minute)}
+	err := throttle(cfg, locked, base)
+	te, ok := err.(*ThrottledError)
+	if !ok || !te.Locked || te.RetryAfterSeconds() != 600 {
+		t.Fatalf("Expected 600s lockout, got %v", err)
+	}
+	if err := throttle(cfg, locked, base.Add(10*time.Minute)); err != nil {
+		t.Errorf("Expected lockout to expire, got %v", err)
+	}
+
+	// Three failures require a 4 second wait after the last one
+	backedOff := db.LoginFailure{Failures: 3, LastFailureAt: base}
+	err = throttle(cfg, backedOff, base.Add(time.Second))
+	te, ok = err.(*ThrottledError)
+	if !ok || te.Locked || te.RetryAfterSeconds() != 3 {
+		t.Fatalf("Expected 3s backoff, got %v", err)
+	}
+	if err := throttle(cfg, backedOff, base.Add(4*time.Second)); err != nil {
+		t.Errorf("Expected backoff to expire, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
+)
+
+// CSRFFieldName is the form field CSRFMiddleware reads the token from
+const CSRFFieldName = "csrf_token"
+
+const csrfTokenKey contextKey = "csrfToken"
+
+// CSRFToken returns a masked CSRF token for the request, for handlers that hand
+// it to JavaScript themselves (e.g. in a JSON response). Templates use
+// @csrfField, @csrfToken or @csrfHeaders instead.
+func CSRFToken(ctx context.Context) string {
+	token, ok := ctx.Value(csrfTokenKey).([]byte)
+	if !ok {
+		return ""
+	}
+	masked, _ := security.MaskCSRFToken(token)
+	return masked
+}
+
+// csrfWriter hands the request's CSRF token to @csrfField and friends
+type csrfWriter struct {
+	wrappedWriter
+	token  []byte
+	header string
+}
+
+// CSRFToken implements view.CSRFWriter; every call returns a differently masked token
+func (w *csrfWriter) CSRFToken() string {
+	masked, _ := security.MaskCSRFToken(w.token)
+	return masked
+}
+
+// CSRFFieldName implements view.CSRFWriter
+func (w *csrfWriter) CSRFFieldName() string { return CSRFFieldName }
+
+// CSRFHeaderName implements view.CSRFWriter
+func (w *csrfWriter) CSRFHeaderName() string { return w.header }
+
+// csrfSecret returns the browser's CSRF secret, issuing a new cookie when it
+// has none (or a malformed one)
+func csrfSecret(w http.ResponseWriter, r *http.Request, cfg config.SecurityConfig) string {
+	if cookie, err := r.Cookie(cfg.CSRFCookieName); err == nil && security.ValidCSRFSecret(cookie.Value) {
+		return cookie.Value
+	}
+
+	secret, err := security.GenerateCSRFSecret()
+	if err != nil {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.CSRFCookieName,
+		Value:    secret,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode, // Lax so arriving from another site doesn't replace the secret of open tabs
+	})
+	return secret
+}
+
+// csrfSession identifies the signed-in user the token is bound to ("" when
+// anonymous), so a token rendered before login can't be replayed after it
+// and a token from one account is useless in another
+func csrfSession(r *http.Request, jwtSecret string) string {
+	cookie, err := r.Cookie("auth_token")
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+	claims, err := security.ParseAndValidateToken(jwtSecret, cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return claims.Sub
+}
+
+// submittedCSRFToken reads the token from the CSRF header or, for HTML forms,
+// from the csrf_token field
+func submittedCSRFToken(r *http.Request, header string) string {
+	if token := r.Header.Get(header); token != "" {
+		return token
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+		return r.FormValue(CSRFFieldName)
+	}
+	return ""
+}
+
+// checkCSRFOrigin rejects unsafe requests whose Origin (or, without one, Referer)
+// is neither this site nor a trusted origin. Requests that carry neither header
+// (or "Origin: null") are left to the token check.
+func checkCSRFOrigin(r *http.Request, trusted map[string]bool) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		referer := r.Header.Get("Referer")
+		if referer == "" {
+			return nil
+		}
+		u, err := url.Parse(referer)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid Referer")
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+
+	if trusted[strings.ToLower(origin)] || sameOrigin(r, origin) {
+		return nil
+	}
+	return fmt.Errorf("cross-origin request from %s rejected", origin)
+}
+
+// sameOrigin reports whether origin is the site the request was sent to, as
+// seen by the client (behind a trusted proxy, the forwarded host and scheme)
+func sameOrigin(r *http.Request, origin string) bool {
+	info := clientInfo(r)
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Host, info.Host) {
+		return false
+	}
+	// Pages served over TLS must also have been loaded over TLS
+	return info.Scheme != "https" || u.Scheme == "https"
+}
+
+// csrfTrustedOrigins normalizes security.csrf_trusted_origins for lookups
+func csrfTrustedOrigins(origins []string) map[string]bool {
+	trusted := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		trusted[strings.ToLower(strings.TrimRight(origin, "/"))] = true
+	}
+	return trusted
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+var csrfTestConfig = config.SecurityConfig{
+	EnableCSRF:         true,
+	CSRFHeaderName:     "X-CSRF-Token",
+	CSRFCookieName:     "csrf_token",
+	JWTSecret:          "test-secret",
+	CSRFTrustedOrigins: []string{"https://app.example.com"},
+}
+
+// csrfTestHandler renders a form on GET and answers 200 to anything that gets through
+func csrfTestHandler(t *testing.T) func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	dir := t.TempDir()
+	page := `<form method="POST">@csrfField</form><body hx-headers='@csrfHeaders'></body>`
+	if err := os.WriteFile(filepath.Join(dir, "form.html"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	views, err := view.NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return CSRFMiddleware(csrfTestConfig)(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if r.Method == "GET" {
+			if err := views.Render(w, "form", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+var (
+	csrfFieldRegex  = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+	csrfHeaderRegex = regexp.MustCompile(`X-CSRF-Token&#34;:&#34;([^&]+)&#34;`)
+)
+
+// loadForm GETs the form and returns the CSRF cookie and the two rendered tokens
+func loadForm(t *testing.T, h func(http.ResponseWriter, *http.Request, map[string]string), cookies ...*http.Cookie) (*http.Cookie, string, string) {
+	r := httptest.NewRequest("GET", "/form", nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	h(w, r, nil)
+
+	body := w.Body.String()
+	field := csrfFieldRegex.FindStringSubmatch(body)
+	header := csrfHeaderRegex.FindStringSubmatch(body)
+	if field == nil || header == nil {
+		t.Fatalf("Expected @csrfField and @csrfHeaders to render tokens, got %q", body)
+	}
+
+	cookie := &http.Cookie{Name: "csrf_token"}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "csrf_token" {
+			cookie = c
+		}
+	}
+	for _, c := range cookies {
+		if c.Name == "csrf_token" && cookie.Value == "" {
+			cookie = c
+		}
+	}
+	if cookie.Value == "" {
+		t.Fatal("Expected a CSRF cookie")
+	}
+	return cookie, field[1], header[1]
+}
+
+func postForm(h func(http.ResponseWriter, *http.Request, map[string]string), token string, headers map[string]string, cookies ...*http.Cookie) int {
+	form := url.Values{}
+	if token != "" {
+		form.Set("csrf_token", token)
+	}
+	r := httptest.NewRequest("POST", "/form", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	h(w, r, nil)
+	return w.Code
+}
+
+func TestCSRFFormAndHeader(t *testing.T) {
+	h := csrfTestHandler(t)
+	cookie, field, header := loadForm(t, h)
+
+	if field == header {
+		t.Error("Expected each rendered token to be masked differently")
+	}
+
+	sameSite := map[string]string{"Origin": "http://example.com"}
+	if code := postForm(h, field, sameSite, cookie); code != http.StatusOK {
+		t.Errorf("Form field token: expected 200, got %d", code)
+	}
+	if code := postForm(h, "", map[string]string{"Origin": "http://example.com", "X-CSRF-Token": header}, cookie); code != http.StatusOK {
+		t.Errorf("Header token: expected 200, got %d", code)
+	}
+	if code := postForm(h, field, map[string]string{"Origin": "https://app.example.com"}, cookie); code != http.StatusOK {
+		t.Errorf("Trusted origin: expected 200, got %d", code)
+	}
+
+	if code := postForm(h, "", sameSite, cookie); code != http.StatusForbidden {
+		t.Errorf("Missing token: expected 403, got %d", code)
+	}
+	if code := postForm(h, field, sameSite); code != http.StatusForbidden {
+		t.Errorf("Missing cookie: expected 403, got %d", code)
+	}
+	if code := postForm(h, field, map[string]string{"Origin": "https://evil.example"}, cookie); code != http.StatusForbidden {
+		t.Errorf("Cross-origin: expected 403, got %d", code)
+	}
+	if code := postForm(h, field, map[string]string{"Referer": "https://evil.example/page"}, cookie); code != http.StatusForbidden {
+		t.Errorf("Cross-origin Referer: expected 403, got %d", code)
+	}
+}
+
+func TestCSRFSessionBinding(t *testing.T) {
+	h := csrfTestHandler(t)
+	cookie, anonymousToken, _ := loadForm(t, h)
+
+	jwt, err := security.GenerateToken(csrfTestConfig.JWTSecret, "42", "user", 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &http.Cookie{Name: "auth_token", Value: jwt}
+
+	// A token rendered before login doesn't work once signed in
+	if code := postForm(h, anonymousToken, nil, cookie, session); code != http.StatusForbidden {
+		t.Errorf("Anonymous token in a session: expected 403, got %d", code)
+	}
+
+	_, sessionToken, _ := loadForm(t, h, cookie, session)
+	if code := postForm(h, sessionToken, nil, cookie, session); code != http.StatusOK {
+		t.Errorf("Session token: expected 200, got %d", code)
+	}
+
+	// ...and a session's token is useless for another user
+	other, err := security.GenerateToken(csrfTestConfig.JWTSecret, "43", "user", 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := postForm(h, sessionToken, nil, cookie, &http.Cookie{Name: "auth_token", Value: other}); code != http.StatusForbidden {
+		t.Errorf("Other user's token: expected 403, got %d", code)
+	}
+}
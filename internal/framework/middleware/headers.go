@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+)
+
+const cspNonceKey contextKey = "cspNonce"
+
+// CSPNonce returns the Content-Security-Policy nonce of the request, for
+// handlers that write inline <script> tags without the template engine.
+// Templates use @cspNonce instead.
+func CSPNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceKey).(string)
+	return nonce
+}
+
+// nonceWriter hands the request's CSP nonce to @cspNonce
+type nonceWriter struct {
+	wrappedWriter
+	nonce string
+}
+
+// CSPNonce implements view.NonceWriter
+func (w *nonceWriter) CSPNonce() string { return w.nonce }
+
+func generateNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// cspNoncePlaceholder marks where the per-request nonce goes in a prepared policy
+const cspNoncePlaceholder = "{nonce}"
+
+// cspPolicy is a CSP header prepared at startup; only the nonce changes per request
+type cspPolicy struct {
+	header   string
+	template string // directives joined, with cspNoncePlaceholder where the nonce goes
+}
+
+// newCSPPolicy builds the policy from config. Directives are sorted so the
+// header is stable, and nonce directives get 'nonce-…' appended.
+func newCSPPolicy(cfg config.CSPConfig) cspPolicy {
+	header := "Content-Security-Policy"
+	if cfg.ReportOnly {
+		header = "Content-Security-Policy-Report-Only"
+	}
+
+	nonceDirectives := make(map[string]bool, len(cfg.NonceDirectives))
+	for _, d := range cfg.NonceDirectives {
+		nonceDirectives[d] = true
+	}
+
+	names := make([]string, 0, len(cfg.Directives))
+	for name := range cfg.Directives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		sources := append([]string(nil), cfg.Directives[name]...)
+		if nonceDirectives[name] {
+			sources = append(sources, "'nonce-"+cspNoncePlaceholder+"'")
+		}
+		parts = append(parts, strings.TrimSpace(name+" "+strings.Join(sources, " ")))
+	}
+	if cfg.ReportURI != "" {
+		parts = append(parts, "report-uri "+cfg.ReportURI)
+	}
+	return cspPolicy{header: header, template: strings.Join(parts, "; ")}
+}
+
+// value returns the header value for a request with the given nonce
+func (p cspPolicy) value(nonce string) string {
+	return strings.ReplaceAll(p.template, cspNoncePlaceholder, nonce)
+}
+
+// hstsValue formats Strict-Transport-Security ("" when disabled)
+func hstsValue(cfg config.HSTSConfig) string {
+	if !cfg.Enabled {
+		return ""
+	}
+	v := fmt.Sprintf("max-age=%d", cfg.MaxAgeSeconds)
+	if cfg.IncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if cfg.Preload {
+		v += "; preload"
+	}
+	return v
+}
+
+// permissionsPolicyValue formats Permissions-Policy, e.g.
+// camera=(), geolocation=(self "https://maps.example.com")
+func permissionsPolicyValue(features map[string][]string) string {
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		var allow []string
+		for _, origin := range features[name] {
+			switch origin {
+			case "self", "*", "src":
+				allow = append(allow, origin)
+			default:
+				allow = append(allow, `"`+origin+`"`)
+			}
+		}
+		if len(allow) == 1 && allow[0] == "*" {
+			parts = append(parts, name+"=*")
+			continue
+		}
+		parts = append(parts, name+"=("+strings.Join(allow, " ")+")")
+	}
+	return strings.Join(parts, ", ")
+}
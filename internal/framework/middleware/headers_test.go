@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+func TestSecurityHeadersNonce(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(`<script nonce="@cspNonce">run()</script>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	views, err := view.NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.HeadersConfig{
+		CSP: config.CSPConfig{
+			Enabled: true,
+			Directives: map[string][]string{
+				"script-src":  {"'self'"},
+				"default-src": {"'self'"},
+				"object-src":  {"'none'"},
+			},
+			NonceDirectives: []string{"script-src"},
+			ReportURI:       "/csp-report",
+		},
+		HSTS:              config.HSTSConfig{Enabled: true, MaxAgeSeconds: 600, IncludeSubdomains: true},
+		PermissionsPolicy: map[string][]string{"geolocation": {"self", "https://maps.example.com"}, "camera": {}},
+	}
+
+	var nonce string
+	h := SecurityHeaders(cfg)(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		nonce = CSPNonce(r.Context())
+		if err := views.Render(w, "page", nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/", nil), nil)
+
+	if nonce == "" {
+		t.Fatal("Expected a nonce in the request context")
+	}
+	wantCSP := "default-src 'self'; object-src 'none'; script-src 'self' 'nonce-" + nonce + "'; report-uri /csp-report"
+	if got := rec.Header().Get("Content-Security-Policy"); got != wantCSP {
+		t.Errorf("CSP = %q, want %q", got, wantCSP)
+	}
+	if !strings.Contains(rec.Body.String(), `nonce="`+nonce+`"`) {
+		t.Errorf("Expected @cspNonce to render the request nonce, got %q", rec.Body.String())
+	}
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=600; includeSubDomains" {
+		t.Errorf("Unexpected HSTS %q", got)
+	}
+	if got := rec.Header().Get("Permissions-Policy"); got != `camera=(), geolocation=(self "https://maps.example.com")` {
+		t.Errorf("Unexpected Permissions-Policy %q", got)
+	}
+
+	// A second request gets a different nonce
+	first := nonce
+	h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), nil)
+	if nonce == first {
+		t.Error("Expected a fresh nonce per request")
+	}
+}
+
+func TestSecurityHeadersReportOnly(t *testing.T) {
+	cfg := config.HeadersConfig{CSP: config.CSPConfig{
+		Enabled:    true,
+		ReportOnly: true,
+		Directives: map[string][]string{"default-src": {"'self'"}},
+	}}
+
+	rec := httptest.NewRecorder()
+	SecurityHeaders(cfg)(func(w http.ResponseWriter, r *http.Request, params map[string]string) {})(rec, httptest.NewRequest("GET", "/", nil), nil)
+
+	if rec.Header().Get("Content-Security-Policy") != "" {
+		t.Error("Expected no enforcing CSP in report-only mode")
+	}
+	if got := rec.Header().Get("Content-Security-Policy-Report-Only"); got != "default-src 'self'" {
+		t.Errorf("Unexpected report-only policy %q", got)
+	}
+}
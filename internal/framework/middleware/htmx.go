@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/router"
+)
+
+// VaryHTMX adds Vary: HX-Request to responses, so browsers and shared caches
+// store the fragment HTMX gets (view.Engine.RenderHTMX) apart from the full
+// page of the same URL. Without it, going back to a page can show a bare
+// fragment.
+func VaryHTMX(next router.Handler) router.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		w.Header().Add("Vary", "HX-Request")
+		next(w, r, params)
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaryHTMX(t *testing.T) {
+	h := VaryHTMX(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		w.Header().Add("Vary", "Accept-Encoding")
+	})
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/", nil), nil)
+	if got := rec.Header().Values("Vary"); len(got) != 2 || got[0] != "HX-Request" || got[1] != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want HX-Request kept alongside the handler's", got)
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/i18n"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/router"
+)
+
+// LocaleCookie remembers the locale a visitor picked with ?lang=
+const LocaleCookie = "lang"
+
+// Locale picks the locale of each request and attaches its i18n.Localizer to
+// the context (i18n.FromContext) and the response writer, where templates
+// find it for @t and the date and number helpers. The first of these the
+// bundle has a catalog for wins:
+//
+//  1. The lang query parameter (?lang=es), which is also stored in the lang
+//     cookie for a year, so a language switcher is just links
+//  2. The lang cookie
+//  3. Accept-Language, by quality
+//  4. The bundle's default locale (app.locale)
+//
+// Responses get Content-Language and Vary: Accept-Language, except on routes
+// registered with router.Unlocalized(), which are passed through untouched.
+func Locale(bundle *i18n.Bundle, cfg config.SecurityConfig) router.Middleware {
+	return func(next router.Handler) router.Handler {
+		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			if policy, _ := router.PolicyFromContext(r.Context()); policy.Unlocalized {
+				next(w, r, params)
+				return
+			}
+
+			tag := ""
+			if lang := r.URL.Query().Get(LocaleCookie); lang != "" {
+				if tag = bundle.Match(lang); tag != "" {
+					http.SetCookie(w, &http.Cookie{
+						Name:     LocaleCookie,
+						Value:    tag,
+						Path:     "/",
+						MaxAge:   365 * 24 * 60 * 60,
+						HttpOnly: true,
+						Secure:   cfg.SecureCookies,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+			}
+			if c, err := r.Cookie(LocaleCookie); tag == "" && err == nil {
+				tag = bundle.Match(c.Value)
+			}
+			if tag == "" {
+				tag = bundle.Match(parseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+			}
+
+			l := bundle.Localizer(tag)
+			w.Header().Add("Vary", "Accept-Language")
+			if l.Locale() != "" {
+				w.Header().Set("Content-Language", l.Locale())
+			}
+			next(&localeWriter{wrappedWriter: wrappedWriter{w}, Localizer: l}, r.WithContext(i18n.NewContext(r.Context(), l)), params)
+		}
+	}
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header, most
+// preferred first. Tags with q=0 and the * wildcard are left out.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+// localeWriter hands the request's Localizer to @t and the formatting helpers
+type localeWriter struct {
+	wrappedWriter
+	*i18n.Localizer // Locale implements view.LocaleWriter and T view.Translator
+}
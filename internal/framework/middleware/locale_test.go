@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/i18n"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/router"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/view"
+)
+
+func TestLocale(t *testing.T) {
+	bundle := i18n.NewBundle("en-US")
+	if err := bundle.AddJSON("en", []byte(`{"hello": "Hello, {name}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.AddJSON("es", []byte(`{"hello": "Hola, {name}"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(`@locale @t("hello", "name", .) @number(1234.5)`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	views, err := view.NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var translated string
+	h := Locale(bundle, config.SecurityConfig{})(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		translated = i18n.T(r.Context(), "hello", "name", "Ana")
+		if err := views.Render(w, "page", "Ana"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		url    string
+		cookie string
+		accept string
+		want   string
+		hello  string
+	}{
+		{"default", "/", "", "", "en-US Hello, Ana 1,234.50", "Hello, Ana"},
+		{"Accept-Language", "/", "", "fr;q=0.9, es-MX;q=0.8, en;q=0.5", "es-MX Hola, Ana 1.234,50", "Hola, Ana"},
+		{"cookie", "/", "es", "en", "es Hola, Ana 1.234,50", "Hola, Ana"},
+		{"query", "/?lang=en", "es", "es", "en Hello, Ana 1,234.50", "Hello, Ana"},
+		{"unknown query", "/?lang=xx", "es", "", "es Hola, Ana 1.234,50", "Hola, Ana"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: LocaleCookie, Value: tt.cookie})
+		}
+		if tt.accept != "" {
+			req.Header.Set("Accept-Language", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req, nil)
+
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, got, tt.want)
+		}
+		if translated != tt.hello {
+			t.Errorf("%s: i18n.T = %q, want %q", tt.name, translated, tt.hello)
+		}
+		if rec.Header().Get("Vary") != "Accept-Language" {
+			t.Errorf("%s: Vary = %q", tt.name, rec.Header().Get("Vary"))
+		}
+	}
+
+	// ?lang= is remembered
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/?lang=es", nil), nil)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != LocaleCookie || cookies[0].Value != "es" || cookies[0].MaxAge <= 0 {
+		t.Errorf("Expected a lang=es cookie, got %v", cookies)
+	}
+	if got := rec.Header().Get("Content-Language"); got != "es" {
+		t.Errorf("Content-Language = %q", got)
+	}
+}
+
+func TestLocaleUnlocalized(t *testing.T) {
+	bundle := i18n.NewBundle("en")
+	if err := bundle.AddJSON("es", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := router.New()
+	r.Use(Locale(bundle, config.SecurityConfig{}))
+	r.GET("/static/{path...}", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		w.Write([]byte("body{}"))
+	}, router.Public(), router.Unlocalized())
+
+	// Static files are the same in every language, so caches keep one copy
+	req := httptest.NewRequest("GET", "/static/app.css", nil)
+	req.Header.Set("Accept-Language", "es")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if vary, lang := rec.Header().Get("Vary"), rec.Header().Get("Content-Language"); vary != "" || lang != "" {
+		t.Errorf("Vary = %q, Content-Language = %q, want neither", vary, lang)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"es", []string{"es"}},
+		{"en;q=0.5, pt-BR, *;q=0.1, de;q=0, fr;q=0.8", []string{"pt-BR", "fr", "en"}},
+		{"es;q=bad, it", []string{"it"}},
+	}
+	for _, tt := range tests {
+		if got := parseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
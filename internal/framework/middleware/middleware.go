@@ -4,7 +4,7 @@
 //
 // This package implements critical security and observability middleware:
 //   - CSRF protection (prevents cross-site request forgery attacks)
-//   - JWT authentication (validates JWT tokens from cookies)
+//   - JWT authentication (validates JWT tokens from cookies, and API keys)
 //   - Rate limiting (prevents abuse and DoS attacks)
 //   - Request ID generation (for request tracing)
 //   - Logging (HTTP request/response logging)
@@ -23,29 +23,35 @@
 //
 // MIDDLEWARE EXECUTION ORDER (in main.go):
 //  1. Global middleware (apply to all routes):
-//     - Rate limiting (if enabled)
+//     - Client IP/scheme/host resolution (forwarding headers of trusted proxies)
 //     - Request ID generation
 //     - Logging
 //     - Panic recovery
-//  2. Route-specific middleware (apply to specific routes):
-//     - CSRF protection (forms)
-//     - JWT authentication (protected routes)
+//     - Locale negotiation (?lang=, lang cookie, Accept-Language; see locale.go)
+//     - Vary: HX-Request, so HTMX fragments and full pages are cached apart
+//     - CSRF protection (skipped for routes declared router.CSRFExempt)
+//     - JWT authentication (skipped for routes declared router.Public)
+//     - Rate limiting (if enabled; after JWT so limits can be per user)
+//  2. Route-specific checks (enforced by the router):
 //     - Role-based access control (admin routes)
 //
 // USAGE IN MAIN.GO:
 //
 //	// Global middleware
 //	r := router.NewRouter()
-//	if cfg.RateLimit.Enabled {
-//	    r.Use(middleware.RateLimit(cfg.RateLimit))
-//	}
+//	r.Use(middleware.ProxyHeaders(trustedProxies))
 //	r.Use(middleware.RequestID())
 //	r.Use(middleware.Logging)
 //	r.Use(middleware.Recover)
+//	r.Use(middleware.CSRFMiddleware(cfg.Security))
+//	r.Use(middleware.JWTAuthMiddleware(cfg.Security))
+//	if cfg.RateLimit.Enabled {
+//	    limiter, _ := ratelimit.FromConfig(cfg.RateLimit)
+//	    r.Use(middleware.RateLimit(limiter))
+//	}
 //
-//	// Route-specific middleware
-//	adminAuth := middleware.RequireRole("admin")
-//	r.Handle("GET", "/admin", adminAuth(handleDashboard))
+//	// Route protection is declared per route (see package router)
+//	r.Handle("GET", "/admin", handleDashboard, router.RequireRole("admin"))
 //
 // ADDING CUSTOM MIDDLEWARE:
 // Create your own middleware in internal/app/middleware/custom.go:
@@ -82,15 +88,21 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
-	"net"
 	"net/http"
-	"strings"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/AlejandroMBJS/goBastion/internal/framework/apikey"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/cors"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/httperr"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/ratelimit"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/router"
 	"github.com/AlejandroMBJS/goBastion/internal/framework/security"
 )
@@ -102,6 +114,7 @@ type contextKey string
 const (
 	requestIDKey contextKey = "requestID"
 	claimsKey    contextKey = "claims"
+	apiKeyKey    contextKey = "apiKey"
 )
 
 // 1. RequestID generates and attaches a unique request ID
@@ -123,21 +136,60 @@ func RequestID() router.Middleware {
 	}
 }
 
-// 2. Logging logs HTTP requests with method, path, status, and duration
+// loggingConfig is the logging section in effect, swapped by ConfigureLogging
+var loggingConfig atomic.Pointer[config.LoggingConfig]
+
+// ConfigureLogging applies the logging section of config.json to Logging.
+// It is safe to call while serving, so it can subscribe to config reloads.
+func ConfigureLogging(cfg config.LoggingConfig) {
+	loggingConfig.Store(&cfg)
+}
+
+// 2. Logging logs HTTP requests with method, path, status, and duration.
+// logging.level "warn" only logs 4xx/5xx responses and "error" only 5xx;
+// logging.format "json" writes one JSON object per line.
 func Logging(next router.Handler) router.Handler {
 	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 		start := time.Now()
 
 		// Wrap response writer to capture status code
-		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		wrapped := &responseWriter{wrappedWriter: wrappedWriter{w}, statusCode: http.StatusOK}
 
 		next(wrapped, r, params)
 
 		duration := time.Since(start)
-		requestID := GetRequestID(r.Context())
+		cfg := config.LoggingConfig{Level: "info", Format: "text", RequestID: true}
+		if c := loggingConfig.Load(); c != nil {
+			cfg = *c
+		}
+		switch {
+		case cfg.Level == "warn" && wrapped.statusCode < 400,
+			cfg.Level == "error" && wrapped.statusCode < 500:
+			return
+		}
+
+		requestID := ""
+		if cfg.RequestID {
+			requestID = GetRequestID(r.Context())
+		}
+
+		if cfg.Format == "json" {
+			line, _ := json.Marshal(map[string]any{
+				"time":        start.UTC().Format(time.RFC3339Nano),
+				"request_id":  requestID,
+				"ip":          ClientIP(r),
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      wrapped.statusCode,
+				"duration_ms": float64(duration.Microseconds()) / 1000,
+			})
+			fmt.Fprintln(log.Writer(), string(line))
+			return
+		}
 
-		log.Printf("[%s] %s %s - %d - %v\n",
+		log.Printf("[%s] %s %s %s - %d - %v\n",
 			requestID,
+			ClientIP(r),
 			r.Method,
 			r.URL.Path,
 			wrapped.statusCode,
@@ -154,9 +206,11 @@ func Recover(next router.Handler) router.Handler {
 				requestID := GetRequestID(r.Context())
 				log.Printf("[%s] PANIC: %v\n", requestID, err)
 
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				fmt.Fprintf(w, `{"error":"Internal server error","request_id":"%s"}`, requestID)
+				httperr.WriteProblem(w, r, httperr.Problem{
+					Status:    http.StatusInternalServerError,
+					Detail:    "Internal server error",
+					RequestID: requestID,
+				})
 			}
 		}()
 
@@ -177,42 +231,48 @@ func WithTimeout(timeout time.Duration) router.Middleware {
 	}
 }
 
-// 5. CORSMiddleware handles Cross-Origin Resource Sharing
-func CORSMiddleware(allowedOrigins []string) router.Middleware {
+// 5. CORS adds the CORS headers of the matched route's policy (router.CORS, or
+// "default") to its responses. Preflight requests never reach it: the router
+// hands them to CORSPreflight.
+func CORS(policies cors.Policies) router.Middleware {
 	return func(next router.Handler) router.Handler {
 		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-			origin := r.Header.Get("Origin")
-
-			// Check if origin is allowed
-			allowed := false
-			for _, allowedOrigin := range allowedOrigins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					allowed = true
-					break
-				}
-			}
-
-			if allowed {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-				w.Header().Set("Access-Control-Max-Age", "86400")
-			}
+			policy, _ := router.PolicyFromContext(r.Context())
+			policies.Get(policy.CORS).Actual(w, r)
+			next(w, r, params)
+		}
+	}
+}
 
-			// Handle preflight
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
+// CORSPreflight answers CORS preflight requests with the policy of the route
+// being asked about (see router.Preflight). Allowed preflights get 204 with the
+// Access-Control-Allow-* headers; disallowed ones get 403 without them.
+// OPTIONS requests that aren't preflights get 204 with just the Allow header.
+func CORSPreflight(policies cors.Policies) router.Handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !cors.IsPreflight(r) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 
-			next(w, r, params)
+		policy, _ := router.PolicyFromContext(r.Context())
+		if err := policies.Get(policy.CORS).Preflight(w, r); err != nil {
+			httperr.Write(w, r, http.StatusForbidden, "CORS preflight rejected: "+err.Error())
+			return
 		}
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-// 6. CSRFMiddleware implements CSRF protection using double-submit cookie
+// 6. CSRFMiddleware protects cookie-authenticated requests against cross-site
+// request forgery. Every request gets a token bound to the browser's CSRF cookie
+// and the signed-in user; templates write it with @csrfField (forms) and
+// @csrfHeaders (hx-headers for HTMX). Unsafe requests must send it back in the
+// csrf_token form field or the CSRF header, and must come from this site or a
+// trusted origin when the browser says where they come from.
 func CSRFMiddleware(cfg config.SecurityConfig) router.Middleware {
+	trusted := csrfTrustedOrigins(cfg.CSRFTrustedOrigins)
+
 	return func(next router.Handler) router.Handler {
 		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 			if !cfg.EnableCSRF {
@@ -220,47 +280,38 @@ func CSRFMiddleware(cfg config.SecurityConfig) router.Middleware {
 				return
 			}
 
-			// Skip CSRF for auth endpoints and API routes (JWT provides protection)
-			if shouldSkipAuth(r.URL.Path) || strings.HasPrefix(r.URL.Path, "/api/") {
+			// Skip CSRF for routes declared exempt, and for requests carrying their
+			// credentials in the Authorization header (browsers never attach it on their own)
+			if policy, _ := router.PolicyFromContext(r.Context()); policy.CSRFExempt || r.Header.Get("Authorization") != "" {
 				next(w, r, params)
 				return
 			}
 
-			method := r.Method
+			secret := csrfSecret(w, r, cfg)
+			session := csrfSession(r, cfg.JWTSecret)
+			token := security.CSRFToken(cfg.JWTSecret, secret, session)
+			r = r.WithContext(context.WithValue(r.Context(), csrfTokenKey, token))
+			w = &csrfWriter{wrappedWriter: wrappedWriter{w}, token: token, header: cfg.CSRFHeaderName}
 
 			// Safe methods don't need CSRF validation
-			if method == "GET" || method == "HEAD" || method == "OPTIONS" {
-				// Generate token if cookie is missing
-				cookie, err := r.Cookie(cfg.CSRFCookieName)
-				if err != nil || cookie.Value == "" {
-					token, err := security.GenerateCSRFToken(cfg.JWTSecret)
-					if err == nil {
-						http.SetCookie(w, &http.Cookie{
-							Name:     cfg.CSRFCookieName,
-							Value:    token,
-							Path:     "/",
-							HttpOnly: true,
-							Secure:   false, // Set to true in production with HTTPS
-							SameSite: http.SameSiteStrictMode,
-						})
-					}
-				}
+			if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
 				next(w, r, params)
 				return
 			}
 
 			// Mutating methods need validation
-			cookie, err := r.Cookie(cfg.CSRFCookieName)
-			if err != nil {
-				w.WriteHeader(http.StatusForbidden)
-				w.Write([]byte(`{"error":"CSRF token missing"}`))
+			if err := checkCSRFOrigin(r, trusted); err != nil {
+				httperr.Write(w, r, http.StatusForbidden, "CSRF check failed: "+err.Error())
 				return
 			}
 
-			headerToken := r.Header.Get(cfg.CSRFHeaderName)
-			if !security.ValidateCSRFToken(cfg.JWTSecret, cookie.Value, headerToken) {
-				w.WriteHeader(http.StatusForbidden)
-				w.Write([]byte(`{"error":"CSRF token invalid"}`))
+			submitted := submittedCSRFToken(r, cfg.CSRFHeaderName)
+			if submitted == "" {
+				httperr.Write(w, r, http.StatusForbidden, "CSRF token missing")
+				return
+			}
+			if !security.ValidateCSRFToken(cfg.JWTSecret, secret, session, submitted) {
+				httperr.Write(w, r, http.StatusForbidden, "CSRF token invalid")
 				return
 			}
 
@@ -269,7 +320,8 @@ func CSRFMiddleware(cfg config.SecurityConfig) router.Middleware {
 	}
 }
 
-// 7. JWTAuthMiddleware validates JWT tokens from Authorization header or auth_token cookie
+// 7. JWTAuthMiddleware validates JWT tokens from Authorization header or auth_token cookie.
+// Bearer tokens starting with "gbk_" are treated as API keys (see package apikey).
 func JWTAuthMiddleware(cfg config.SecurityConfig) router.Middleware {
 	return func(next router.Handler) router.Handler {
 		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
@@ -278,8 +330,8 @@ func JWTAuthMiddleware(cfg config.SecurityConfig) router.Middleware {
 				return
 			}
 
-			// Skip auth for certain paths
-			if shouldSkipAuth(r.URL.Path) {
+			// Public routes are declared at registration; everything else requires credentials
+			if policy, _ := router.PolicyFromContext(r.Context()); policy.Access == router.AccessPublic {
 				next(w, r, params)
 				return
 			}
@@ -292,34 +344,57 @@ func JWTAuthMiddleware(cfg config.SecurityConfig) router.Middleware {
 			if authHeader != "" {
 				token, err = security.ExtractBearerToken(authHeader)
 				if err != nil {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusUnauthorized)
-					w.Write([]byte(`{"error":"Invalid authorization header"}`))
+					httperr.Write(w, r, http.StatusUnauthorized, "Invalid authorization header")
 					return
 				}
 			} else {
 				// If no header, try to get token from cookie
 				cookie, err := r.Cookie("auth_token")
 				if err != nil || cookie.Value == "" {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusUnauthorized)
-					w.Write([]byte(`{"error":"Missing authorization header or cookie"}`))
+					httperr.Write(w, r, http.StatusUnauthorized, "Missing authorization header or cookie")
 					return
 				}
 				token = cookie.Value
 			}
 
+			// API keys (personal access tokens) are accepted in the Authorization header only
+			if authHeader != "" && apikey.IsAPIKey(token) {
+				key, user, err := apikey.Authenticate(r.Context(), token, ClientIP(r))
+				if err != nil {
+					httperr.Write(w, r, http.StatusUnauthorized, "Invalid or expired API key")
+					return
+				}
+				if !apikey.Allows(key, r.Method) {
+					httperr.Write(w, r, http.StatusForbidden, "API key lacks the required scope")
+					return
+				}
+
+				claims := security.Claims{
+					Sub:  strconv.FormatInt(user.ID, 10),
+					Role: apikey.EffectiveRole(key, user),
+					Iat:  key.CreatedAt.Unix(),
+				}
+				if !key.ExpiresAt.IsZero() {
+					claims.Exp = key.ExpiresAt.Unix()
+				}
+
+				ctx := context.WithValue(r.Context(), claimsKey, claims)
+				ctx = context.WithValue(ctx, apiKeyKey, key)
+				ctx = router.WithPrincipal(ctx, principalFor(claims))
+				next(w, r.WithContext(ctx), params)
+				return
+			}
+
 			// Validate the token
 			claims, err := security.ParseAndValidateToken(cfg.JWTSecret, token)
 			if err != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte(`{"error":"Invalid or expired token"}`))
+				httperr.Write(w, r, http.StatusUnauthorized, "Invalid or expired token")
 				return
 			}
 
 			// Store claims in context
 			ctx := context.WithValue(r.Context(), claimsKey, claims)
+			ctx = router.WithPrincipal(ctx, principalFor(claims))
 			r = r.WithContext(ctx)
 
 			next(w, r, params)
@@ -327,23 +402,21 @@ func JWTAuthMiddleware(cfg config.SecurityConfig) router.Middleware {
 	}
 }
 
-// 8. RequireRole checks if the user has the required role
+// 8. RequireRole checks if the user has the required role.
+// Prefer declaring router.RequireRole when registering the route, which also
+// shows up in `go-bastion routes`.
 func RequireRole(role string) router.Middleware {
 	return func(next router.Handler) router.Handler {
 		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 			claims := GetClaims(r.Context())
 			if claims == nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte(`{"error":"Authentication required"}`))
+				httperr.Write(w, r, http.StatusUnauthorized, "Authentication required")
 				return
 			}
 
 			// Check role
 			if claims.Role != role && claims.Role != "admin" {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusForbidden)
-				w.Write([]byte(`{"error":"Insufficient permissions"}`))
+				httperr.Write(w, r, http.StatusForbidden, "Insufficient permissions")
 				return
 			}
 
@@ -352,15 +425,44 @@ func RequireRole(role string) router.Middleware {
 	}
 }
 
-// 9. SecurityHeaders sets security-related HTTP headers
-func SecurityHeaders() router.Middleware {
+// 9. SecurityHeaders sets security-related HTTP headers from security.headers:
+// a Content-Security-Policy with a fresh nonce per request (available to
+// templates as @cspNonce and to handlers via CSPNonce), HSTS, Permissions-Policy,
+// COOP/COEP, X-Frame-Options and Referrer-Policy.
+func SecurityHeaders(cfg config.HeadersConfig) router.Middleware {
+	csp := newCSPPolicy(cfg.CSP)
+	hsts := hstsValue(cfg.HSTS)
+	permissions := permissionsPolicyValue(cfg.PermissionsPolicy)
+
 	return func(next router.Handler) router.Handler {
 		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-			w.Header().Set("X-Content-Type-Options", "nosniff")
-			w.Header().Set("X-Frame-Options", "SAMEORIGIN")
-			w.Header().Set("Referrer-Policy", "no-referrer")
-			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-			w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net")
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			if cfg.FrameOptions != "" {
+				h.Set("X-Frame-Options", cfg.FrameOptions)
+			}
+			if cfg.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+			}
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			if permissions != "" {
+				h.Set("Permissions-Policy", permissions)
+			}
+			if cfg.CrossOriginOpenerPolicy != "" {
+				h.Set("Cross-Origin-Opener-Policy", cfg.CrossOriginOpenerPolicy)
+			}
+			if cfg.CrossOriginEmbedderPolicy != "" {
+				h.Set("Cross-Origin-Embedder-Policy", cfg.CrossOriginEmbedderPolicy)
+			}
+
+			if cfg.CSP.Enabled {
+				nonce := generateNonce()
+				h.Set(csp.header, csp.value(nonce))
+				r = r.WithContext(context.WithValue(r.Context(), cspNonceKey, nonce))
+				w = &nonceWriter{wrappedWriter: wrappedWriter{w}, nonce: nonce}
+			}
 
 			next(w, r, params)
 		}
@@ -377,22 +479,28 @@ func MaxBodySize(limitBytes int64) router.Middleware {
 	}
 }
 
-// 11. RateLimit implements a simple rate limiter
-func RateLimit(cfg config.RateLimitConfig) router.Middleware {
-	limiter := newRateLimiter(cfg.RequestsPerMinute)
-
+// 11. RateLimit enforces the rate limit policy of the matched route
+// (router.RateLimit, or "default"). Install it after JWTAuthMiddleware so
+// policies keyed by user or API key can see the caller.
+//
+// Responses carry RateLimit-* headers; rejected requests get 429 with
+// Retry-After. If the store fails the request is let through and the error
+// logged, so a database hiccup doesn't take the site down.
+func RateLimit(limiter *ratelimit.Limiter) router.Middleware {
 	return func(next router.Handler) router.Handler {
 		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-			if !cfg.Enabled {
+			policy, _ := router.PolicyFromContext(r.Context())
+
+			res, err := limiter.Take(r.Context(), policy.RateLimit, rateLimitIdentity(r))
+			if err != nil {
+				log.Printf("[%s] rate limit: %v", GetRequestID(r.Context()), err)
 				next(w, r, params)
 				return
 			}
 
-			ip := getIP(r)
-			if !limiter.Allow(ip) {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-				w.Write([]byte(`{"error":"Too many requests"}`))
+			ratelimit.SetHeaders(w.Header(), res)
+			if !res.Allowed {
+				httperr.Write(w, r, http.StatusTooManyRequests, "Too many requests")
 				return
 			}
 
@@ -401,6 +509,18 @@ func RateLimit(cfg config.RateLimitConfig) router.Middleware {
 	}
 }
 
+// rateLimitIdentity is who the request is counted as
+func rateLimitIdentity(r *http.Request) ratelimit.Identity {
+	id := ratelimit.Identity{IP: ClientIP(r)}
+	if pr, ok := router.PrincipalFromContext(r.Context()); ok {
+		id.User = pr.Subject
+	}
+	if key := GetAPIKey(r.Context()); key != nil {
+		id.APIKey = strconv.FormatInt(key.ID, 10)
+	}
+	return id
+}
+
 // Helper functions
 
 func generateRequestID() string {
@@ -423,44 +543,45 @@ func GetClaims(ctx context.Context) *security.Claims {
 	return nil
 }
 
-func shouldSkipAuth(path string) bool {
-	skipPaths := []string{
-		"/api/v1/auth/register",
-		"/api/v1/auth/login",
-		"/docs",
-		"/docs/openapi.json",
-		"/login",
-		"/register",
+// GetAPIKey returns the API key that authenticated the request, or nil for
+// requests authenticated with a JWT
+func GetAPIKey(ctx context.Context) *db.APIKey {
+	if key, ok := ctx.Value(apiKeyKey).(db.APIKey); ok {
+		return &key
 	}
+	return nil
+}
 
-	for _, skipPath := range skipPaths {
-		if strings.HasPrefix(path, skipPath) {
-			return true
-		}
-	}
-	return false
+var (
+	permissionsMu   sync.RWMutex
+	rolePermissions = map[string][]string{}
+)
+
+// GrantPermissions gives every user with the role the listed permissions, as
+// required by routes registered with router.RequirePermission. The admin role
+// passes every permission check without grants.
+func GrantPermissions(role string, perms ...string) {
+	permissionsMu.Lock()
+	defer permissionsMu.Unlock()
+	rolePermissions[role] = append(rolePermissions[role], perms...)
 }
 
-func getIP(r *http.Request) string {
-	// Try X-Forwarded-For first
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		return strings.TrimSpace(ips[0])
-	}
+// principalFor builds the router principal for authenticated claims
+func principalFor(claims security.Claims) router.Principal {
+	permissionsMu.RLock()
+	perms := append([]string(nil), rolePermissions[claims.Role]...)
+	permissionsMu.RUnlock()
 
-	// Try X-Real-IP
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	return router.Principal{
+		Subject:     claims.Sub,
+		Role:        claims.Role,
+		Permissions: perms,
 	}
-
-	// Fall back to RemoteAddr
-	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
-	return ip
 }
 
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
-	http.ResponseWriter
+	wrappedWriter
 	statusCode int
 }
 
@@ -468,83 +589,3 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
-
-// Simple rate limiter implementation
-type rateLimiter struct {
-	visitors map[string]*visitor
-	mu       sync.RWMutex
-	limit    int
-	window   time.Duration
-}
-
-type visitor struct {
-	requests []time.Time
-	mu       sync.Mutex
-}
-
-func newRateLimiter(requestsPerMinute int) *rateLimiter {
-	rl := &rateLimiter{
-		visitors: make(map[string]*visitor),
-		limit:    requestsPerMinute,
-		window:   time.Minute,
-	}
-
-	// Cleanup old visitors every 5 minutes
-	go rl.cleanup()
-
-	return rl
-}
-
-func (rl *rateLimiter) Allow(ip string) bool {
-	rl.mu.RLock()
-	v, exists := rl.visitors[ip]
-	rl.mu.RUnlock()
-
-	if !exists {
-		v = &visitor{requests: make([]time.Time, 0)}
-		rl.mu.Lock()
-		rl.visitors[ip] = v
-		rl.mu.Unlock()
-	}
-
-	v.mu.Lock()
-	defer v.mu.Unlock()
-
-	now := time.Now()
-	cutoff := now.Add(-rl.window)
-
-	// Remove old requests
-	valid := 0
-	for i, t := range v.requests {
-		if t.After(cutoff) {
-			valid = i
-			break
-		}
-	}
-	v.requests = v.requests[valid:]
-
-	// Check limit
-	if len(v.requests) >= rl.limit {
-		return false
-	}
-
-	v.requests = append(v.requests, now)
-	return true
-}
-
-func (rl *rateLimiter) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		for ip, v := range rl.visitors {
-			v.mu.Lock()
-			if len(v.requests) == 0 || time.Since(v.requests[len(v.requests)-1]) > 10*time.Minute {
-				delete(rl.visitors, ip)
-			}
-			v.mu.Unlock()
-		}
-		rl.mu.Unlock()
-	}
-}
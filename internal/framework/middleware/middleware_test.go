@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Streaming handlers (Server-Sent Events, view.Stream) flush through the
+// writer Logging wraps
+func TestLoggingFlush(t *testing.T) {
+	handler := Logging(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		w.Write([]byte("data: hi\n\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() = %v", err)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/events", nil), nil)
+	if !rec.Flushed {
+		t.Error("the response wasn't flushed")
+	}
+}
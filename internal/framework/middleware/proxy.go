@@ -0,0 +1,274 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/router"
+)
+
+const clientInfoKey contextKey = "clientInfo"
+
+// ClientInfo is where a request really came from, as resolved by ProxyHeaders
+type ClientInfo struct {
+	IP     string // client address
+	Scheme string // "http" or "https", as used by the client
+	Host   string // host (and port) the client asked for
+}
+
+// ClientInfoFromContext returns the client info resolved by ProxyHeaders
+func ClientInfoFromContext(ctx context.Context) (ClientInfo, bool) {
+	info, ok := ctx.Value(clientInfoKey).(ClientInfo)
+	return info, ok
+}
+
+// ClientIP returns the client IP address for the request
+func ClientIP(r *http.Request) string {
+	return clientInfo(r).IP
+}
+
+// RequestScheme returns the scheme the client used ("http" or "https")
+func RequestScheme(r *http.Request) string {
+	return clientInfo(r).Scheme
+}
+
+// RequestHost returns the host the client asked for
+func RequestHost(r *http.Request) string {
+	return clientInfo(r).Host
+}
+
+// clientInfo returns the info resolved by ProxyHeaders or, without it, what the
+// connection itself says
+func clientInfo(r *http.Request) ClientInfo {
+	if info, ok := ClientInfoFromContext(r.Context()); ok {
+		return info
+	}
+	return directClientInfo(r)
+}
+
+func directClientInfo(r *http.Request) ClientInfo {
+	info := ClientInfo{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		info.Scheme = "https"
+	}
+	if addr, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		info.IP = addr.Addr().Unmap().String()
+	} else {
+		info.IP, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	return info
+}
+
+// ParseTrustedProxies parses server.trusted_proxies, accepting CIDRs and single IPs
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// 12. ProxyHeaders resolves the client IP, scheme and host of every request and
+// stores them in the context (see ClientIP, RequestScheme and RequestHost).
+// Install it first so every other middleware sees the resolved values.
+//
+// Forwarding headers are only believed when the connection comes from one of
+// the trusted proxies. The chain of addresses in Forwarded (RFC 7239) or, without
+// it, X-Forwarded-For is walked from the right, skipping trusted proxies, and
+// the first address that isn't one is the client; anything to its left was
+// written by the client and is ignored. With no trusted proxies, forwarding
+// headers are ignored entirely.
+func ProxyHeaders(trusted []netip.Prefix) router.Middleware {
+	return func(next router.Handler) router.Handler {
+		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			info := resolveClientInfo(r, trusted)
+			next(w, r.WithContext(context.WithValue(r.Context(), clientInfoKey, info)), params)
+		}
+	}
+}
+
+// forwardedHop is one proxy's view of the request
+type forwardedHop struct {
+	addr  string // "for"
+	proto string
+	host  string
+}
+
+func resolveClientInfo(r *http.Request, trusted []netip.Prefix) ClientInfo {
+	info := directClientInfo(r)
+	if !isTrustedProxy(info.IP, trusted) {
+		return info
+	}
+
+	hops := forwardedHops(r)
+	if len(hops) == 0 {
+		if ip, ok := parseForwardedAddr(r.Header.Get("X-Real-IP")); ok {
+			info.IP = ip
+		}
+		return info
+	}
+
+	// Walk from the proxy nearest to us towards the client. Each hop was written
+	// by the proxy to its right, so it is only as trustworthy as that proxy.
+	client := -1
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseForwardedAddr(hops[i].addr)
+		if !ok {
+			break // obfuscated or garbage: stop at the last trusted proxy
+		}
+		client = i
+		info.IP = ip
+		if !isTrustedProxy(ip, trusted) {
+			break
+		}
+	}
+	if client < 0 {
+		return info
+	}
+
+	// The hop that names the client also says how the client connected
+	if proto := strings.ToLower(hops[client].proto); proto == "http" || proto == "https" {
+		info.Scheme = proto
+	}
+	if host := hops[client].host; validForwardedHost(host) {
+		info.Host = host
+	}
+	return info
+}
+
+// forwardedHops reads Forwarded or, without it, X-Forwarded-For/-Proto/-Host.
+// Proto and host lists that don't line up with X-Forwarded-For are taken to be
+// set (not appended) by the nearest proxy.
+func forwardedHops(r *http.Request) []forwardedHop {
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		return parseForwarded(strings.Join(values, ","))
+	}
+
+	addrs := headerList(r, "X-Forwarded-For")
+	protos := headerList(r, "X-Forwarded-Proto")
+	hosts := headerList(r, "X-Forwarded-Host")
+	hops := make([]forwardedHop, len(addrs))
+	for i, addr := range addrs {
+		hops[i] = forwardedHop{
+			addr:  addr,
+			proto: alignedValue(protos, i, len(addrs)),
+			host:  alignedValue(hosts, i, len(addrs)),
+		}
+	}
+	return hops
+}
+
+func alignedValue(values []string, i, n int) string {
+	switch {
+	case len(values) == n:
+		return values[i]
+	case len(values) > 0:
+		return values[len(values)-1]
+	default:
+		return ""
+	}
+}
+
+// headerList splits every value of a comma-separated header
+func headerList(r *http.Request, name string) []string {
+	var list []string
+	for _, value := range r.Header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(v))
+		}
+	}
+	return list
+}
+
+// parseForwarded parses an RFC 7239 Forwarded header value:
+//
+//	for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711"
+func parseForwarded(value string) []forwardedHop {
+	var hops []forwardedHop
+	for _, element := range splitQuoted(value, ',') {
+		var hop forwardedHop
+		for _, pair := range splitQuoted(element, ';') {
+			k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			v = strings.Trim(strings.TrimSpace(v), `"`)
+			switch strings.ToLower(strings.TrimSpace(k)) {
+			case "for":
+				hop.addr = v
+			case "proto":
+				hop.proto = v
+			case "host":
+				hop.host = v
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// splitQuoted splits s on sep outside double quotes
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseForwardedAddr parses "192.0.2.60", "192.0.2.60:80", "2001:db8::1" or
+// "[2001:db8::1]:4711", returning the normalized IP
+func parseForwardedAddr(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddrPort(s); err == nil {
+		return addr.Addr().Unmap().String(), true
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")); err == nil {
+		return addr.Unmap().String(), true
+	}
+	return "", false
+}
+
+func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// validForwardedHost rejects values that can't be a host[:port]
+func validForwardedHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, " /\\@?#\"")
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyHeaders(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    ClientInfo
+	}{
+		{
+			name:    "untrusted peer can't spoof",
+			remote:  "203.0.113.9:5000",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": "https", "X-Real-IP": "1.2.3.4"},
+			want:    ClientInfo{IP: "203.0.113.9", Scheme: "http", Host: "app.example.com"},
+		},
+		{
+			name:    "rightmost untrusted address wins",
+			remote:  "10.0.0.2:5000",
+			headers: map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7, 10.0.0.5", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "www.example.com"},
+			want:    ClientInfo{IP: "198.51.100.7", Scheme: "https", Host: "www.example.com"},
+		},
+		{
+			name:    "every hop trusted",
+			remote:  "10.0.0.2:5000",
+			headers: map[string]string{"X-Forwarded-For": "10.1.1.1, 10.0.0.5"},
+			want:    ClientInfo{IP: "10.1.1.1", Scheme: "http", Host: "app.example.com"},
+		},
+		{
+			name:    "X-Real-IP from a trusted proxy",
+			remote:  "10.0.0.2:5000",
+			headers: map[string]string{"X-Real-IP": "198.51.100.7"},
+			want:    ClientInfo{IP: "198.51.100.7", Scheme: "http", Host: "app.example.com"},
+		},
+		{
+			name:   "Forwarded takes precedence",
+			remote: "[2001:db8::1]:443",
+			headers: map[string]string{
+				"Forwarded":       `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https;host="shop.example.com", for=10.0.0.5`,
+				"X-Forwarded-For": "1.2.3.4",
+			},
+			want: ClientInfo{IP: "2001:db8:cafe::17", Scheme: "https", Host: "shop.example.com"},
+		},
+		{
+			name:    "obfuscated identifier stops the walk",
+			remote:  "10.0.0.2:5000",
+			headers: map[string]string{"Forwarded": "for=198.51.100.7, for=_hidden, for=10.0.0.5;proto=https"},
+			want:    ClientInfo{IP: "10.0.0.5", Scheme: "https", Host: "app.example.com"},
+		},
+		{
+			name:    "invalid forwarded values are ignored",
+			remote:  "10.0.0.2:5000",
+			headers: map[string]string{"Forwarded": `for=198.51.100.7;proto=javascript;host="evil.example/path"`},
+			want:    ClientInfo{IP: "198.51.100.7", Scheme: "http", Host: "app.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://app.example.com/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			var got ClientInfo
+			ProxyHeaders(trusted)(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+				got, _ = ClientInfoFromContext(r.Context())
+			})(httptest.NewRecorder(), r, nil)
+
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientInfoWithoutProxyHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "https://app.example.com/", nil)
+	r.RemoteAddr = "[::ffff:192.0.2.1]:1234"
+	r.TLS = &tls.ConnectionState{}
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if ip := ClientIP(r); ip != "192.0.2.1" {
+		t.Errorf("ClientIP = %q, want 192.0.2.1", ip)
+	}
+	if scheme := RequestScheme(r); scheme != "https" {
+		t.Errorf("RequestScheme = %q, want https", scheme)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("Expected an invalid CIDR to be rejected")
+	}
+	if _, err := ParseTrustedProxies([]string{"proxy.internal"}); err == nil {
+		t.Error("Expected a hostname to be rejected")
+	}
+}
+
+func TestCSRFOriginBehindProxy(t *testing.T) {
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+
+	check := func(origin string) error {
+		r := httptest.NewRequest("POST", "http://app:8080/form", nil)
+		r.RemoteAddr = "10.0.0.2:5000"
+		r.Header.Set("X-Forwarded-For", "198.51.100.7")
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "www.example.com")
+		r.Header.Set("Origin", origin)
+
+		var err error
+		ProxyHeaders(trusted)(func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			err = checkCSRFOrigin(r, nil)
+		})(httptest.NewRecorder(), r, nil)
+		return err
+	}
+
+	if err := check("https://www.example.com"); err != nil {
+		t.Errorf("Public origin behind the proxy: %v", err)
+	}
+	if check("http://www.example.com") == nil {
+		t.Error("Expected a plain-HTTP origin to be rejected on an HTTPS site")
+	}
+	if check("http://app:8080") == nil {
+		t.Error("Expected the internal host to be rejected")
+	}
+}
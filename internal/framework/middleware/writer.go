@@ -0,0 +1,22 @@
+package middleware
+
+import "net/http"
+
+// wrappedWriter is embedded by the writers this package wraps responses in,
+// such as the ones carrying the CSP nonce, CSRF token and locale to
+// view.Engine.Render, which only sees the ResponseWriter. It keeps what the
+// writer underneath can do reachable through any number of wrappers.
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+// Unwrap lets http.ResponseController, and view.Engine looking for the values
+// above, reach the underlying writer
+func (w wrappedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush keeps streaming responses (Server-Sent Events, view.Stream) working
+func (w wrappedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
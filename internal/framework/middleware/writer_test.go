@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Flushes reach the connection through any stack of wrappers
+func TestWrappedWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var w http.ResponseWriter = &nonceWriter{wrappedWriter: wrappedWriter{rec}, nonce: "n"}
+	w = &csrfWriter{wrappedWriter: wrappedWriter{w}}
+	w = &responseWriter{wrappedWriter: wrappedWriter{w}, statusCode: http.StatusOK}
+
+	if err := http.NewResponseController(w).Flush(); err != nil || !rec.Flushed {
+		t.Errorf("Flush() = %v, recorder flushed = %v", err, rec.Flushed)
+	}
+
+	var inner http.ResponseWriter = w
+	for {
+		if nw, ok := inner.(*nonceWriter); ok {
+			if nw.CSPNonce() != "n" {
+				t.Errorf("CSPNonce() = %q", nw.CSPNonce())
+			}
+			break
+		}
+		u, ok := inner.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			t.Fatal("Unwrap() doesn't reach the nonce writer")
+		}
+		inner = u.Unwrap()
+	}
+}
@@ -0,0 +1,145 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// jwksRefreshInterval limits how often an unknown kid triggers a JWKS refetch
+const jwksRefreshInterval = time.Minute
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// key returns the public key for kid, refetching the JWKS when the key is unknown
+// (providers rotate keys without notice).
+func (p *Provider) key(ctx context.Context, m *metadata, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := lookupKey(p.keys, kid); ok {
+		return k, nil
+	}
+	if time.Since(p.keysFetch) < jwksRefreshInterval && p.keys != nil {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, m.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if pub, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = pub
+		}
+	}
+	p.keys = keys
+	p.keysFetch = time.Now()
+
+	if k, ok := lookupKey(p.keys, kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+}
+
+// lookupKey finds a key by kid; tokens without a kid match a single-key set
+func lookupKey(keys map[string]any, kid string) (any, bool) {
+	if k, ok := keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// verifySignature checks a JWS signature for the supported algorithms
+func verifySignature(alg string, key any, signingInput string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key type does not match RS256", ErrInvalidIDToken)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return fmt.Errorf("%w: key type does not match ES256", ErrInvalidIDToken)
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidIDToken, alg)
+}
@@ -0,0 +1,419 @@
+// Package oidc implements an OpenID Connect relying party for external login.
+//
+// It supports the authorization code flow with PKCE (S256), provider discovery
+// via /.well-known/openid-configuration, and ID token verification against the
+// provider's JWKS (RS256 and ES256), including issuer, audience, expiry and
+// nonce checks. State handling is left to the caller (see internal/app/router/oidc.go).
+//
+// USAGE:
+//
+//	oidc.Configure(cfg.OAuth, cfg.App.BaseURL)
+//
+//	p, ok := oidc.Get("google")
+//	verifier, challenge, _ := oidc.NewPKCE()
+//	authURL, _ := p.AuthCodeURL(ctx, state, nonce, challenge)
+//	// ... user is redirected back to p.RedirectURL() ...
+//	tok, _ := p.Exchange(ctx, code, verifier)
+//	id, _ := p.VerifyIDToken(ctx, tok.IDToken, nonce)
+//
+// For tests and local development, oidctest provides a mock identity provider.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidIDToken  = errors.New("invalid ID token")
+)
+
+// clockSkew is the leeway applied to exp/iat checks
+const clockSkew = time.Minute
+
+// Provider is a configured OpenID Connect identity provider
+type Provider struct {
+	cfg         config.OIDCProviderConfig
+	redirectURL string
+	client      *http.Client
+
+	mu        sync.Mutex
+	meta      *metadata
+	keys      map[string]any
+	keysFetch time.Time
+}
+
+// metadata is the subset of the discovery document we rely on
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse is the token endpoint response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// IDToken holds the verified claims of an ID token
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Expiry        time.Time
+}
+
+// ProviderInfo describes a provider for rendering login buttons
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Provider{}
+	ordered    []ProviderInfo
+)
+
+// Configure registers the providers from the config. Call it once at startup.
+func Configure(cfg config.OAuthConfig, baseURL string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = map[string]*Provider{}
+	ordered = nil
+	for _, pc := range cfg.Providers {
+		if pc.Name == "" || pc.IssuerURL == "" || pc.ClientID == "" {
+			continue
+		}
+		p := NewProvider(pc, baseURL, nil)
+		registry[pc.Name] = p
+		ordered = append(ordered, ProviderInfo{Name: pc.Name, DisplayName: p.DisplayName()})
+	}
+}
+
+// Get returns a configured provider by name
+func Get(name string) (*Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	p, ok := registry[name]
+	return p, ok
+}
+
+// Providers lists configured providers in config order
+func Providers() []ProviderInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]ProviderInfo(nil), ordered...)
+}
+
+// NewProvider creates a provider. The redirect URL is <baseURL>/auth/oidc/<name>/callback.
+// A nil client uses a default client with a 10 second timeout.
+func NewProvider(cfg config.OIDCProviderConfig, baseURL string, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		cfg:         cfg,
+		redirectURL: strings.TrimRight(baseURL, "/") + "/auth/oidc/" + cfg.Name + "/callback",
+		client:      client,
+	}
+}
+
+// Name returns the provider slug
+func (p *Provider) Name() string { return p.cfg.Name }
+
+// DisplayName returns the login button label
+func (p *Provider) DisplayName() string {
+	if p.cfg.DisplayName != "" {
+		return p.cfg.DisplayName
+	}
+	return p.cfg.Name
+}
+
+// RedirectURL returns the callback URL registered with the provider
+func (p *Provider) RedirectURL() string { return p.redirectURL }
+
+// AllowSignup reports whether unknown identities may create local accounts
+func (p *Provider) AllowSignup() bool { return p.cfg.AllowSignup }
+
+// LinkByEmail reports whether identities with a verified email may attach to an existing account
+func (p *Provider) LinkByEmail() bool { return p.cfg.LinkByEmail }
+
+// discover fetches and caches the provider's discovery document
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	wellKnown := strings.TrimRight(p.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var m metadata
+	if err := p.getJSON(ctx, wellKnown, &m); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimRight(m.Issuer, "/") != strings.TrimRight(p.cfg.IssuerURL, "/") {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match configured %q", m.Issuer, p.cfg.IssuerURL)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing required endpoints")
+	}
+
+	p.meta = &m
+	return p.meta, nil
+}
+
+// AuthCodeURL builds the authorization request URL for the code flow with PKCE
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.redirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades an authorization code for tokens
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		json.Unmarshal(body, &e)
+		return nil, fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, e.Error, e.Description)
+	}
+
+	var tok TokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if tok.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return &tok, nil
+}
+
+// VerifyIDToken checks the signature and claims of an ID token and returns its identity.
+// nonce must be the value sent in the authorization request.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	key, err := p.key(ctx, m, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Iss           string          `json:"iss"`
+		Sub           string          `json:"sub"`
+		Aud           audience        `json:"aud"`
+		Azp           string          `json:"azp"`
+		Exp           int64           `json:"exp"`
+		Iat           int64           `json:"iat"`
+		Nonce         string          `json:"nonce"`
+		Email         string          `json:"email"`
+		EmailVerified json.RawMessage `json:"email_verified"`
+		Name          string          `json:"name"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Iss != m.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Iss)
+	case !claims.Aud.contains(p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: token was not issued for this client", ErrInvalidIDToken)
+	case len(claims.Aud) > 1 && claims.Azp != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	case claims.Sub == "":
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	case now.After(time.Unix(claims.Exp, 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidIDToken)
+	case time.Unix(claims.Iat, 0).After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: token issued in the future", ErrInvalidIDToken)
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	// Some providers send email_verified as a string
+	verified := string(claims.EmailVerified) == "true" || string(claims.EmailVerified) == `"true"`
+
+	return &IDToken{
+		Issuer:        claims.Iss,
+		Subject:       claims.Sub,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Name:          claims.Name,
+		Expiry:        time.Unix(claims.Exp, 0),
+	}, nil
+}
+
+// NewPKCE returns a random code verifier and its S256 code challenge
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// RandomString returns n random bytes encoded as base64url, for state and nonce values
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// audience accepts both the string and array forms of the aud claim
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(v string) bool {
+	for _, s := range a {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/oidc"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/oidc/oidctest"
+)
+
+// noRedirect stops the client at the provider's redirect back to the app
+var noRedirect = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func newTestProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+	idp := oidctest.NewServer("test-client", "test-secret")
+	t.Cleanup(idp.Close)
+
+	p := oidc.NewProvider(config.OIDCProviderConfig{
+		Name:         "mock",
+		IssuerURL:    idp.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+	}, "http://app.local", nil)
+	return idp, p
+}
+
+// authorize runs the authorization request and returns the code and state from the callback URL
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	resp, err := noRedirect.Get(authURL)
+	if err != nil {
+		t.Fatalf("Authorization request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect from authorize, got %d", resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid callback URL: %v", err)
+	}
+	if loc.Path != "/auth/oidc/mock/callback" {
+		t.Fatalf("Unexpected callback path %q", loc.Path)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestCodeFlowWithPKCE(t *testing.T) {
+	idp, p := newTestProvider(t)
+	idp.SetIdentity(oidctest.Identity{Subject: "42", Email: "jane@example.com", EmailVerified: true, Name: "Jane"})
+	ctx := context.Background()
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE failed: %v", err)
+	}
+	authURL, err := p.AuthCodeURL(ctx, "state-123", "nonce-456", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+
+	code, state := authorize(t, authURL)
+	if state != "state-123" {
+		t.Errorf("Expected state to round-trip, got %q", state)
+	}
+
+	tok, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+
+	id, err := p.VerifyIDToken(ctx, tok.IDToken, "nonce-456")
+	if err != nil {
+		t.Fatalf("VerifyIDToken failed: %v", err)
+	}
+	if id.Subject != "42" || id.Email != "jane@example.com" || !id.EmailVerified || id.Name != "Jane" {
+		t.Errorf("Unexpected identity: %+v", id)
+	}
+
+	// Codes are single-use
+	if _, err := p.Exchange(ctx, code, verifier); err == nil {
+		t.Error("Expected reused code to be rejected")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	_, p := newTestProvider(t)
+	ctx := context.Background()
+
+	_, challenge, _ := oidc.NewPKCE()
+	authURL, err := p.AuthCodeURL(ctx, "s", "n", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	code, _ := authorize(t, authURL)
+
+	otherVerifier, _, _ := oidc.NewPKCE()
+	if _, err := p.Exchange(ctx, code, otherVerifier); err == nil {
+		t.Error("Expected exchange with wrong PKCE verifier to fail")
+	}
+}
+
+func TestVerifyIDTokenChecks(t *testing.T) {
+	idp, p := newTestProvider(t)
+	ctx := context.Background()
+	id := oidctest.Identity{Subject: "42", Email: "jane@example.com"}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		nonce    string
+		audience string
+		issuedAt time.Time
+	}{
+		{"wrong nonce", "other-nonce", "test-client", now},
+		{"wrong audience", "nonce", "someone-else", now},
+		{"expired", "nonce", "test-client", now.Add(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := idp.IssueIDToken(id, tt.nonce, tt.audience, tt.issuedAt)
+			if err != nil {
+				t.Fatalf("IssueIDToken failed: %v", err)
+			}
+			if _, err := p.VerifyIDToken(ctx, raw, "nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+
+	// Tampered payload must fail signature verification
+	raw, _ := idp.IssueIDToken(id, "nonce", "test-client", now)
+	other, _ := idp.IssueIDToken(oidctest.Identity{Subject: "admin"}, "nonce", "test-client", now)
+	rawParts, otherParts := strings.Split(raw, "."), strings.Split(other, ".")
+	tampered := rawParts[0] + "." + otherParts[1] + "." + rawParts[2]
+	if _, err := p.VerifyIDToken(ctx, tampered, "nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("Expected tampered token to be rejected, got %v", err)
+	}
+}
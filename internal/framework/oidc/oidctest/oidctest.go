@@ -0,0 +1,272 @@
+// Package oidctest provides a minimal in-process OpenID Connect identity provider
+// for tests and local development.
+//
+// The mock implements discovery, an authorization endpoint that immediately
+// approves the request and redirects back with a code, a token endpoint that
+// enforces the redirect URI and PKCE verifier, and a JWKS endpoint. ID tokens
+// are signed with a freshly generated RS256 key.
+//
+// USAGE IN TESTS:
+//
+//	idp := oidctest.NewServer("test-client", "test-secret")
+//	defer idp.Close()
+//	idp.SetIdentity(oidctest.Identity{Subject: "42", Email: "jane@example.com", EmailVerified: true})
+//
+//	cfg := config.OIDCProviderConfig{Name: "mock", IssuerURL: idp.URL, ClientID: "test-client", ClientSecret: "test-secret"}
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Identity is the user the mock provider signs in as
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type authRequest struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	identity      Identity
+}
+
+// Provider is the mock identity provider handler
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu       sync.Mutex
+	identity Identity
+	codes    map[string]authRequest
+}
+
+// Server is a mock provider listening on a local httptest server
+type Server struct {
+	*Provider
+	*httptest.Server
+}
+
+// NewProvider creates a mock provider for the given issuer URL.
+// Mount its Handler at the root of that URL.
+func NewProvider(issuer, clientID, clientSecret string) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return &Provider{
+		Issuer:       strings.TrimRight(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		identity:     Identity{Subject: "mock-user-1", Email: "mock.user@example.com", EmailVerified: true, Name: "Mock User"},
+		codes:        map[string]authRequest{},
+	}
+}
+
+// NewServer starts a mock provider on a random local port
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{}
+	var p *Provider
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.Handler().ServeHTTP(w, r)
+	}))
+	p = NewProvider(s.Server.URL, clientID, clientSecret)
+	s.Provider = p
+	return s
+}
+
+// SetIdentity changes the user returned by subsequent logins
+func (p *Provider) SetIdentity(id Identity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.identity = id
+}
+
+// Handler returns the provider's HTTP handler
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	return mux
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// handleAuthorize approves every valid request and redirects back with a code
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	if q.Get("client_id") != p.ClientID || redirectURI == "" {
+		http.Error(w, "invalid client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "code flow with S256 PKCE required", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = authRequest{
+		clientID:      p.ClientID,
+		redirectURI:   redirectURI,
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		identity:      p.identity,
+	}
+	p.mu.Unlock()
+
+	back, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	bq := back.Query()
+	bq.Set("code", code)
+	bq.Set("state", q.Get("state"))
+	back.RawQuery = bq.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if user, pass, ok := r.BasicAuth(); ok {
+		user, _ = url.QueryUnescape(user)
+		pass, _ = url.QueryUnescape(pass)
+		if pass != p.ClientSecret {
+			tokenError(w, "invalid_client")
+			return
+		}
+		clientID = user
+	} else if p.ClientSecret != "" {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	req, ok := p.codes[code]
+	delete(p.codes, code) // codes are single-use
+	p.mu.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || clientID != req.clientID || r.PostForm.Get("redirect_uri") != req.redirectURI {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != req.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	idToken, err := p.IssueIDToken(req.identity, req.nonce, p.ClientID, time.Now())
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// IssueIDToken signs an ID token for the identity. Exposed so tests can craft
+// tokens with a wrong nonce or audience.
+func (p *Provider) IssueIDToken(id Identity, nonce, audience string, issuedAt time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "mock"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":            p.Issuer,
+		"sub":            id.Subject,
+		"aud":            audience,
+		"exp":            issuedAt.Add(5 * time.Minute).Unix(),
+		"iat":            issuedAt.Unix(),
+		"nonce":          nonce,
+		"email":          id.Email,
+		"email_verified": id.EmailVerified,
+		"name":           id.Name,
+	})
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,116 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RangeFunc returns the SHA-1 hash suffixes (35 uppercase hex characters) of
+// breached passwords whose hash starts with the given 5-character prefix.
+//
+// This is the k-anonymity range model used by Have I Been Pwned: only the
+// prefix ever leaves the caller, so a remote implementation (e.g. one calling
+// https://api.pwnedpasswords.com/range/{prefix}) can be plugged in with
+// SetRangeFunc without disclosing the password or its full hash.
+type RangeFunc func(prefix string) ([]string, error)
+
+var (
+	rangeMu sync.RWMutex
+	rangeFn RangeFunc
+)
+
+// SetRangeFunc replaces the breached password lookup. nil disables the check.
+func SetRangeFunc(fn RangeFunc) {
+	rangeMu.Lock()
+	defer rangeMu.Unlock()
+	rangeFn = fn
+}
+
+// IsBreached reports whether the password is in the breached password list.
+// Lookup errors are treated as "not breached" so an unavailable list never
+// blocks sign-ups.
+func IsBreached(password string) bool {
+	rangeMu.RLock()
+	fn := rangeFn
+	rangeMu.RUnlock()
+	if fn == nil {
+		return false
+	}
+
+	sum := sha1.Sum([]byte(password))
+	full := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := full[:5], full[5:]
+
+	suffixes, err := fn(prefix)
+	if err != nil {
+		return false
+	}
+	for _, s := range suffixes {
+		if strings.EqualFold(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// BreachedList is an in-memory breached password list indexed by hash prefix
+type BreachedList struct {
+	ranges map[string][]string
+}
+
+// LoadBreachedList reads a file of SHA-1 password hashes, one per line, in the
+// format of the Have I Been Pwned downloads ("<40 hex chars>" optionally followed
+// by ":<count>"). Blank lines and lines starting with # are ignored.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &BreachedList{ranges: map[string][]string{}}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		if len(hash) != 40 {
+			return nil, fmt.Errorf("%s:%d: expected a 40 character SHA-1 hash", path, line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid hex: %w", path, line, err)
+		}
+
+		hash = strings.ToUpper(hash)
+		list.ranges[hash[:5]] = append(list.ranges[hash[:5]], hash[5:])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Range implements RangeFunc
+func (l *BreachedList) Range(prefix string) ([]string, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
+
+// Len returns the number of hashes in the list
+func (l *BreachedList) Len() int {
+	n := 0
+	for _, r := range l.ranges {
+		n += len(r)
+	}
+	return n
+}
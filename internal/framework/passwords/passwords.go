@@ -0,0 +1,118 @@
+// Package passwords implements password hashing and the password policy.
+//
+// It enforces the configurable rules in security.password_policy (length,
+// character classes, no name/email substrings), rejects passwords found in a
+// breached/common password list, blocks reuse of recent passwords, and hashes
+// with bcrypt at the configured cost. Hashes made with a different cost are
+// reported by NeedsRehash so callers can upgrade them transparently on login.
+//
+// USAGE:
+//
+//	passwords.Configure(cfg.Security)
+//
+//	if err := passwords.Validate(pw, email, name); err != nil {
+//	    // reject with err.Error()
+//	}
+//	hash, err := passwords.Hash(pw)
+//
+//	if err := passwords.Compare(hash, pw); err == nil && passwords.NeedsRehash(hash) {
+//	    newHash, _ := passwords.Hash(pw)
+//	    // store newHash
+//	}
+//
+// Until Configure is called, the policy only checks a length of 8 to 72 and
+// hashes use bcrypt.DefaultCost.
+package passwords
+
+import (
+	"log"
+	"sync"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	mu     sync.RWMutex
+	policy = config.PasswordPolicyConfig{MinLength: 8, MaxLength: 72}
+	cost   = bcrypt.DefaultCost
+)
+
+// Configure sets the policy and hashing cost and loads the breached password
+// list. Call it once at startup. A missing list file is logged and skipped.
+func Configure(cfg config.SecurityConfig) {
+	mu.Lock()
+	policy = cfg.PasswordPolicy
+	cost = cfg.BcryptCost
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	mu.Unlock()
+
+	if cfg.PasswordPolicy.BreachedListPath == "" {
+		SetRangeFunc(nil)
+		return
+	}
+	list, err := LoadBreachedList(cfg.PasswordPolicy.BreachedListPath)
+	if err != nil {
+		log.Printf("passwords: breached password list not loaded: %v", err)
+		SetRangeFunc(nil)
+		return
+	}
+	SetRangeFunc(list.Range)
+}
+
+func currentPolicy() config.PasswordPolicyConfig {
+	mu.RLock()
+	defer mu.RUnlock()
+	return policy
+}
+
+func currentCost() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return cost
+}
+
+// HistorySize returns how many previous passwords are checked for reuse
+func HistorySize() int {
+	return currentPolicy().HistorySize
+}
+
+// Hash hashes a password with the configured bcrypt cost
+func Hash(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), currentCost())
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
+
+// Compare checks a password against a hash. An empty hash (accounts that
+// only sign in through an external provider) never matches.
+func Compare(hash, password string) error {
+	if hash == "" {
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+// NeedsRehash reports whether a hash was made with a cost other than the configured one
+func NeedsRehash(hash string) bool {
+	c, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false
+	}
+	return c != currentCost()
+}
+
+// InHistory reports whether the password matches any of the given previous hashes
+func InHistory(password string, hashes []string) bool {
+	for _, h := range hashes {
+		if Compare(h, password) == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package passwords
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testConfig() config.SecurityConfig {
+	return config.SecurityConfig{
+		BcryptCost: bcrypt.MinCost,
+		PasswordPolicy: config.PasswordPolicyConfig{
+			MinLength:            8,
+			MaxLength:            72,
+			RequireUppercase:     true,
+			RequireLowercase:     true,
+			RequireDigit:         true,
+			DisallowPersonalInfo: true,
+			HistorySize:          3,
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	Configure(testConfig())
+	t.Cleanup(func() { Configure(config.SecurityConfig{}) })
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"valid", "Tr0ubadour-Horse", false},
+		{"too short", "Ab1", true},
+		{"too long", "Aa1" + string(make([]byte, 80)), true},
+		{"no uppercase", "lowercase123", true},
+		{"no lowercase", "UPPERCASE123", true},
+		{"no digit", "NoDigitsHere", true},
+		{"contains name", "Jane2024Rocks", true},
+		{"contains email local part", "Xjdoe99Secure", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.password, "jdoe@example.com", "Jane Doe")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) error = %v, wantErr %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	// SHA-1 of "Password1", once in HIBP format with a count
+	content := "# test list\n70CCD9007338D6D81DD3B6271621B9CF9A97EA00:111658\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.PasswordPolicy.BreachedListPath = path
+	Configure(cfg)
+	t.Cleanup(func() { Configure(config.SecurityConfig{}) })
+
+	if !IsBreached("Password1") {
+		t.Error("Expected Password1 to be reported as breached")
+	}
+	if IsBreached("Tr0ubadour-Horse") {
+		t.Error("Expected an unlisted password not to be reported as breached")
+	}
+	if err := Validate("Password1", "a@example.com", "Al"); err != ErrBreached {
+		t.Errorf("Expected ErrBreached, got %v", err)
+	}
+}
+
+func TestRehashAndHistory(t *testing.T) {
+	Configure(testConfig())
+	t.Cleanup(func() { Configure(config.SecurityConfig{}) })
+
+	hash, err := Hash("Tr0ubadour-Horse")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if err := Compare(hash, "Tr0ubadour-Horse"); err != nil {
+		t.Errorf("Compare failed: %v", err)
+	}
+	if NeedsRehash(hash) {
+		t.Error("Expected hash with the configured cost not to need a rehash")
+	}
+
+	oldHash, _ := bcrypt.GenerateFromPassword([]byte("Tr0ubadour-Horse"), bcrypt.MinCost+1)
+	if !NeedsRehash(string(oldHash)) {
+		t.Error("Expected hash with a different cost to need a rehash")
+	}
+
+	if !InHistory("Tr0ubadour-Horse", []string{"", string(oldHash)}) {
+		t.Error("Expected password to be found in history")
+	}
+	if InHistory("Another-Pass1", []string{hash}) {
+		t.Error("Expected different password not to be found in history")
+	}
+	if Compare("", "anything") == nil {
+		t.Error("Expected empty hash never to match")
+	}
+}
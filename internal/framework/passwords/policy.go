@@ -0,0 +1,82 @@
+package passwords
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrBreached     = errors.New("password appears in a list of breached or common passwords, choose another one")
+	ErrPersonalInfo = errors.New("password must not contain your name or email")
+	ErrReused       = errors.New("password was used recently, choose a different one")
+)
+
+// Validate checks a new password against the policy. email and name are the
+// account's details, used to reject passwords built from them.
+func Validate(password, email, name string) error {
+	p := currentPolicy()
+
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return fmt.Errorf("password must not exceed %d characters", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		return errors.New("password must contain an uppercase letter")
+	}
+	if p.RequireLowercase && !lower {
+		return errors.New("password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		return errors.New("password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		return errors.New("password must contain a symbol")
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(password, email, name) {
+		return ErrPersonalInfo
+	}
+
+	if IsBreached(password) {
+		return ErrBreached
+	}
+
+	return nil
+}
+
+// containsPersonalInfo reports whether the password contains the email's local
+// part or any part of the name. Parts shorter than 3 characters are ignored.
+func containsPersonalInfo(password, email, name string) bool {
+	pw := strings.ToLower(password)
+
+	parts := strings.Fields(strings.ToLower(name))
+	if local, _, ok := strings.Cut(strings.ToLower(email), "@"); ok {
+		parts = append(parts, local)
+	}
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(pw, part) {
+			return true
+		}
+	}
+	return false
+}
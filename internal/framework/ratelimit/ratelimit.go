@@ -0,0 +1,335 @@
+// Package ratelimit limits how often a client may call the application.
+//
+// Limits are named policies from config.json (rate_limit.policies), attached to
+// routes with router.RateLimit("name"); routes without one use the "default"
+// policy (requests_per_minute per IP unless "default" is configured). Each
+// policy picks an algorithm and what is counted:
+//
+//   - sliding_window: at most Limit requests in any Window (approximated from
+//     the current and previous fixed windows, so it needs two counters per key)
+//   - token_bucket: a bucket of Limit tokens refilled over Window, which allows
+//     short bursts while keeping the same average rate
+//   - key "ip", "user" or "api_key": anonymous requests always fall back to the
+//     client IP, and API key requests without a key fall back to the user
+//
+// Counters live in a Store. MemoryStore keeps them per instance; DBStore keeps
+// them in the application database so every instance shares them. Other backends
+// (Redis, ...) implement Store by running Apply atomically on their side.
+//
+// USAGE:
+//
+//	limiter, err := ratelimit.FromConfig(cfg.RateLimit)
+//	r.Use(middleware.RateLimit(limiter)) // after JWTAuthMiddleware
+//
+//	r.Handle("POST", "/login", handleLogin, router.Public(), router.RateLimit("auth"))
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+)
+
+// Algorithm is how a policy counts requests
+type Algorithm string
+
+const (
+	SlidingWindow Algorithm = "sliding_window"
+	TokenBucket   Algorithm = "token_bucket"
+)
+
+// Key is what a policy counts requests per
+type Key string
+
+const (
+	KeyIP     Key = "ip"
+	KeyUser   Key = "user"
+	KeyAPIKey Key = "api_key"
+)
+
+// DefaultPolicy is used by routes that don't declare a policy
+const DefaultPolicy = "default"
+
+// Rule is the limit enforced for one key
+type Rule struct {
+	Algorithm Algorithm
+	Limit     int // 0 = unlimited
+	Window    time.Duration
+}
+
+// ttl is how long an untouched counter takes to return to its initial state
+func (r Rule) ttl() time.Duration {
+	return 2 * r.Window
+}
+
+// Policy is a named rule and what it is counted per
+type Policy struct {
+	Name string
+	Rule
+	Key Key
+}
+
+// State is the stored counter of one key.
+//
+// Sliding window: Tokens is the number of requests in the current window,
+// Previous the number in the one before, and Stamp the start of the current window.
+// Token bucket: Tokens is the tokens left and Stamp the time of the last refill.
+type State struct {
+	Tokens   float64
+	Previous float64
+	Stamp    time.Time
+}
+
+// Result is the outcome of one request against a policy
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the quota is fully available again
+	RetryAfter time.Duration // until the next request would be allowed (when !Allowed)
+	Policy     Policy
+}
+
+// Store keeps counters. Take must apply one request to key atomically, across
+// every instance sharing the store.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error)
+}
+
+// Apply counts one request against s under rule at now, updating s. Stores call
+// it inside whatever atomic section their backend provides.
+func Apply(rule Rule, s *State, now time.Time) Result {
+	if rule.Limit <= 0 || rule.Window <= 0 {
+		return Result{Allowed: true}
+	}
+	if rule.Algorithm == TokenBucket {
+		return applyTokenBucket(rule, s, now)
+	}
+	return applySlidingWindow(rule, s, now)
+}
+
+func applySlidingWindow(rule Rule, s *State, now time.Time) Result {
+	limit := float64(rule.Limit)
+	windowStart := now.Truncate(rule.Window)
+	if !s.Stamp.Equal(windowStart) {
+		if s.Stamp.Equal(windowStart.Add(-rule.Window)) {
+			s.Previous = s.Tokens
+		} else {
+			s.Previous = 0
+		}
+		s.Tokens = 0
+		s.Stamp = windowStart
+	}
+
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(rule.Window)
+	used := s.Previous*weight + s.Tokens
+	res := Result{Limit: rule.Limit, Reset: rule.Window - elapsed}
+
+	if used+1 <= limit {
+		s.Tokens++
+		res.Allowed = true
+		res.Remaining = int(math.Floor(limit - used - 1))
+		return res
+	}
+
+	// Wait until the previous window's share has decayed enough, or, when the
+	// current window alone is full, into the next window
+	if s.Tokens+1 <= limit && s.Previous > 0 {
+		decayed := 1 - (limit-s.Tokens-1)/s.Previous
+		res.RetryAfter = time.Duration(decayed*float64(rule.Window)) - elapsed
+	} else {
+		res.RetryAfter = rule.Window - elapsed
+		if s.Tokens > 0 && limit-1 < s.Tokens {
+			res.RetryAfter += time.Duration((1 - (limit-1)/s.Tokens) * float64(rule.Window))
+		}
+	}
+	res.RetryAfter = res.RetryAfter.Round(time.Millisecond) // float noise
+	res.Reset = max(res.Reset, res.RetryAfter)
+	return res
+}
+
+func applyTokenBucket(rule Rule, s *State, now time.Time) Result {
+	limit := float64(rule.Limit)
+	perToken := rule.Window / time.Duration(rule.Limit)
+
+	if s.Stamp.IsZero() {
+		s.Tokens = limit
+	} else if elapsed := now.Sub(s.Stamp); elapsed > 0 {
+		s.Tokens = math.Min(limit, s.Tokens+float64(elapsed)/float64(perToken))
+	}
+	s.Stamp = now
+
+	res := Result{Limit: rule.Limit}
+	if s.Tokens >= 1 {
+		s.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - s.Tokens) * float64(perToken))
+	}
+	res.Remaining = int(math.Floor(s.Tokens))
+	res.Reset = time.Duration((limit - s.Tokens) * float64(perToken))
+	return res
+}
+
+// Identity is who a request is counted as
+type Identity struct {
+	IP     string
+	User   string // "" when anonymous
+	APIKey string // "" unless authenticated with an API key
+}
+
+// key returns the counter key of id under policy p
+func (p Policy) key(id Identity) string {
+	switch {
+	case p.Key == KeyAPIKey && id.APIKey != "":
+		return p.Name + "|key:" + id.APIKey
+	case (p.Key == KeyAPIKey || p.Key == KeyUser) && id.User != "":
+		return p.Name + "|user:" + id.User
+	default:
+		return p.Name + "|ip:" + id.IP
+	}
+}
+
+// Limiter applies named policies using a Store
+type Limiter struct {
+	store Store
+
+	mu       sync.RWMutex
+	policies map[string]Policy
+}
+
+// New creates a limiter. The "default" policy must be among policies.
+func New(store Store, policies ...Policy) *Limiter {
+	l := &Limiter{store: store}
+	l.setPolicies(policies)
+	return l
+}
+
+func (l *Limiter) setPolicies(policies []Policy) {
+	byName := make(map[string]Policy, len(policies))
+	for _, p := range policies {
+		byName[p.Name] = p
+	}
+	l.mu.Lock()
+	l.policies = byName
+	l.mu.Unlock()
+}
+
+// FromConfig builds a limiter from the rate_limit section of config.json
+func FromConfig(cfg config.RateLimitConfig) (*Limiter, error) {
+	store, err := NewStore(cfg.Store)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := policiesFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return New(store, policies...), nil
+}
+
+// Update replaces the policies with those of cfg when config.json is
+// reloaded. The store and its counters are kept; changing rate_limit.store
+// needs a restart.
+func (l *Limiter) Update(cfg config.RateLimitConfig) error {
+	policies, err := policiesFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	l.setPolicies(policies)
+	return nil
+}
+
+func policiesFromConfig(cfg config.RateLimitConfig) ([]Policy, error) {
+	policies := []Policy{{
+		Name: DefaultPolicy,
+		Rule: Rule{Algorithm: SlidingWindow, Limit: cfg.RequestsPerMinute, Window: time.Minute},
+		Key:  KeyIP,
+	}}
+	for name, pc := range cfg.Policies {
+		p, err := policyFromConfig(name, pc)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+func policyFromConfig(name string, pc config.RateLimitPolicyConfig) (Policy, error) {
+	p := Policy{
+		Name: name,
+		Rule: Rule{Algorithm: Algorithm(pc.Algorithm), Limit: pc.Limit, Window: time.Duration(pc.WindowSeconds) * time.Second},
+		Key:  Key(pc.Key),
+	}
+	if p.Algorithm == "" {
+		p.Algorithm = SlidingWindow
+	}
+	if p.Key == "" {
+		p.Key = KeyIP
+	}
+
+	switch {
+	case p.Algorithm != SlidingWindow && p.Algorithm != TokenBucket:
+		return p, fmt.Errorf("rate limit policy %q: unknown algorithm %q", name, pc.Algorithm)
+	case p.Key != KeyIP && p.Key != KeyUser && p.Key != KeyAPIKey:
+		return p, fmt.Errorf("rate limit policy %q: unknown key %q", name, pc.Key)
+	case p.Limit > 0 && p.Window <= 0:
+		return p, fmt.Errorf("rate limit policy %q: window_seconds must be positive", name)
+	}
+	return p, nil
+}
+
+// Policy returns a configured policy by name
+func (l *Limiter) Policy(name string) (Policy, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	p, ok := l.policies[name]
+	return p, ok
+}
+
+// Take counts one request by id against the named policy ("" = default).
+// Unknown policy names are an error (rather than silently falling back to the
+// default) so a typo in router.RateLimit shows up in the logs.
+func (l *Limiter) Take(ctx context.Context, name string, id Identity) (Result, error) {
+	if name == "" {
+		name = DefaultPolicy
+	}
+	p, ok := l.Policy(name)
+	if !ok {
+		return Result{}, fmt.Errorf("unknown rate limit policy %q", name)
+	}
+	if p.Limit <= 0 {
+		return Result{Allowed: true, Policy: p}, nil
+	}
+
+	res, err := l.store.Take(ctx, p.key(id), p.Rule, time.Now())
+	res.Policy = p
+	return res, err
+}
+
+// SetHeaders reports the quota with the RateLimit-* headers (IETF httpapi
+// draft) and, when the request was rejected, Retry-After
+func SetHeaders(h http.Header, res Result) {
+	if res.Limit <= 0 {
+		return
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(max(res.Remaining, 0)))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit, ceilSeconds(res.Policy.Window)))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
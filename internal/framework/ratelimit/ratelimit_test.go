@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/config"
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	rule := Rule{Algorithm: SlidingWindow, Limit: 10, Window: time.Minute}
+	base := time.Unix(1_700_000_040, 0).Truncate(time.Minute)
+	var s State
+
+	for i := range 10 {
+		if res := Apply(rule, &s, base.Add(time.Duration(i)*time.Second)); !res.Allowed || res.Remaining != 9-i {
+			t.Fatalf("request %d: got %+v", i+1, res)
+		}
+	}
+	res := Apply(rule, &s, base.Add(30*time.Second))
+	if res.Allowed {
+		t.Fatal("Expected the 11th request in the window to be rejected")
+	}
+	if res.RetryAfter != 30*time.Second+6*time.Second {
+		t.Errorf("RetryAfter = %v, want 36s", res.RetryAfter)
+	}
+	if res.Reset < res.RetryAfter {
+		t.Errorf("Reset %v is earlier than RetryAfter %v", res.Reset, res.RetryAfter)
+	}
+
+	// Halfway through the next window half of the previous window still counts
+	if res := Apply(rule, &s, base.Add(90*time.Second)); !res.Allowed || res.Remaining != 4 {
+		t.Errorf("Half-decayed window: got %+v", res)
+	}
+
+	// Two windows later nothing counts
+	if res := Apply(rule, &s, base.Add(3*time.Minute)); !res.Allowed || res.Remaining != 9 {
+		t.Errorf("Expired window: got %+v", res)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	rule := Rule{Algorithm: TokenBucket, Limit: 5, Window: 10 * time.Second}
+	base := time.Unix(1_700_000_000, 0)
+	var s State
+
+	// The full bucket allows a burst
+	for i := range 5 {
+		if res := Apply(rule, &s, base); !res.Allowed {
+			t.Fatalf("burst request %d rejected", i+1)
+		}
+	}
+	res := Apply(rule, &s, base)
+	if res.Allowed || res.RetryAfter != 2*time.Second {
+		t.Fatalf("Empty bucket: got %+v, want rejected with 2s RetryAfter", res)
+	}
+
+	// One token every 2s
+	if res := Apply(rule, &s, base.Add(2*time.Second)); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Refilled token: got %+v", res)
+	}
+	if res := Apply(rule, &s, base.Add(time.Hour)); !res.Allowed || res.Remaining != 4 {
+		t.Errorf("Bucket should cap at its limit: got %+v", res)
+	}
+}
+
+func TestPolicyKey(t *testing.T) {
+	anon := Identity{IP: "10.0.0.1"}
+	user := Identity{IP: "10.0.0.1", User: "42"}
+	key := Identity{IP: "10.0.0.1", User: "42", APIKey: "7"}
+
+	tests := []struct {
+		key  Key
+		id   Identity
+		want string
+	}{
+		{KeyIP, key, "p|ip:10.0.0.1"},
+		{KeyUser, anon, "p|ip:10.0.0.1"},
+		{KeyUser, key, "p|user:42"},
+		{KeyAPIKey, user, "p|user:42"},
+		{KeyAPIKey, key, "p|key:7"},
+	}
+	for _, tt := range tests {
+		if got := (Policy{Name: "p", Key: tt.key}).key(tt.id); got != tt.want {
+			t.Errorf("%s key of %+v = %q, want %q", tt.key, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	limiter, err := FromConfig(config.RateLimitConfig{
+		RequestsPerMinute: 100,
+		Policies: map[string]config.RateLimitPolicyConfig{
+			"auth": {Limit: 2, WindowSeconds: 60},
+			"open": {Limit: 0},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id := Identity{IP: "10.0.0.1"}
+
+	limiter.Take(ctx, "auth", id)
+	limiter.Take(ctx, "auth", id)
+	res, err := limiter.Take(ctx, "auth", id)
+	if err != nil || res.Allowed {
+		t.Fatalf("Expected the auth policy to reject the third request, got %+v, %v", res, err)
+	}
+
+	// Policies count separately
+	if res, _ := limiter.Take(ctx, "", id); !res.Allowed || res.Limit != 100 {
+		t.Errorf("Default policy: got %+v", res)
+	}
+	if res, _ := limiter.Take(ctx, "open", id); !res.Allowed {
+		t.Error("Expected a zero limit to be unlimited")
+	}
+	if _, err := limiter.Take(ctx, "typo", id); err == nil {
+		t.Error("Expected an unknown policy to be an error")
+	}
+
+	h := http.Header{}
+	SetHeaders(h, res)
+	if h.Get("RateLimit-Limit") != "2" || h.Get("RateLimit-Remaining") != "0" || h.Get("RateLimit-Policy") != "2;w=60" || h.Get("Retry-After") == "" {
+		t.Errorf("Unexpected headers: %v", h)
+	}
+
+	if _, err := FromConfig(config.RateLimitConfig{Policies: map[string]config.RateLimitPolicyConfig{"x": {Algorithm: "leaky"}}}); err == nil {
+		t.Error("Expected an unknown algorithm to be rejected")
+	}
+}
+
+func TestDBStoreIsShared(t *testing.T) {
+	err := db.Init(config.DatabaseConfig{
+		Driver:       "sqlite3",
+		DSN:          filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Two stores stand in for two instances of the application
+	a, b := NewDBStore(), NewDBStore()
+	rule := Rule{Algorithm: TokenBucket, Limit: 3, Window: time.Minute}
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+
+	for i, store := range []Store{a, b, a} {
+		if res, err := store.Take(ctx, "k", rule, now); err != nil || !res.Allowed {
+			t.Fatalf("request %d: got %+v, %v", i+1, res, err)
+		}
+	}
+	if res, err := b.Take(ctx, "k", rule, now); err != nil || res.Allowed {
+		t.Errorf("Expected the shared bucket to be empty, got %+v, %v", res, err)
+	}
+}
+
+func TestLimiterUpdate(t *testing.T) {
+	limiter, _ := FromConfig(config.RateLimitConfig{Policies: map[string]config.RateLimitPolicyConfig{
+		"auth": {Limit: 1, WindowSeconds: 60},
+	}})
+	ctx := context.Background()
+	id := Identity{IP: "10.0.0.1"}
+
+	limiter.Take(ctx, "auth", id)
+	if res, _ := limiter.Take(ctx, "auth", id); res.Allowed {
+		t.Fatal("Expected the second request to be rejected")
+	}
+
+	// Raising the limit applies to the counters already kept by the store
+	if err := limiter.Update(config.RateLimitConfig{Policies: map[string]config.RateLimitPolicyConfig{
+		"auth": {Limit: 3, WindowSeconds: 60},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := limiter.Take(ctx, "auth", id); !res.Allowed || res.Limit != 3 {
+		t.Errorf("After the update: got %+v", res)
+	}
+
+	if err := limiter.Update(config.RateLimitConfig{Policies: map[string]config.RateLimitPolicyConfig{
+		"auth": {Algorithm: "leaky"},
+	}}); err == nil {
+		t.Error("Expected an invalid policy to be rejected")
+	}
+	if p, _ := limiter.Policy("auth"); p.Limit != 3 {
+		t.Errorf("A rejected update changed the policy: %+v", p)
+	}
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/db"
+)
+
+// NewStore returns the store named by rate_limit.store in config.json
+func NewStore(name string) (Store, error) {
+	switch name {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "database":
+		return NewDBStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q (use \"memory\" or \"database\")", name)
+	}
+}
+
+// sweepInterval is how often stores drop counters that have expired
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	state     State
+	expiresAt time.Time
+}
+
+// MemoryStore keeps counters in process memory. Limits are per instance, so
+// use DBStore when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// Take implements Store
+func (m *MemoryStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for k, e := range m.entries {
+			if now.After(e.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	e, ok := m.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		m.entries[key] = e
+	}
+	res := Apply(rule, &e.state, now)
+	e.expiresAt = now.Add(rule.ttl())
+	return res, nil
+}
+
+// dbStoreAttempts bounds the optimistic retries of a contended key
+const dbStoreAttempts = 5
+
+// DBStore keeps counters in the rate_limits table, so every instance using the
+// same database shares them. Updates use optimistic locking on a version column.
+type DBStore struct {
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewDBStore creates a store backed by db.DB
+func NewDBStore() *DBStore {
+	return &DBStore{}
+}
+
+// Take implements Store
+func (d *DBStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	d.sweep(ctx, now)
+
+	for range dbStoreAttempts {
+		row, err := db.GetRateLimitState(ctx, key)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return Result{}, err
+		}
+
+		state := State{Tokens: row.Tokens, Previous: row.Previous, Stamp: row.Stamp}
+		res := Apply(rule, &state, now)
+		next := db.RateLimitState{
+			Key:       key,
+			Tokens:    state.Tokens,
+			Previous:  state.Previous,
+			Stamp:     state.Stamp,
+			Version:   row.Version,
+			ExpiresAt: now.Add(rule.ttl()),
+		}
+
+		var saved bool
+		if errors.Is(err, db.ErrNotFound) {
+			saved, err = db.InsertRateLimitState(ctx, next)
+		} else {
+			saved, err = db.UpdateRateLimitState(ctx, next)
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		if saved {
+			return res, nil
+		}
+	}
+	return Result{}, fmt.Errorf("rate limit key %q: too much contention", key)
+}
+
+// sweep deletes expired counters at most once per sweepInterval per instance
+func (d *DBStore) sweep(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	due := now.Sub(d.lastSweep) >= sweepInterval
+	if due {
+		d.lastSweep = now
+	}
+	d.mu.Unlock()
+
+	if due {
+		_ = db.DeleteExpiredRateLimits(ctx, now)
+	}
+}
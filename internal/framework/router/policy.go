@@ -0,0 +1,214 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/httperr"
+)
+
+// Access is the level of authentication a route requires
+type Access int
+
+const (
+	// AccessAuthenticated routes require a valid JWT or API key. This is the
+	// default for every route that does not declare otherwise.
+	AccessAuthenticated Access = iota
+	// AccessPublic routes can be called without credentials
+	AccessPublic
+)
+
+// String returns the access level as shown by `go-bastion routes`
+func (a Access) String() string {
+	if a == AccessPublic {
+		return "public"
+	}
+	return "authenticated"
+}
+
+// Policy is the protection a route declares at registration.
+//
+// Authentication (Access), CSRF (CSRFExempt) and rate limits (RateLimit) are
+// enforced by JWTAuthMiddleware, CSRFMiddleware and RateLimit, which read the matched route's policy
+// from the request context. Roles and Permissions are enforced by the router
+// itself, right before the handler runs, so a route that requires a role is
+// refused even if no authentication middleware is installed.
+type Policy struct {
+	Access      Access
+	Roles       []string // any one of these roles is enough ("admin" passes every check)
+	Permissions []string // every one of these permissions is required
+	CSRFExempt  bool     // skip CSRF validation (the handler or the credential protects itself)
+	RateLimit   string   // rate limit policy from config.json ("" = "default")
+	CORS        string   // CORS policy from config.json ("" = "default")
+	Unlocalized bool     // the response is the same in every locale (no Vary: Accept-Language)
+}
+
+// String summarizes the policy, e.g. "authenticated role=admin csrf-exempt"
+func (p Policy) String() string {
+	parts := []string{p.Access.String()}
+	if len(p.Roles) > 0 {
+		parts = append(parts, "role="+strings.Join(p.Roles, ","))
+	}
+	if len(p.Permissions) > 0 {
+		parts = append(parts, "perm="+strings.Join(p.Permissions, ","))
+	}
+	if p.CSRFExempt {
+		parts = append(parts, "csrf-exempt")
+	}
+	if p.RateLimit != "" {
+		parts = append(parts, "ratelimit="+p.RateLimit)
+	}
+	if p.CORS != "" {
+		parts = append(parts, "cors="+p.CORS)
+	}
+	if p.Unlocalized {
+		parts = append(parts, "unlocalized")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Option declares part of a route's Policy when registering it:
+//
+//	r.Handle("GET", "/login", handleLoginPage, router.Public())
+//	r.Handle("GET", "/admin", handleDashboard, router.RequireRole("admin"))
+type Option func(*Policy)
+
+// Public marks a route as callable without credentials
+func Public() Option {
+	return func(p *Policy) { p.Access = AccessPublic }
+}
+
+// Authenticated marks a route as requiring credentials. It is the default and
+// only needed to make the intent explicit.
+func Authenticated() Option {
+	return func(p *Policy) { p.Access = AccessAuthenticated }
+}
+
+// RequireRole restricts a route to users with any of the given roles.
+// It implies authentication.
+func RequireRole(roles ...string) Option {
+	return func(p *Policy) {
+		p.Access = AccessAuthenticated
+		p.Roles = append(p.Roles, roles...)
+	}
+}
+
+// RequirePermission restricts a route to principals holding all of the given
+// permissions. It implies authentication.
+func RequirePermission(perms ...string) Option {
+	return func(p *Policy) {
+		p.Access = AccessAuthenticated
+		p.Permissions = append(p.Permissions, perms...)
+	}
+}
+
+// CSRFExempt disables CSRF validation for a route. Use it for endpoints that
+// don't rely on cookies (token endpoints) or that validate a form token themselves.
+func CSRFExempt() Option {
+	return func(p *Policy) { p.CSRFExempt = true }
+}
+
+// RateLimit applies a named rate limit policy (rate_limit.policies in
+// config.json) to a route instead of the default one
+func RateLimit(name string) Option {
+	return func(p *Policy) { p.RateLimit = name }
+}
+
+// CORS applies a named CORS policy (cors.policies in config.json) to a route
+// instead of the default one
+func CORS(name string) Option {
+	return func(p *Policy) { p.CORS = name }
+}
+
+// Unlocalized marks a route whose responses don't depend on the visitor's
+// language, like static files, so middleware.Locale leaves them alone and
+// shared caches keep one copy
+func Unlocalized() Option {
+	return func(p *Policy) { p.Unlocalized = true }
+}
+
+// Principal is the authenticated caller, as seen by the router's policy checks.
+// Authentication middleware stores it with WithPrincipal.
+type Principal struct {
+	Subject     string
+	Role        string
+	Permissions []string
+}
+
+// can reports whether the principal satisfies the roles and permissions of p
+func (pr Principal) can(p Policy) bool {
+	if pr.Role == "admin" {
+		return true
+	}
+	if len(p.Roles) > 0 {
+		ok := false
+		for _, role := range p.Roles {
+			if pr.Role == role {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, perm := range p.Permissions {
+		if !hasString(pr.Permissions, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey string
+
+const (
+	policyKey    contextKey = "route_policy"
+	principalKey contextKey = "route_principal"
+)
+
+// PolicyFromContext returns the policy of the route that matched the request
+func PolicyFromContext(ctx context.Context) (Policy, bool) {
+	p, ok := ctx.Value(policyKey).(Policy)
+	return p, ok
+}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFromContext returns the authenticated principal, if any
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
+
+// enforce wraps h with the role and permission checks of p
+func enforce(p Policy, h Handler) Handler {
+	if len(p.Roles) == 0 && len(p.Permissions) == 0 {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		pr, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			httperr.Write(w, r, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if !pr.can(p) {
+			httperr.Write(w, r, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+		h(w, r, params)
+	}
+}
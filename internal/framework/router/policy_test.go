@@ -0,0 +1,78 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicyInContext(t *testing.T) {
+	r := New()
+
+	var got Policy
+	r.Handle("POST", "/login", func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+		got, _ = PolicyFromContext(req.Context())
+	}, Public(), CSRFExempt())
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/login", nil))
+
+	if got.Access != AccessPublic || !got.CSRFExempt {
+		t.Errorf("Expected public, CSRF-exempt policy in context, got %q", got)
+	}
+
+	routes := r.Routes()
+	if len(routes) != 1 || routes[0].Policy.String() != "public csrf-exempt" {
+		t.Errorf("Unexpected route table: %+v", routes)
+	}
+}
+
+func TestRoleAndPermissionEnforcement(t *testing.T) {
+	// Stand-in for authentication middleware: the principal comes from headers
+	authenticate := func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+			if role := req.Header.Get("X-Role"); role != "" {
+				pr := Principal{Subject: "1", Role: role}
+				if perm := req.Header.Get("X-Perm"); perm != "" {
+					pr.Permissions = []string{perm}
+				}
+				req = req.WithContext(WithPrincipal(req.Context(), pr))
+			}
+			next(w, req, params)
+		}
+	}
+
+	r := New()
+	r.Use(authenticate)
+	ok := func(w http.ResponseWriter, req *http.Request, params map[string]string) {}
+	r.Handle("GET", "/admin", ok, RequireRole("admin"))
+	r.Handle("GET", "/reports", ok, RequirePermission("reports:read"))
+	r.Handle("GET", "/open", ok, Public())
+
+	tests := []struct {
+		path, role, perm string
+		want             int
+	}{
+		{"/admin", "", "", http.StatusUnauthorized},
+		{"/admin", "user", "", http.StatusForbidden},
+		{"/admin", "admin", "", http.StatusOK},
+		{"/reports", "user", "", http.StatusForbidden},
+		{"/reports", "user", "reports:read", http.StatusOK},
+		{"/reports", "admin", "", http.StatusOK},
+		{"/open", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.role != "" {
+			req.Header.Set("X-Role", tt.role)
+		}
+		if tt.perm != "" {
+			req.Header.Set("X-Perm", tt.perm)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("GET %s as %q/%q: got %d, want %d", tt.path, tt.role, tt.perm, rec.Code, tt.want)
+		}
+	}
+}
@@ -61,7 +61,8 @@
 // ROUTE PATTERNS:
 //   - Static: /users, /admin/dashboard
 //   - With params: /users/{id}, /posts/{postID}/comments/{commentID}
-//   - Wildcard: Not yet supported (would need modification)
+//   - Rest of the path: /static/{path...} matches /static/css/site.css with
+//     params["path"] = "css/site.css" (last segment only, never empty)
 //
 // PATH PARAMETER EXTRACTION:
 //
@@ -70,24 +71,27 @@
 //	    // ... handle request
 //	})
 //
-// COMMON PATTERNS:
-//
-// 1. Protecting routes with authentication:
+// ROUTE POLICIES:
+// Every route declares its protection at registration. Routes are
+// authenticated by default; options relax or tighten that:
 //
-//	authMiddleware := middleware.RequireAuth()
-//	r.Handle("GET", "/protected", authMiddleware(handleProtected))
+//	r.Handle("GET", "/login", handleLogin, router.Public())
+//	r.Handle("POST", "/api/v1/auth/login", handleAPILogin, router.Public(), router.CSRFExempt())
+//	r.Handle("GET", "/admin", handleAdmin, router.RequireRole("admin"))
+//	r.Handle("POST", "/reports", handleReport, router.RequirePermission("reports:write"))
 //
-// 2. Role-based access control:
+// JWTAuthMiddleware and CSRFMiddleware read the matched route's Policy from the
+// request context; role and permission checks are done by the router itself.
+// `go-bastion routes` prints every route with its effective policy.
 //
-//	adminOnly := middleware.RequireRole("admin")
-//	r.Handle("GET", "/admin", adminOnly(handleAdmin))
+// COMMON PATTERNS:
 //
-// 3. Route groups (convention, not built-in):
+// 1. Route groups share a path prefix, options and middlewares:
 //
 //	func registerAdminRoutes(r *router.Router) {
-//	    adminAuth := middleware.RequireRole("admin")
-//	    r.Handle("GET", "/admin/users", adminAuth(handleUsers))
-//	    r.Handle("GET", "/admin/settings", adminAuth(handleSettings))
+//	    admin := r.Group("/admin", router.RequireRole("admin"))
+//	    admin.Handle("GET", "/users", handleUsers)
+//	    admin.Handle("GET", "/settings", handleSettings)
 //	}
 //
 // For examples, see: cmd/server/main.go (route registration)
@@ -95,8 +99,11 @@
 package router
 
 import (
+	"context"
 	"net/http"
 	"strings"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/httperr"
 )
 
 // Handler is the function signature for route handlers with path parameters.
@@ -136,12 +143,19 @@ type Route struct {
 	Method  string
 	Pattern string
 	Handler Handler
+	Policy  Policy
 }
 
 // Router is a minimal HTTP router built on net/http
 type Router struct {
 	routes      []Route
 	middlewares []Middleware
+	preflight   Handler
+
+	// Set on groups (see Group)
+	root   *Router
+	prefix string
+	opts   []Option
 }
 
 // New creates a new Router instance
@@ -152,52 +166,112 @@ func New() *Router {
 	}
 }
 
-// Use registers a global middleware
+// Use registers a global middleware. On a group, the middleware only applies
+// to the group's routes.
 func (r *Router) Use(mw Middleware) {
 	r.middlewares = append(r.middlewares, mw)
 }
 
-// Handle registers a new route with the given method, pattern, and handler
-func (r *Router) Handle(method, pattern string, h Handler) {
+// Group returns a router that registers routes under prefix with opts applied
+// before each route's own options, e.g.
+//
+//	api := r.Group("/api", router.CORS("api"))
+//	api.GET("/users", handleUsers) // GET /api/users
+//
+// An empty prefix only shares options. Groups start with the middlewares
+// registered so far and add their own with Use; their routes are served by the
+// router they were created from.
+func (r *Router) Group(prefix string, opts ...Option) *Router {
+	return &Router{
+		middlewares: append([]Middleware(nil), r.middlewares...),
+		root:        r.base(),
+		prefix:      r.prefix + prefix,
+		opts:        append(append([]Option(nil), r.opts...), opts...),
+	}
+}
+
+// base is the router that holds the route table
+func (r *Router) base() *Router {
+	if r.root != nil {
+		return r.root
+	}
+	return r
+}
+
+// Preflight sets the handler for OPTIONS requests to paths that have routes
+// but no OPTIONS route of their own, i.e. CORS preflights. It runs without
+// middlewares (browsers send preflights without credentials), with the
+// policy of the route whose method the preflight asks about in the context.
+// Without one, such requests get 204 with an Allow header.
+func (r *Router) Preflight(h Handler) {
+	r.base().preflight = h
+}
+
+// Handle registers a new route with the given method, pattern, and handler.
+// Options declare the route's Policy; without any, the route requires
+// authentication and CSRF validation for unsafe methods.
+func (r *Router) Handle(method, pattern string, h Handler, opts ...Option) {
+	var policy Policy
+	for _, opt := range r.opts {
+		opt(&policy)
+	}
+	for _, opt := range opts {
+		opt(&policy)
+	}
+
+	// Role and permission checks run after all middlewares (authentication included)
+	h = enforce(policy, h)
+
 	// Apply all middlewares to the handler
 	for i := len(r.middlewares) - 1; i >= 0; i-- {
 		h = r.middlewares[i](h)
 	}
 
-	r.routes = append(r.routes, Route{
+	root := r.base()
+	root.routes = append(root.routes, Route{
 		Method:  method,
-		Pattern: pattern,
+		Pattern: r.prefix + pattern,
 		Handler: h,
+		Policy:  policy,
 	})
 }
 
 // GET is a convenience method for registering GET routes
-func (r *Router) GET(pattern string, h Handler) {
-	r.Handle("GET", pattern, h)
+func (r *Router) GET(pattern string, h Handler, opts ...Option) {
+	r.Handle("GET", pattern, h, opts...)
 }
 
 // POST is a convenience method for registering POST routes
-func (r *Router) POST(pattern string, h Handler) {
-	r.Handle("POST", pattern, h)
+func (r *Router) POST(pattern string, h Handler, opts ...Option) {
+	r.Handle("POST", pattern, h, opts...)
 }
 
 // PUT is a convenience method for registering PUT routes
-func (r *Router) PUT(pattern string, h Handler) {
-	r.Handle("PUT", pattern, h)
+func (r *Router) PUT(pattern string, h Handler, opts ...Option) {
+	r.Handle("PUT", pattern, h, opts...)
 }
 
 // DELETE is a convenience method for registering DELETE routes
-func (r *Router) DELETE(pattern string, h Handler) {
-	r.Handle("DELETE", pattern, h)
+func (r *Router) DELETE(pattern string, h Handler, opts ...Option) {
+	r.Handle("DELETE", pattern, h, opts...)
 }
 
 // PATCH is a convenience method for registering PATCH routes
-func (r *Router) PATCH(pattern string, h Handler) {
-	r.Handle("PATCH", pattern, h)
+func (r *Router) PATCH(pattern string, h Handler, opts ...Option) {
+	r.Handle("PATCH", pattern, h, opts...)
+}
+
+// Routes returns the registered routes in registration order
+func (r *Router) Routes() []Route {
+	root := r.base()
+	routes := make([]Route, len(root.routes))
+	copy(routes, root.routes)
+	return routes
 }
 
 // ServeHTTP implements http.Handler interface
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r = r.base()
 	for _, route := range r.routes {
 		if route.Method != req.Method {
 			continue
@@ -205,12 +279,48 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 		params, ok := match(route.Pattern, req.URL.Path)
 		if ok {
-			route.Handler(w, req, params)
+			// Expose the route's policy to authentication and CSRF middleware
+			ctx := context.WithValue(req.Context(), policyKey, route.Policy)
+			route.Handler(w, req.WithContext(ctx), params)
 			return
 		}
 	}
 
-	http.NotFound(w, req)
+	if req.Method == http.MethodOptions && r.serveOptions(w, req) {
+		return
+	}
+
+	httperr.Write(w, req, http.StatusNotFound, "Page not found")
+}
+
+// serveOptions answers an OPTIONS request for a path that has routes. It
+// reports false when no route matches the path.
+func (r *Router) serveOptions(w http.ResponseWriter, req *http.Request) bool {
+	var target *Route
+	var targetParams map[string]string
+	methods := []string{}
+	for i, route := range r.routes {
+		params, ok := match(route.Pattern, req.URL.Path)
+		if !ok {
+			continue
+		}
+		methods = append(methods, route.Method)
+		if target == nil || route.Method == req.Header.Get("Access-Control-Request-Method") {
+			target, targetParams = &r.routes[i], params
+		}
+	}
+	if target == nil {
+		return false
+	}
+
+	w.Header().Set("Allow", strings.Join(append(methods, http.MethodOptions), ", "))
+	if r.preflight == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+	ctx := context.WithValue(req.Context(), policyKey, target.Policy)
+	r.preflight(w, req.WithContext(ctx), targetParams)
+	return true
 }
 
 // match checks if a pattern matches a path and extracts parameters
@@ -227,6 +337,13 @@ func match(pattern, path string) (map[string]string, bool) {
 	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
 	pathParts := strings.Split(strings.Trim(path, "/"), "/")
 
+	// A trailing {name...} takes the rest of the path
+	last := patternParts[len(patternParts)-1]
+	rest := strings.HasPrefix(last, "{") && strings.HasSuffix(last, "...}")
+	if rest && len(pathParts) > len(patternParts) {
+		pathParts = append(pathParts[:len(patternParts)-1], strings.Join(pathParts[len(patternParts)-1:], "/"))
+	}
+
 	// Must have same number of parts
 	if len(patternParts) != len(pathParts) {
 		return nil, false
@@ -238,8 +355,13 @@ func match(pattern, path string) (map[string]string, bool) {
 		patternPart := patternParts[i]
 		pathPart := pathParts[i]
 
-		// Check if this is a parameter (enclosed in braces)
-		if strings.HasPrefix(patternPart, "{") && strings.HasSuffix(patternPart, "}") {
+		if rest && i == len(patternParts)-1 {
+			if pathPart == "" {
+				return nil, false
+			}
+			params[strings.TrimSuffix(strings.TrimPrefix(patternPart, "{"), "...}")] = pathPart
+		} else if strings.HasPrefix(patternPart, "{") && strings.HasSuffix(patternPart, "}") {
+			// Check if this is a parameter (enclosed in braces)
 			// Extract parameter name
 			paramName := strings.TrimPrefix(strings.TrimSuffix(patternPart, "}"), "{")
 			params[paramName] = pathPart
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	r := New()
+	var trace []string
+	r.Use(func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+			trace = append(trace, "global")
+			next(w, req, params)
+		}
+	})
+
+	api := r.Group("/api", Public(), CORS("api"))
+	api.Use(func(next Handler) Handler {
+		return func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+			trace = append(trace, "api")
+			next(w, req, params)
+		}
+	})
+	v1 := api.Group("/v1")
+
+	var got Policy
+	v1.GET("/users/{id}", func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+		got, _ = PolicyFromContext(req.Context())
+		trace = append(trace, "handler:"+params["id"])
+	}, CORS("internal"))
+	r.GET("/home", func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+		trace = append(trace, "home")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/users/7", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/home", nil))
+
+	if want := "global api handler:7 global home"; strings.Join(trace, " ") != want {
+		t.Errorf("trace = %q, want %q", strings.Join(trace, " "), want)
+	}
+	// Route options are applied after the group's
+	if got.Access != AccessPublic || got.CORS != "internal" {
+		t.Errorf("Unexpected policy %q", got)
+	}
+	if routes := r.Routes(); len(routes) != 2 || routes[0].Pattern != "/api/v1/users/{id}" {
+		t.Errorf("Unexpected route table: %+v", routes)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	r := New()
+	noop := func(w http.ResponseWriter, req *http.Request, params map[string]string) {}
+	r.GET("/items", noop)
+	r.DELETE("/items", noop, CORS("api"))
+
+	// Without a preflight handler OPTIONS lists the methods
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/items", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, DELETE, OPTIONS" {
+		t.Errorf("OPTIONS: got %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	// The preflight handler sees the policy of the route being asked about
+	var got Policy
+	r.Preflight(func(w http.ResponseWriter, req *http.Request, params map[string]string) {
+		got, _ = PolicyFromContext(req.Context())
+	})
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if got.CORS != "api" {
+		t.Errorf("Expected the DELETE route's policy, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("OPTIONS on an unknown path: expected 404, got %d", w.Code)
+	}
+}
+
+func TestMatchRest(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"/static/site.css", "site.css", true},
+		{"/static/css/a/site.css", "css/a/site.css", true},
+		{"/static", "", false},
+		{"/static/", "", false},
+		{"/other/site.css", "", false},
+	}
+	for _, tt := range tests {
+		params, ok := match("/static/{path...}", tt.path)
+		if ok != tt.ok || params["path"] != tt.want {
+			t.Errorf("match(%q) = %v, %v, want %q, %v", tt.path, params, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -8,60 +8,80 @@ import (
 	"errors"
 )
 
-// GenerateCSRFToken generates a random CSRF token
-func GenerateCSRFToken(secret string) (string, error) {
-	// Generate random bytes
-	b := make([]byte, 32)
+// CSRF tokens are derived from a random per-browser secret (kept in an HttpOnly
+// cookie) and the session they are used in:
+//
+//	token = HMAC-SHA256(jwtSecret, cookieSecret | session)
+//
+// so a token only validates for the browser and the signed-in user it was
+// rendered for, and can't be computed without the server secret. Tokens are
+// masked with a one-time pad every time they are written into a page, so the
+// bytes differ on every response (BREACH) while still validating.
+
+const csrfTokenLength = 32
+
+// GenerateCSRFSecret generates the random per-browser secret stored in the CSRF cookie
+func GenerateCSRFSecret() (string, error) {
+	b := make([]byte, csrfTokenLength)
 	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	// Create HMAC signature
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write(b)
-	signature := h.Sum(nil)
-
-	// Combine random bytes and signature
-	token := append(b, signature...)
+// ValidCSRFSecret reports whether a cookie value looks like a secret issued by GenerateCSRFSecret
+func ValidCSRFSecret(secret string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(secret)
+	return err == nil && len(b) == csrfTokenLength
+}
 
-	// Base64 URL encode
-	return base64.RawURLEncoding.EncodeToString(token), nil
+// CSRFToken returns the unmasked token for a cookie secret and session
+// (the user ID, or "" for anonymous visitors)
+func CSRFToken(key, cookieSecret, session string) []byte {
+	h := hmac.New(sha256.New, []byte("csrf:"+key))
+	h.Write([]byte(cookieSecret))
+	h.Write([]byte{0})
+	h.Write([]byte(session))
+	return h.Sum(nil)
 }
 
-// ValidateCSRFToken validates a CSRF token using double-submit cookie pattern
-func ValidateCSRFToken(secret, cookieToken, headerToken string) bool {
-	// Both tokens must be present
-	if cookieToken == "" || headerToken == "" {
-		return false
+// MaskCSRFToken returns pad || (pad XOR token), base64 encoded, with a fresh random pad
+func MaskCSRFToken(token []byte) (string, error) {
+	pad := make([]byte, len(token))
+	if _, err := rand.Read(pad); err != nil {
+		return "", err
 	}
+	masked := make([]byte, 2*len(token))
+	copy(masked, pad)
+	for i := range token {
+		masked[len(token)+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked), nil
+}
 
-	// Tokens must match (double-submit)
-	if cookieToken != headerToken {
-		return false
+// UnmaskCSRFToken reverses MaskCSRFToken
+func UnmaskCSRFToken(masked string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(masked)
+	if err != nil || len(b) != 2*sha256.Size {
+		return nil, errors.New("invalid CSRF token")
+	}
+	token := make([]byte, sha256.Size)
+	for i := range token {
+		token[i] = b[i] ^ b[sha256.Size+i]
 	}
+	return token, nil
+}
 
-	// Decode the token
-	decoded, err := base64.RawURLEncoding.DecodeString(cookieToken)
-	if err != nil {
+// ValidateCSRFToken checks a submitted (masked) token against the cookie secret and session
+func ValidateCSRFToken(key, cookieSecret, session, submitted string) bool {
+	if cookieSecret == "" || submitted == "" {
 		return false
 	}
-
-	// Must be at least 32 bytes (random) + 32 bytes (HMAC-SHA256)
-	if len(decoded) < 64 {
+	token, err := UnmaskCSRFToken(submitted)
+	if err != nil {
 		return false
 	}
-
-	// Split into random bytes and signature
-	randomBytes := decoded[:32]
-	providedSignature := decoded[32:]
-
-	// Recreate signature
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write(randomBytes)
-	expectedSignature := h.Sum(nil)
-
-	// Compare signatures
-	return hmac.Equal(expectedSignature, providedSignature)
+	return hmac.Equal(token, CSRFToken(key, cookieSecret, session))
 }
 
 // GenerateSimpleToken generates a simple random token (without HMAC)
@@ -0,0 +1,48 @@
+package security
+
+// Purpose tokens are single-purpose JWTs (MFA challenges, unlock links, ...).
+// Each purpose signs with its own key derived from the JWT secret, so a token
+// issued for one purpose can never be accepted as an access token or as a
+// token for another purpose.
+
+// generatePurposeToken signs a token for the given purpose and subject
+func generatePurposeToken(secret, purpose, sub string, ttlMinutes int) (string, error) {
+	return GenerateToken(purposeKey(secret, purpose), sub, purpose, ttlMinutes)
+}
+
+// parsePurposeToken validates a purpose token and returns its subject
+func parsePurposeToken(secret, purpose, token string) (string, error) {
+	claims, err := ParseAndValidateToken(purposeKey(secret, purpose), token)
+	if err != nil {
+		return "", err
+	}
+	if claims.Role != purpose {
+		return "", ErrInvalidToken
+	}
+	return claims.Sub, nil
+}
+
+func purposeKey(secret, purpose string) string {
+	return purpose + ":" + secret
+}
+
+// GenerateUnlockToken issues a token that unlocks a locked-out account by email
+func GenerateUnlockToken(secret, email string, ttlMinutes int) (string, error) {
+	return generatePurposeToken(secret, "account_unlock", email, ttlMinutes)
+}
+
+// ParseUnlockToken validates an unlock token and returns the account email
+func ParseUnlockToken(secret, token string) (string, error) {
+	return parsePurposeToken(secret, "account_unlock", token)
+}
+
+// GenerateOIDCStateToken signs the state of an in-flight OIDC login (state, nonce,
+// PKCE verifier) so it can be kept in a cookie between redirect and callback
+func GenerateOIDCStateToken(secret, payload string, ttlMinutes int) (string, error) {
+	return generatePurposeToken(secret, "oidc_state", payload, ttlMinutes)
+}
+
+// ParseOIDCStateToken validates an OIDC state token and returns its payload
+func ParseOIDCStateToken(secret, token string) (string, error) {
+	return parsePurposeToken(secret, "oidc_state", token)
+}
@@ -0,0 +1,103 @@
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the base32 encoding of the RFC 6238 SHA-1 test key "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestGenerateTOTPCode checks the RFC 6238 test vectors (truncated to 6 digits)
+func TestGenerateTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := GenerateTOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode() error = %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("GenerateTOTPCode(t=%d) = %q, want %q", tt.unix, code, tt.expected)
+		}
+	}
+}
+
+// TestValidateTOTP tests skew tolerance and replay protection
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	previous, _ := GenerateTOTPCode(rfc6238Secret, step-1)
+	if _, err := ValidateTOTP(rfc6238Secret, previous, now, 0); err != nil {
+		t.Errorf("ValidateTOTP() rejected code within skew: %v", err)
+	}
+
+	tooOld, _ := GenerateTOTPCode(rfc6238Secret, step-3)
+	if _, err := ValidateTOTP(rfc6238Secret, tooOld, now, 0); err != ErrInvalidTOTPCode {
+		t.Errorf("ValidateTOTP() error = %v, want %v", err, ErrInvalidTOTPCode)
+	}
+
+	current, _ := GenerateTOTPCode(rfc6238Secret, step)
+	matched, err := ValidateTOTP(rfc6238Secret, current, now, 0)
+	if err != nil || matched != step {
+		t.Fatalf("ValidateTOTP() = %d, %v; want %d, nil", matched, err, step)
+	}
+	if _, err := ValidateTOTP(rfc6238Secret, current, now, matched); err != ErrTOTPCodeReused {
+		t.Errorf("ValidateTOTP() replay error = %v, want %v", err, ErrTOTPCodeReused)
+	}
+}
+
+// TestRecoveryCodes tests recovery code generation and normalized hashing
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected recovery code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+	}
+
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))) {
+		t.Error("HashRecoveryCode() should ignore case, dashes and surrounding spaces")
+	}
+}
+
+// TestMFAChallengeIsNotAccessToken ensures challenge tokens can't authenticate requests
+func TestMFAChallengeIsNotAccessToken(t *testing.T) {
+	token, err := GenerateMFAChallenge("secret", "42", 5)
+	if err != nil {
+		t.Fatalf("GenerateMFAChallenge() error = %v", err)
+	}
+
+	sub, err := ParseMFAChallenge("secret", token)
+	if err != nil || sub != "42" {
+		t.Errorf("ParseMFAChallenge() = %q, %v; want \"42\", nil", sub, err)
+	}
+
+	if _, err := ParseAndValidateToken("secret", token); err == nil {
+		t.Error("challenge token must not validate as an access token")
+	}
+
+	access, _ := GenerateToken("secret", "42", "user", 5)
+	if _, err := ParseMFAChallenge("secret", access); err == nil {
+		t.Error("access token must not validate as a challenge token")
+	}
+}
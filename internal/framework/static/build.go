@@ -0,0 +1,71 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ManifestFile is the name of the manifest Build writes into the directory
+const ManifestFile = "manifest.json"
+
+// Build prepares the directory dir for deployment: it writes a gzip copy
+// (name.gz) of every compressible file, a brotli copy (name.br) too when the
+// brotli command is installed, as the standard library can't encode brotli,
+// and manifest.json with the fingerprinted name of every file. It returns
+// the manifest.
+func Build(dir string) (map[string]string, error) {
+	brotli, _ := exec.LookPath("brotli")
+
+	s := New(os.DirFS(dir), "")
+	manifest, err := s.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	delete(manifest, ManifestFile)
+
+	for name := range manifest {
+		f, err := s.load(name)
+		if err != nil {
+			return nil, err
+		}
+		if !Compressible(f.ctype) {
+			continue
+		}
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := writeGzip(file+".gz", f.content); err != nil {
+			return nil, err
+		}
+		if brotli != "" {
+			if out, err := exec.Command(brotli, "--force", "--keep", "--best", file).CombinedOutput(); err != nil {
+				return nil, fmt.Errorf("brotli %s: %v: %s", name, err, out)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeGzip(file string, content []byte) error {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	zw.Write(content)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,348 @@
+// Package static serves the static/ directory with fingerprinted URLs,
+// compressed responses and cache headers.
+//
+// Every file is served under its own path and under a fingerprinted one that
+// includes a hash of its content:
+//
+//	/static/css/output.css
+//	/static/css/output.3f2a1b9c0d4e.css
+//
+// Templates link to the fingerprinted URL with @asset("css/output.css"). As
+// that URL changes whenever the file does, browsers and proxies keep it for a
+// year (Cache-Control: immutable). The plain path is revalidated on every use
+// (Cache-Control: no-cache) with its ETag, and answered 304 when unchanged.
+//
+// Responses are compressed when the client accepts it: with brotli from a
+// precompressed name.br next to the file, with gzip from name.gz or, without
+// one, compressed once in memory. go-bastion assets build writes both (see
+// Build) along with a manifest of the fingerprinted names.
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/httperr"
+)
+
+// hashLen is the number of hex digits of the content hash in fingerprints
+const hashLen = 12
+
+// Server serves the files of an fs.FS under a URL prefix
+type Server struct {
+	fsys   fs.FS
+	prefix string
+	dev    atomic.Bool // Reload files that changed
+
+	mu    sync.RWMutex
+	files map[string]*file // Loaded files by name
+}
+
+// file is a loaded file with its compressed variants
+type file struct {
+	name    string
+	hash    string
+	modTime time.Time
+	size    int64
+	ctype   string
+	content []byte
+	gzip    []byte // nil when compressing doesn't pay off
+	brotli  []byte // Only from a precompressed name.br
+}
+
+// New creates a Server for the files in fsys, served under prefix, e.g.
+// "/static/". Files are read and hashed on first use.
+//
+// USAGE:
+//
+//	assets := static.New(os.DirFS("static"), "/static/")
+//	views.AddFunc("asset", assets.URL)
+//	r.GET("/static/{path...}", router.WrapHandler(assets), router.Public())
+func New(fsys fs.FS, prefix string) *Server {
+	return &Server{fsys: fsys, prefix: prefix, files: make(map[string]*file)}
+}
+
+// SetDevMode makes the server check the files it loaded on every use and
+// reload the ones that changed
+func (s *Server) SetDevMode(dev bool) {
+	s.dev.Store(dev)
+}
+
+// URL returns the fingerprinted URL of the file name, e.g.
+// "/static/css/output.3f2a1b9c0d4e.css" for "css/output.css". Files that can't
+// be read get their plain URL, so a missing file shows up as a 404 in the
+// browser rather than breaking the page.
+func (s *Server) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	f, err := s.load(name)
+	if err != nil {
+		return s.prefix + name
+	}
+	return s.prefix + Fingerprint(name, f.hash)
+}
+
+// Fingerprint inserts hash into name before its extension
+func Fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	if ext == "" || strings.Contains(ext, "/") {
+		return name + "." + hash
+	}
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// ServeHTTP serves the file named by the request path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		httperr.Write(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, s.prefix)
+	f, immutable := s.resolve(name)
+	if f == nil {
+		httperr.Write(w, r, http.StatusNotFound, "Page not found")
+		return
+	}
+
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if immutable {
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		h.Set("Cache-Control", "public, no-cache")
+	}
+	h.Set("Content-Type", f.ctype)
+
+	// Each encoding is a different representation, with its own ETag
+	content, etag := f.content, f.hash
+	accept := r.Header.Get("Accept-Encoding")
+	switch {
+	case f.brotli != nil && accepts(accept, "br"):
+		content, etag = f.brotli, f.hash+"-br"
+		h.Set("Content-Encoding", "br")
+	case f.gzip != nil && accepts(accept, "gzip"):
+		content, etag = f.gzip, f.hash+"-gzip"
+		h.Set("Content-Encoding", "gzip")
+	}
+	h.Set("ETag", strconv.Quote(etag))
+
+	// ServeContent answers If-None-Match, If-Modified-Since and ranges
+	http.ServeContent(w, r, f.name, f.modTime, bytes.NewReader(content))
+}
+
+// resolve finds the file for a request path, either its plain name or a
+// fingerprint of its current content
+func (s *Server) resolve(name string) (f *file, immutable bool) {
+	if f, err := s.load(name); err == nil {
+		return f, false
+	}
+	original, hash, ok := parseFingerprint(name)
+	if !ok {
+		return nil, false
+	}
+	// An outdated fingerprint isn't served the current content, which
+	// would then be cached as immutable under the old name
+	if f, err := s.load(original); err == nil && f.hash == hash {
+		return f, true
+	}
+	return nil, false
+}
+
+// parseFingerprint undoes Fingerprint
+func parseFingerprint(name string) (original, hash string, ok bool) {
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if isHash(strings.TrimPrefix(ext, ".")) {
+		// No extension: name.hash
+		return dir + stem, ext[1:], true
+	}
+	i := strings.LastIndexByte(stem, '.')
+	if i < 0 || !isHash(stem[i+1:]) {
+		return "", "", false
+	}
+	return dir + stem[:i] + ext, stem[i+1:], true
+}
+
+func isHash(s string) bool {
+	if len(s) != hashLen {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// load returns the file name, reading it on first use (and, in dev mode,
+// when it changed)
+func (s *Server) load(name string) (*file, error) {
+	if !fs.ValidPath(name) || hidden(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	s.mu.RLock()
+	f := s.files[name]
+	s.mu.RUnlock()
+	if f != nil && !s.dev.Load() {
+		return f, nil
+	}
+
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fs.ErrNotExist
+	}
+	if f != nil && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+		return f, nil
+	}
+
+	f, err = s.read(name, info)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.files[name] = f
+	s.mu.Unlock()
+	return f, nil
+}
+
+// read loads a file and its compressed variants
+func (s *Server) read(name string, info fs.FileInfo) (*file, error) {
+	content, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	f := &file{
+		name:    name,
+		hash:    hex.EncodeToString(sum[:])[:hashLen],
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		ctype:   contentType(name, content),
+		content: content,
+	}
+	if !Compressible(f.ctype) {
+		return f, nil
+	}
+
+	f.brotli = s.precompressed(name+".br", info)
+	if f.gzip = s.precompressed(name+".gz", info); f.gzip == nil {
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(content)
+		zw.Close()
+		if buf.Len() < len(content) {
+			f.gzip = buf.Bytes()
+		}
+	}
+	return f, nil
+}
+
+// precompressed reads a compressed copy of a file, unless it is older than
+// the file itself
+func (s *Server) precompressed(name string, source fs.FileInfo) []byte {
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil || info.ModTime().Before(source.ModTime()) {
+		return nil
+	}
+	content, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// Manifest maps the name of every file to its fingerprinted name
+func (s *Server) Manifest() (map[string]string, error) {
+	manifest := make(map[string]string)
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || hidden(name) || isVariant(s.fsys, name) {
+			return err
+		}
+		f, err := s.load(name)
+		if err != nil {
+			return err
+		}
+		manifest[name] = Fingerprint(name, f.hash)
+		return nil
+	})
+	return manifest, err
+}
+
+// isVariant reports whether name is the compressed copy of another file
+func isVariant(fsys fs.FS, name string) bool {
+	for _, ext := range []string{".gz", ".br"} {
+		if strings.HasSuffix(name, ext) {
+			if _, err := fs.Stat(fsys, strings.TrimSuffix(name, ext)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hidden reports whether a path has a dotfile in it (.env, .git/...), which
+// are never served
+func hidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
+
+func contentType(name string, content []byte) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+	return http.DetectContentType(content)
+}
+
+// Compressible reports whether files of a content type are worth compressing
+func Compressible(ctype string) bool {
+	ctype, _, _ = strings.Cut(ctype, ";")
+	switch {
+	case strings.HasPrefix(ctype, "text/"):
+		return true
+	case strings.HasSuffix(ctype, "+xml"), strings.HasSuffix(ctype, "+json"):
+		return true
+	}
+	switch ctype {
+	case "application/javascript", "application/json", "application/xml", "application/wasm", "font/ttf", "font/otf":
+		return true
+	}
+	return false
+}
+
+// accepts reports whether an Accept-Encoding header allows coding
+func accepts(header, coding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case coding:
+			return q > 0
+		case "*":
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
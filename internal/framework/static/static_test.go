@@ -0,0 +1,182 @@
+package static
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var css = strings.Repeat("body { color: red; }\n", 50)
+
+func testServer() (*Server, fstest.MapFS) {
+	fsys := fstest.MapFS{
+		"css/site.css":     {Data: []byte(css)},
+		"js/app.min.js":    {Data: []byte("x")},
+		"js/app.min.js.br": {Data: []byte("brotli!")},
+		"LICENSE":          {Data: []byte("MIT")},
+		".env":             {Data: []byte("SECRET=1")},
+	}
+	return New(fsys, "/static/"), fsys
+}
+
+func get(s *Server, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestFingerprint(t *testing.T) {
+	for _, name := range []string{"css/site.css", "js/app.min.js", "LICENSE"} {
+		fingerprinted := Fingerprint(name, "0123456789ab")
+		original, hash, ok := parseFingerprint(fingerprinted)
+		if !ok || original != name || hash != "0123456789ab" {
+			t.Errorf("parseFingerprint(%q) = %q, %q, %v", fingerprinted, original, hash, ok)
+		}
+	}
+	if got := Fingerprint("js/app.min.js", "0123456789ab"); got != "js/app.min.0123456789ab.js" {
+		t.Errorf("Fingerprint() = %q", got)
+	}
+	if _, _, ok := parseFingerprint("css/site.css"); ok {
+		t.Error("parseFingerprint accepted a plain name")
+	}
+}
+
+func TestServe(t *testing.T) {
+	s, _ := testServer()
+
+	url := s.URL("css/site.css")
+	if !strings.HasPrefix(url, "/static/css/site.") || len(url) != len("/static/css/site.css")+hashLen+1 {
+		t.Fatalf("URL() = %q", url)
+	}
+	if got := s.URL("missing.css"); got != "/static/missing.css" {
+		t.Errorf("URL(missing) = %q", got)
+	}
+
+	// The fingerprinted URL is immutable...
+	w := get(s, url)
+	if w.Code != 200 || w.Body.String() != css || w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Errorf("GET %s = %d %q", url, w.Code, w.Header())
+	}
+	if ctype := w.Header().Get("Content-Type"); !strings.HasPrefix(ctype, "text/css") {
+		t.Errorf("Content-Type = %q", ctype)
+	}
+
+	// ...the plain one is revalidated with its ETag
+	w = get(s, "/static/css/site.css")
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || w.Header().Get("Cache-Control") != "public, no-cache" || etag == "" {
+		t.Errorf("GET plain = %d %q", w.Code, w.Header())
+	}
+	if w := get(s, "/static/css/site.css", "If-None-Match", etag); w.Code != 304 || w.Body.Len() != 0 {
+		t.Errorf("Conditional GET = %d, want 304", w.Code)
+	}
+	if w := get(s, "/static/css/site.css", "If-None-Match", `"other"`); w.Code != 200 {
+		t.Errorf("Conditional GET with another ETag = %d, want 200", w.Code)
+	}
+
+	for _, path := range []string{
+		"/static/css/site.000000000000.css", // Outdated fingerprint
+		"/static/.env",
+		"/static/css",
+		"/static/missing.css",
+	} {
+		if w := get(s, path); w.Code != 404 {
+			t.Errorf("GET %s = %d, want 404", path, w.Code)
+		}
+	}
+	if w := get(s, "/static/LICENSE"); w.Code != 200 || w.Body.String() != "MIT" {
+		t.Errorf("GET LICENSE = %d %q", w.Code, w.Body)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	s, _ := testServer()
+
+	w := get(s, "/static/css/site.css", "Accept-Encoding", "gzip, deflate")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Headers = %q", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != css {
+		t.Error("gzip body doesn't decompress to the file")
+	}
+	gzipTag := w.Header().Get("ETag")
+	if w := get(s, "/static/css/site.css"); w.Header().Get("ETag") == gzipTag {
+		t.Error("gzip and identity responses share an ETag")
+	}
+
+	// Precompressed brotli wins when accepted; gzip isn't worth it for 1 byte
+	if w := get(s, "/static/js/app.min.js", "Accept-Encoding", "gzip, br"); w.Header().Get("Content-Encoding") != "br" || w.Body.String() != "brotli!" {
+		t.Errorf("br response = %q %q", w.Header(), w.Body)
+	}
+	if w := get(s, "/static/js/app.min.js", "Accept-Encoding", "gzip, br;q=0"); w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Content-Encoding = %q, want none", w.Header().Get("Content-Encoding"))
+	}
+	if w := get(s, "/static/css/site.css", "Accept-Encoding", "*"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("Accept-Encoding * = %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestDevMode(t *testing.T) {
+	s, fsys := testServer()
+	before := s.URL("css/site.css")
+
+	fsys["css/site.css"] = &fstest.MapFile{Data: []byte("p {}"), ModTime: time.Now()}
+	if s.URL("css/site.css") != before {
+		t.Error("URL() changed without dev mode")
+	}
+	s.SetDevMode(true)
+	after := s.URL("css/site.css")
+	if after == before {
+		t.Error("URL() didn't change in dev mode")
+	}
+	if w := get(s, after); w.Body.String() != "p {}" {
+		t.Errorf("GET %s = %q", after, w.Body)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0o755)
+	os.WriteFile(filepath.Join(dir, "css", "site.css"), []byte(css), 0o644)
+	os.WriteFile(filepath.Join(dir, "logo.png"), []byte("\x89PNG\r\n\x1a\n"), 0o644)
+
+	manifest, err := Build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != 2 || !strings.HasPrefix(manifest["css/site.css"], "css/site.") {
+		t.Errorf("Build() = %v", manifest)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "css", "site.css.gz")); err != nil {
+		t.Error("No gzip copy of site.css")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logo.png.gz")); err == nil {
+		t.Error("Compressed a PNG")
+	}
+
+	var written map[string]string
+	data, _ := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err := json.Unmarshal(data, &written); err != nil || written["css/site.css"] != manifest["css/site.css"] {
+		t.Errorf("manifest.json = %s", data)
+	}
+
+	// Building again leaves the compressed copies and the manifest out
+	if again, err := Build(dir); err != nil || len(again) != 2 {
+		t.Errorf("Second Build() = %v, %v", again, err)
+	}
+}
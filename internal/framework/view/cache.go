@@ -0,0 +1,288 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Template files are looked up as name.gb.html, then name.html. Both use the
+// same syntax; .gb.html is the convention for templates written for layouts.
+var templateExts = []string{".gb.html", ".html"}
+
+// page is a template compiled with its layouts and partials into one set.
+// It is never executed itself: Render executes a clone, so the per-request
+// helpers can be bound to it.
+type page struct {
+	tmpl    *template.Template
+	files   map[string]time.Time // Source files (paths in the engine's fs.FS) and their modification times
+	sources map[string]*source   // By template name
+}
+
+// source is one translated template file
+type source struct {
+	*translation
+	name string // Template name, e.g. "layouts/base"
+	file string
+}
+
+// SetDevMode makes Render check the files a cached template was compiled from
+// and recompile it when one of them changed. Without it templates are compiled
+// once, on first use.
+//
+// USAGE:
+//
+//	views.SetDevMode(cfg.App.Environment == "development")
+func (e *Engine) SetDevMode(dev bool) {
+	e.dev.Store(dev)
+}
+
+// Reset drops every compiled template, so they are compiled again on next use
+func (e *Engine) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pages = nil
+}
+
+// lookup returns the compiled template for name, compiling it if it isn't
+// cached (or, in dev mode, if its files changed)
+func (e *Engine) lookup(name string) (*page, error) {
+	e.mu.RLock()
+	p := e.pages[name]
+	e.mu.RUnlock()
+	if p != nil && !(e.dev.Load() && p.stale(e.fsys)) {
+		return p, nil
+	}
+
+	p, err := e.compile(name)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	if e.pages == nil {
+		e.pages = make(map[string]*page)
+	}
+	e.pages[name] = p
+	e.mu.Unlock()
+	return p, nil
+}
+
+// stale reports whether a source file changed since p was compiled
+func (p *page) stale(fsys fs.FS) bool {
+	for file, mtime := range p.files {
+		info, err := fs.Stat(fsys, file)
+		if err != nil || !info.ModTime().Equal(mtime) {
+			return true
+		}
+	}
+	return false
+}
+
+// compile parses name, the layouts it extends, every component and every
+// partial they include into one template set, whose root is the outermost
+// layout
+func (e *Engine) compile(name string) (*page, error) {
+	p := &page{files: make(map[string]time.Time), sources: make(map[string]*source)}
+
+	// Follow go:: extends up to the layout that renders the document
+	var chain []*source
+	seen := make(map[string]bool)
+	for n := name; n != ""; {
+		if seen[n] {
+			return nil, fmt.Errorf("template %s: extends cycle through %s", name, n)
+		}
+		seen[n] = true
+		src, err := e.load(n, p)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, src)
+		n = src.extends
+	}
+
+	// Components, partials, and the partials they include
+	partials, err := e.loadComponents(p, seen)
+	if err != nil {
+		return nil, err
+	}
+	queue := append(append([]*source(nil), chain...), partials...)
+	for len(queue) > 0 {
+		src := queue[0]
+		queue = queue[1:]
+		for _, inc := range src.includes {
+			if seen[inc] {
+				continue
+			}
+			seen[inc] = true
+			partial, err := e.load(inc, p)
+			if err != nil {
+				return nil, err
+			}
+			if partial.extends != "" {
+				return nil, fmt.Errorf("template %s: a partial can't extend a layout", inc)
+			}
+			partials = append(partials, partial)
+			queue = append(queue, partial)
+		}
+	}
+
+	// Later definitions of a block replace earlier ones, so partials go
+	// first, then the layouts from the root down and the page last
+	order := partials
+	for i := len(chain) - 1; i >= 0; i-- {
+		order = append(order, chain[i])
+	}
+	for _, src := range order {
+		for _, c := range src.components {
+			if p.sources[componentDir+"/"+c] == nil {
+				return nil, fmt.Errorf("template %s: component %q not found (add %s)", src.name, c, e.path(componentDir+"/"+c+templateExts[0]))
+			}
+		}
+	}
+	root := chain[len(chain)-1].name
+	set := template.New(root).Funcs(e.funcs)
+	for _, src := range order {
+		tmpl := set
+		if src.name != root {
+			tmpl = set.New(src.name)
+		}
+		if _, err := tmpl.Parse(src.text); err != nil {
+			if e.verbose.Load() {
+				fmt.Printf("[VERBOSE] Template parse error for %s: %v\n", src.file, err)
+				fmt.Printf("[VERBOSE] Processed content:\n%s\n", src.text)
+			}
+			return nil, fmt.Errorf("failed to parse template: %w", p.locate(err))
+		}
+
+		// The slots of go:: component blocks, which component.go renders
+		for _, slot := range src.slots {
+			if slot.blank() {
+				continue
+			}
+			p.sources[slot.name] = src
+			if _, err := set.New(slot.name).Parse(slot.text()); err != nil {
+				return nil, fmt.Errorf("failed to parse template: %w", p.locate(err))
+			}
+		}
+	}
+
+	p.tmpl = set
+	return p, nil
+}
+
+// load reads and preprocesses the template file for name, recording it in p
+func (e *Engine) load(name string, p *page) (*source, error) {
+	var err error
+	for _, ext := range templateExts {
+		file := name + ext
+		var info fs.FileInfo
+		if info, err = fs.Stat(e.fsys, file); err != nil {
+			continue
+		}
+		var content []byte
+		if content, err = fs.ReadFile(e.fsys, file); err != nil {
+			break
+		}
+		p.files[file] = info.ModTime()
+
+		file = e.path(file)
+		translated, err := translate(name, file, string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		src := &source{translation: translated, name: name, file: file}
+		p.sources[name] = src
+		return src, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		err = fmt.Errorf("template %q not found in %s: %w", name, e.path("."), fs.ErrNotExist)
+	}
+	return nil, fmt.Errorf("failed to read template: %w", err)
+}
+
+// loadComponents loads every template in the components directory into p,
+// except those already seen. The directories are recorded too, so dev mode
+// notices new components.
+func (e *Engine) loadComponents(p *page, seen map[string]bool) ([]*source, error) {
+	var names []string
+	err := fs.WalkDir(e.fsys, componentDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			info, err := d.Info()
+			if err == nil {
+				p.files[path] = info.ModTime()
+			}
+			return err
+		}
+		for _, ext := range templateExts {
+			if name, ok := strings.CutSuffix(path, ext); ok {
+				names = append(names, name)
+				break
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil // No components
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read components: %w", err)
+	}
+
+	var components []*source
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		src, err := e.load(name, p)
+		if err != nil {
+			return nil, err
+		}
+		if src.extends != "" {
+			return nil, fmt.Errorf("template %s: a component can't extend a layout", name)
+		}
+		components = append(components, src)
+	}
+	return components, nil
+}
+
+// path names a file of the engine's fs.FS in errors
+func (e *Engine) path(file string) string {
+	if e.baseDir == "" {
+		return file
+	}
+	return filepath.Join(e.baseDir, filepath.FromSlash(file))
+}
+
+// locate rewrites an html/template error to point at the template's source
+// file, line and column. An error from a component or slot already points
+// at its own line, which is kept rather than the line of the call.
+func (p *page) locate(err error) error {
+	var terr *TemplateError
+	if errors.As(err, &terr) {
+		return terr
+	}
+	m := goErrorRegex.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	src := p.sources[m[1]]
+	if src == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(m[2])
+	col := 0
+	if m[3] != "" {
+		col, _ = strconv.Atoi(m[3])
+		col = src.col(line, col)
+	}
+	return &TemplateError{File: src.file, Line: line, Col: col, Msg: m[4], Err: err}
+}
@@ -0,0 +1,188 @@
+package view
+
+import (
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLayouts(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layouts/base.gb.html", `<title>
+go:: block "title"
+Site
+::end
+</title>
+go:: include "partials/nav" .User
+go:: block "content"
+nothing here
+::end
+<footer>base</footer>`)
+	writeTemplate(t, dir, "layouts/admin.gb.html", `go:: extends "layouts/base"
+go:: block "content"
+<main>
+go:: block "main"
+::end
+</main>
+::end`)
+	writeTemplate(t, dir, "partials/nav.gb.html", `<nav>@.</nav>`)
+	writeTemplate(t, dir, "admin/users.gb.html", `go:: extends "layouts/admin"
+Ignored: outside of any block
+go:: block "title"
+Users
+::end
+go:: block "main"
+<p>@.Count users</p>
+::end`)
+
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := engine.RenderString("admin/users", map[string]any{"User": "ana", "Count": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Users", "<nav>ana</nav>", "<main>", "<p>3 users</p>", "<footer>base</footer>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Site", "nothing here", "Ignored"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Unexpected %q in:\n%s", unwanted, got)
+		}
+	}
+
+	// The layout renders its own defaults
+	got, err = engine.RenderString("layouts/base", map[string]any{"User": "ana"})
+	if err != nil || !strings.Contains(got, "Site") || !strings.Contains(got, "nothing here") {
+		t.Errorf("RenderString(layouts/base) = %q, %v", got, err)
+	}
+}
+
+func TestTemplateCache(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "page.html", `v1 <span>@cspNonce</span>`)
+
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func() string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		if err := engine.Render(nonceWriter{w}, "page", nil); err != nil {
+			t.Fatal(err)
+		}
+		return w.Body.String()
+	}
+	if got := render(); got != "v1 <span>n0nce</span>" {
+		t.Fatalf("Render() = %q", got)
+	}
+
+	// Compiled once: later edits are ignored...
+	writeTemplate(t, dir, "page.html", `v2`)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "page.html"), future, future)
+	if got := render(); !strings.HasPrefix(got, "v1") {
+		t.Errorf("Render() = %q, want the cached template", got)
+	}
+
+	// ...unless in dev mode
+	engine.SetDevMode(true)
+	if got := render(); got != "v2" {
+		t.Errorf("Render() in dev mode = %q, want v2", got)
+	}
+
+	// .gb.html takes precedence
+	writeTemplate(t, dir, "page.gb.html", `v3`)
+	engine.Reset()
+	if got := render(); got != "v3" {
+		t.Errorf("Render() = %q, want page.gb.html", got)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "a.html", `go:: extends "b"`)
+	writeTemplate(t, dir, "b.html", `go:: extends "a"`)
+	writeTemplate(t, dir, "twice.html", "go:: extends \"a\"\ngo:: extends \"b\"")
+	writeTemplate(t, dir, "partial.html", `go:: include "a"`)
+
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"a":       "extends cycle",
+		"twice":   "extends used more than once",
+		"partial": "a partial can't extend a layout",
+		"missing": "not found",
+	} {
+		if _, err := engine.RenderString(name, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("RenderString(%q) error = %v, want %q", name, err, want)
+		}
+	}
+	if _, err := engine.RenderString("missing", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Missing template error %v doesn't wrap fs.ErrNotExist", err)
+	}
+}
+
+// Templates can come from any fs.FS, such as an embed.FS
+func TestEngineFS(t *testing.T) {
+	engine := NewEngineFS(fstest.MapFS{
+		"layouts/base.gb.html": {Data: []byte("<main>\ngo:: block \"content\"\n::end\n</main>")},
+		"pages/home.gb.html":   {Data: []byte("go:: extends \"layouts/base\"\ngo:: block \"content\"\nHi @.\n::end")},
+		"pages/broken.html":    {Data: []byte("@nope()")},
+	})
+
+	got, err := engine.RenderString("pages/home", "ana")
+	if err != nil || !strings.Contains(got, "Hi ana") {
+		t.Errorf("RenderString() = %q, %v", got, err)
+	}
+
+	// Errors name the file inside the file system
+	_, err = engine.RenderString("pages/broken", nil)
+	var terr *TemplateError
+	if !errors.As(err, &terr) || terr.File != "pages/broken.html" {
+		t.Errorf("RenderString(pages/broken) error = %v", err)
+	}
+	if names, _ := engine.Templates(); strings.Join(names, " ") != "layouts/base pages/broken pages/home" {
+		t.Errorf("Templates() = %v", names)
+	}
+}
+
+// The shipped pages compile with their layouts and partials
+func TestShippedLayouts(t *testing.T) {
+	engine, err := NewEngine("../../../templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"auth/login", "auth/register", "auth/two_factor", "admin/dashboard", "admin/users_list", "admin/user_detail"} {
+		if _, err := engine.RenderString(name, map[string]any{"Title": "Test"}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+type nonceWriter struct{ *httptest.ResponseRecorder }
+
+func (nonceWriter) CSPNonce() string { return "n0nce" }
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,355 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Declare records the type of the data a template is rendered with, so Check
+// can verify the fields it uses. data is a value of that type, usually its
+// zero value:
+//
+//	views.Declare("chat/messages", chatHistoryData{})
+//
+// Declare it where the handler is registered. A nil Engine ignores it, so
+// routes can still be registered without one (see go-bastion routes).
+func (e *Engine) Declare(name string, data any) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.types == nil {
+		e.types = make(map[string]reflect.Type)
+	}
+	e.types[name] = reflect.TypeOf(data)
+}
+
+// Templates lists the names of the engine's templates
+func (e *Engine) Templates() ([]string, error) {
+	seen := make(map[string]bool)
+	err := fs.WalkDir(e.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		for _, ext := range templateExts {
+			if strings.HasSuffix(path, ext) {
+				seen[strings.TrimSuffix(path, ext)] = true
+				break
+			}
+		}
+		return nil
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, err
+}
+
+// Check compiles every template without rendering it for a request and
+// returns the problems found: syntax errors, unknown functions, unbalanced
+// go::/::end, HTML contexts html/template can't escape and, for templates
+// with a Declare'd data type, fields that type doesn't have.
+func (e *Engine) Check() ([]error, error) {
+	names, err := e.Templates()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []error
+	for _, name := range names {
+		p, err := e.compile(name)
+		if err != nil {
+			problems = append(problems, unwrapParse(err))
+			continue
+		}
+
+		// html/template checks contexts when the template first runs
+		tmpl, err := p.tmpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+		var escapeErr *template.Error
+		if err := tmpl.Execute(io.Discard, nil); errors.As(err, &escapeErr) {
+			problems = append(problems, p.locate(err))
+			continue
+		}
+
+		e.mu.RLock()
+		dataType, declared := e.types[name]
+		e.mu.RUnlock()
+		if declared {
+			c := &typeChecker{page: p, funcs: e.funcs, visited: make(map[string]bool)}
+			c.template(p.tmpl.Name(), dataType)
+			problems = append(problems, c.problems...)
+		}
+	}
+	return problems, nil
+}
+
+// unwrapParse drops the "failed to parse template" prefix from errors that
+// already say where they are
+func unwrapParse(err error) error {
+	var terr *TemplateError
+	if errors.As(err, &terr) {
+		return terr
+	}
+	return err
+}
+
+// typeChecker follows the type of dot through a compiled template. A nil
+// type is unknown (interfaces, untyped function results) and isn't checked.
+type typeChecker struct {
+	page     *page
+	funcs    template.FuncMap
+	visited  map[string]bool // Template and dot type pairs already checked
+	problems []error
+}
+
+// scope holds the types of the variables in scope
+type scope map[string]reflect.Type
+
+func (c *typeChecker) template(name string, dot reflect.Type) {
+	key := name + "|" + fmt.Sprint(dot)
+	if c.visited[key] {
+		return
+	}
+	c.visited[key] = true
+
+	tmpl := c.page.tmpl.Lookup(name)
+	if tmpl == nil || tmpl.Tree == nil {
+		return
+	}
+	c.list(tmpl.Tree, tmpl.Tree.Root, dot, scope{"$": dot})
+}
+
+func (c *typeChecker) list(tree *parse.Tree, list *parse.ListNode, dot reflect.Type, vars scope) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		c.node(tree, node, dot, vars)
+	}
+}
+
+func (c *typeChecker) node(tree *parse.Tree, node parse.Node, dot reflect.Type, vars scope) {
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		c.pipe(tree, n.Pipe, dot, vars)
+	case *parse.IfNode:
+		c.pipe(tree, n.Pipe, dot, vars)
+		c.list(tree, n.List, dot, vars.clone())
+		c.list(tree, n.ElseList, dot, vars.clone())
+	case *parse.WithNode:
+		t := c.pipe(tree, n.Pipe, dot, vars)
+		c.list(tree, n.List, t, vars.clone())
+		c.list(tree, n.ElseList, dot, vars.clone())
+	case *parse.RangeNode:
+		inner := vars.clone()
+		key, elem := rangeTypes(c.pipeTypeOnly(tree, n.Pipe, dot, vars))
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			inner[n.Pipe.Decl[0].Ident[0]] = key
+			inner[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		c.list(tree, n.List, elem, inner)
+		c.list(tree, n.ElseList, dot, vars.clone())
+	case *parse.TemplateNode:
+		var t reflect.Type
+		if n.Pipe != nil {
+			t = c.pipe(tree, n.Pipe, dot, vars)
+		}
+		c.template(n.Name, t)
+	}
+}
+
+// pipe checks a pipeline, declares its variables and returns its type
+func (c *typeChecker) pipe(tree *parse.Tree, pipe *parse.PipeNode, dot reflect.Type, vars scope) reflect.Type {
+	t := c.pipeTypeOnly(tree, pipe, dot, vars)
+	for _, v := range pipe.Decl {
+		vars[v.Ident[0]] = t
+	}
+	return t
+}
+
+func (c *typeChecker) pipeTypeOnly(tree *parse.Tree, pipe *parse.PipeNode, dot reflect.Type, vars scope) reflect.Type {
+	var t reflect.Type
+	for _, cmd := range pipe.Cmds {
+		t = c.command(tree, cmd, dot, vars, t)
+	}
+	return t
+}
+
+// command returns the type of a command; prev is the type piped into it
+func (c *typeChecker) command(tree *parse.Tree, cmd *parse.CommandNode, dot reflect.Type, vars scope, prev reflect.Type) reflect.Type {
+	args := make([]reflect.Type, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		args[i] = c.arg(tree, arg, dot, vars)
+	}
+	if fn, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		if prev != nil {
+			args = append(args, prev)
+		}
+		return c.call(fn.Ident, args[1:])
+	}
+	return args[0]
+}
+
+// arg returns the type of an operand, reporting fields its type doesn't have
+func (c *typeChecker) arg(tree *parse.Tree, node parse.Node, dot reflect.Type, vars scope) reflect.Type {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.fields(tree, n, dot, n.Ident)
+	case *parse.VariableNode:
+		return c.fields(tree, n, vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		return c.fields(tree, n, c.arg(tree, n.Node, dot, vars), n.Field)
+	case *parse.PipeNode:
+		return c.pipeTypeOnly(tree, n, dot, vars)
+	case *parse.IdentifierNode:
+		return c.call(n.Ident, nil)
+	case *parse.StringNode:
+		return reflect.TypeFor[string]()
+	case *parse.BoolNode:
+		return reflect.TypeFor[bool]()
+	case *parse.NumberNode:
+		if n.IsInt {
+			return reflect.TypeFor[int]()
+		}
+		return reflect.TypeFor[float64]()
+	}
+	return nil
+}
+
+// fields resolves a chain of field and method names from t
+func (c *typeChecker) fields(tree *parse.Tree, node parse.Node, t reflect.Type, names []string) reflect.Type {
+	for _, name := range names {
+		if t == nil {
+			return nil
+		}
+		if m, ok := method(t, name); ok {
+			if m.Type.NumOut() == 0 {
+				return nil
+			}
+			t = m.Type.Out(0)
+			continue
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Interface:
+			return nil
+		case reflect.Map:
+			if t.Key().Kind() != reflect.String {
+				c.report(tree, node, fmt.Sprintf("can't evaluate field %s in type %s", name, t))
+				return nil
+			}
+			t = t.Elem()
+		case reflect.Struct:
+			f, ok := t.FieldByName(name)
+			if !ok {
+				c.report(tree, node, fmt.Sprintf("can't evaluate field %s in type %s", name, t))
+				return nil
+			}
+			if !f.IsExported() {
+				c.report(tree, node, fmt.Sprintf("%s is an unexported field of struct type %s", name, t))
+				return nil
+			}
+			t = f.Type
+		default:
+			c.report(tree, node, fmt.Sprintf("can't evaluate field %s in type %s", name, t))
+			return nil
+		}
+	}
+	return t
+}
+
+// call returns the result type of a function
+func (c *typeChecker) call(name string, args []reflect.Type) reflect.Type {
+	switch name {
+	case "not", "eq", "ne", "lt", "le", "gt", "ge":
+		return reflect.TypeFor[bool]()
+	case "len":
+		return reflect.TypeFor[int]()
+	case "print", "printf", "println", "html", "js", "urlquery":
+		return reflect.TypeFor[string]()
+	case "slice":
+		if len(args) > 0 {
+			return args[0]
+		}
+	case "index":
+		if len(args) == 0 {
+			return nil
+		}
+		t := args[0]
+		for range args[1:] {
+			_, t = rangeTypes(t)
+		}
+		return t
+	}
+	if fn, ok := c.funcs[name]; ok {
+		if t := reflect.TypeOf(fn); t.Kind() == reflect.Func && t.NumOut() > 0 {
+			return t.Out(0)
+		}
+	}
+	return nil
+}
+
+func (c *typeChecker) report(tree *parse.Tree, node parse.Node, msg string) {
+	location, _ := tree.ErrorContext(node)
+	c.problems = append(c.problems, c.page.locate(fmt.Errorf("template: %s: %s", location, msg)))
+}
+
+// method finds a method of t or *t
+func method(t reflect.Type, name string) (reflect.Method, bool) {
+	if m, ok := t.MethodByName(name); ok {
+		return m, true
+	}
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface {
+		return reflect.PointerTo(t).MethodByName(name)
+	}
+	return reflect.Method{}, false
+}
+
+// rangeTypes returns the key and element types of ranging over (or indexing) t
+func rangeTypes(t reflect.Type) (key, elem reflect.Type) {
+	if t == nil {
+		return nil, nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeFor[int](), t.Elem()
+	case reflect.Map:
+		return t.Key(), t.Elem()
+	case reflect.Chan:
+		return t.Elem(), t.Elem()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return t, t
+	}
+	return nil, nil
+}
+
+func (s scope) clone() scope {
+	c := make(scope, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,106 @@
+package view
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type checkUser struct {
+	Name    string
+	Created time.Time
+	secret  string
+}
+
+func (u checkUser) Initials() string { return u.Name[:1] }
+
+type checkData struct {
+	Title string
+	Users []checkUser
+	Tags  map[string]string
+	Extra any
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layouts/base.gb.html", `<title>@.Title</title>
+go:: block "content"
+::end`)
+	writeTemplate(t, dir, "partials/user.gb.html", `<li>@.Name @.Initials() @.Created.Year() @.Nmae</li>`)
+	writeTemplate(t, dir, "users.gb.html", `go:: extends "layouts/base"
+go:: block "content"
+go:: range $i, $u := .Users
+  go:: include "partials/user" $u
+  @$i @$u.secret
+::end
+@.Tags.anything @.Extra.Whatever @len(.Users) @upper(.Title).Nope
+@.Titel
+::end`)
+	writeTemplate(t, dir, "unknown.html", "<p>\n@nope(.X)</p>")
+	writeTemplate(t, dir, "unclosed.html", "go:: if .X\n")
+	writeTemplate(t, dir, "context.html", "<a href=\"\ngo:: if .X\n\"\n::end\n\">")
+	writeTemplate(t, dir, "fine.html", "<p>@.Anything.Goes</p>")
+
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.Declare("users", checkData{})
+
+	names, err := engine.Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names, " "); got != "context fine layouts/base partials/user unclosed unknown users" {
+		t.Errorf("Templates() = %s", got)
+	}
+
+	problems, err := engine.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimPrefix(p.Error(), dir+"/"))
+	}
+	sort.Strings(got)
+	want := []string{
+		"context.html:",
+		"partials/user.gb.html:1:42: can't evaluate field Nmae in type view.checkUser",
+		"unclosed.html:1:1: go:: if is never closed (missing ::end)",
+		"unknown.html:2: function \"nope\" not defined",
+		"users.gb.html:5:7: secret is an unexported field of struct type view.checkUser",
+		"users.gb.html:7:47: can't evaluate field Nope in type string",
+		"users.gb.html:8:1: can't evaluate field Titel in type view.checkData",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Check() = %d problems, want %d:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("Problem %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// The shipped templates check cleanly
+func TestCheckShipped(t *testing.T) {
+	engine, err := NewEngine("../../../templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := engine.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		t.Error(p)
+	}
+}
+
+// Declare on a nil Engine is a no-op, for routes registered without views
+func TestDeclareNil(t *testing.T) {
+	var engine *Engine
+	engine.Declare("x", checkData{})
+}
@@ -0,0 +1,105 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Components are templates in templates/components/ that any page, layout
+// or partial can render with data of its own, its props:
+//
+//	@component("Badge", .User.Role)
+//
+// A go:: component block also passes content, its slots. The body is the
+// default slot and go:: slot blocks inside it fill named ones; both render
+// with the dot of the page where they are written:
+//
+//	go:: component "Card" .Stats
+//	  <p>@.User.Name</p>
+//	  go:: slot "footer"
+//	    <a href="/admin/users">@t("admin.view_all")</a>
+//	  ::end
+//	::end
+//
+// Inside templates/components/Card.gb.html, dot is the props, @slot() renders
+// the default slot, @slot("footer") a named one, and hasSlot("footer")
+// reports whether the caller filled it.
+const componentDir = "components"
+
+// slotSet is the slots a go:: component block passed: the templates named
+// after the block (see slotDef) and the dot to render them with
+type slotSet struct {
+	id  string
+	dot any
+}
+
+// template returns the slot's template, or nil if the caller left it empty
+func (s *slotSet) template(tmpl *template.Template, name []string) *template.Template {
+	if s == nil {
+		return nil
+	}
+	id := s.id
+	if len(name) > 0 && name[0] != "" {
+		id += "." + name[0]
+	}
+	return tmpl.Lookup(id)
+}
+
+// componentFrame is a component being rendered. Slots render in the frame
+// of the template that filled them, so components can nest.
+type componentFrame struct {
+	slots  *slotSet
+	parent *componentFrame
+}
+
+// componentFuncs returns component, slots, slot and hasSlot bound to tmpl,
+// an instance of p. Each instance renders one response, so the frames need
+// no locking.
+func componentFuncs(tmpl *template.Template, p *page) template.FuncMap {
+	var current *componentFrame
+
+	// render executes the named template of tmpl in frame
+	render := func(name string, data any, frame *componentFrame) (template.HTML, error) {
+		saved := current
+		current = frame
+		defer func() { current = saved }()
+
+		var buf strings.Builder
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", p.locate(err)
+		}
+		return template.HTML(buf.String()), nil
+	}
+
+	return template.FuncMap{
+		"component": func(name string, props any, slots ...*slotSet) (template.HTML, error) {
+			t := tmpl.Lookup(componentDir + "/" + name)
+			if t == nil {
+				return "", fmt.Errorf("component %q not found (add %s/%s%s)", name, componentDir, name, templateExts[0])
+			}
+			frame := &componentFrame{parent: current}
+			if len(slots) > 0 {
+				frame.slots = slots[0]
+			}
+			return render(t.Name(), props, frame)
+		},
+		"slots": func(id string, dot any) *slotSet {
+			return &slotSet{id: id, dot: dot}
+		},
+		"slot": func(name ...string) (template.HTML, error) {
+			if current == nil {
+				return "", errors.New("slot used outside a component")
+			}
+			t := current.slots.template(tmpl, name)
+			if t == nil {
+				return "", nil
+			}
+			return render(t.Name(), current.slots.dot, current.parent)
+		},
+		"hasSlot": func(name ...string) bool {
+			return current != nil && current.slots.template(tmpl, name) != nil
+		},
+	}
+}
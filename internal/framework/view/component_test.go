@@ -0,0 +1,146 @@
+package view
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func componentEngine(t *testing.T) (*Engine, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeTemplate(t, dir, "components/Card.gb.html", `<div class="card">
+<h2>@.Title</h2>
+@slot()
+go:: if hasSlot("footer")
+<footer>@slot("footer")</footer>
+::end
+</div>`)
+	writeTemplate(t, dir, "components/Badge.gb.html", `<span class="badge">@.</span>`)
+	writeTemplate(t, dir, "page.gb.html", `<main>
+go:: range .Users
+go:: component "Card" $.Card
+<p>@.Name</p>
+go:: slot "footer"
+@component("Badge", .Role)
+::end
+::end
+::end
+go:: component "Card" $.Card
+::end
+</main>`)
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine, dir
+}
+
+func TestComponents(t *testing.T) {
+	engine, _ := componentEngine(t)
+	data := map[string]any{
+		"Card": map[string]string{"Title": "<User>"},
+		"Users": []map[string]string{
+			{"Name": "Ana", "Role": "admin"},
+			{"Name": "Bob", "Role": "user"},
+		},
+	}
+	got, err := engine.RenderString("page", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Slots render with the dot of the range they are in, props are escaped
+	for _, want := range []string{
+		`<h2>&lt;User&gt;</h2>`,
+		"<p>Ana</p>",
+		`<span class="badge">admin</span>`,
+		"<p>Bob</p>",
+		`<span class="badge">user</span>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render() is missing %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, `<div class="card">`); n != 3 {
+		t.Errorf("Render() has %d cards, want 3:\n%s", n, got)
+	}
+	if n := strings.Count(got, "<footer>"); n != 2 {
+		t.Errorf("Render() has %d footers, want 2 (the empty card has none)", n)
+	}
+}
+
+func TestComponentErrors(t *testing.T) {
+	engine, dir := componentEngine(t)
+	writeTemplate(t, dir, "missing.gb.html", "go:: component \"Modal\"\n::end")
+	writeTemplate(t, dir, "broken_slot.gb.html", "<p>\ngo:: component \"Card\" .\n<b>\n  @.User.Name</b>\n::end")
+	writeTemplate(t, dir, "outside.gb.html", "@slot()")
+
+	if _, err := engine.RenderString("missing", nil); err == nil || !strings.Contains(err.Error(), `component "Modal" not found`) {
+		t.Errorf("Unknown component error = %v", err)
+	}
+
+	// Errors in a slot point at the line of the page
+	_, err := engine.RenderString("broken_slot", map[string]any{"Title": "x", "User": "ana"})
+	var terr *TemplateError
+	if !errors.As(err, &terr) || terr.File != filepath.Join(dir, "broken_slot.gb.html") || terr.Line != 4 || terr.Col != 3 {
+		t.Errorf("Slot error = %v, want broken_slot.gb.html:4:3", err)
+	}
+
+	// and errors in a component at the component's
+	_, err = engine.RenderString("page", map[string]any{"Card": "x", "Users": []int{1}})
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "components", "Card.gb.html")+":2:") {
+		t.Errorf("Component error = %v, want Card.gb.html:2", err)
+	}
+
+	if _, err := engine.RenderString("outside", nil); err == nil || !strings.Contains(err.Error(), "slot used outside a component") {
+		t.Errorf("Slot outside a component error = %v", err)
+	}
+}
+
+func TestTranslateComponents(t *testing.T) {
+	got, err := translate("page", "page.html", "<ul>\ngo:: component \"List\" .Items\n<li>@.Name</li>\n  go:: slot \"empty\"\n  none\n  ::end\n::end\n</ul>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<ul>\n{{ component \"List\" .Items (slots \"page#2\" .) }}\n\n\n\n\n\n</ul>"; got.text != want {
+		t.Errorf("text = %q, want %q", got.text, want)
+	}
+	if len(got.slots) != 2 || len(got.components) != 1 || got.components[0] != "List" {
+		t.Fatalf("slots = %v, components = %v", got.slots, got.components)
+	}
+
+	withProps, err := translate("t", "t.html", "go:: component \"Card\" dict(\"Title\", .Title)\n::end")
+	if want := `{{ component "Card" (dict "Title" .Title) (slots "t#1" .) }}`; err != nil || !strings.HasPrefix(withProps.text, want) {
+		t.Errorf("text = %q, %v, want %q", withProps.text, err, want)
+	}
+
+	// Slot contents keep their line numbers
+	for _, tt := range []struct{ name, text string }{
+		{"page#2", "{{/*\n*/}}\n<li>{{ .Name }}</li>\n\n\n\n"},
+		{"page#2.empty", "{{/*\n\n\n*/}}\n  none\n"},
+	} {
+		var text string
+		for _, s := range got.slots {
+			if s.name == tt.name {
+				text = s.text()
+			}
+		}
+		if text != tt.text {
+			t.Errorf("slot %s = %q, want %q", tt.name, text, tt.text)
+		}
+	}
+
+	for input, want := range map[string]string{
+		"go:: slot \"footer\"\n::end":                                                 "t.html:1:1: go:: slot must be directly inside a go:: component block",
+		"go:: component \"A\"\ngo:: if .X\ngo:: slot \"b\"\n::end\n::end":             "t.html:3:1: go:: slot must be directly inside a go:: component block",
+		"go:: component \"A\"\ngo:: end":                                              "t.html:2:1: go:: component must be closed with ::end",
+		"go:: component \"A\"\ngo:: slot \"b\"\n::end\ngo:: slot \"b\"\n::end\n::end": `t.html:4:1: slot "b" is already defined`,
+	} {
+		_, err := translate("t", "t.html", input)
+		if err == nil || err.Error() != want {
+			t.Errorf("translate(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
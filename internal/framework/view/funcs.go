@@ -0,0 +1,147 @@
+package view
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// stdFuncs returns the helpers every template can use, besides the
+// per-request ones (see requestFuncs) and the locale-aware ones (see
+// localeFuncs). They are documented in TEMPLATE_SYNTAX.md.
+func stdFuncs() template.FuncMap {
+	return template.FuncMap{
+		"upper": strings.ToUpper, // @upper(.Name) -> JOHN
+		"lower": strings.ToLower, // @lower(.Name) -> john
+		"title": strings.Title,   // @title(.Name) -> John
+
+		"truncate":  truncate,  // @truncate(.Bio, 80) -> first 80 characters…
+		"pluralize": pluralize, // @pluralize(.Count, "user", "users")
+		"default":   orDefault, // @default(.Name, "Anonymous")
+		"dict":      dict,      // go:: include "partials/card" dict("Title", .Title, "User", .User)
+		"list":      list,      // go:: range list("a", "b", "c")
+		"json":      toJSON,    // <script>const user = @json(.User);</script>
+		"markdown":  markdown,  // @markdown(.Comment) -> safe HTML
+		"url":       buildURL,  // @url("/users/{id}", "id", .ID, "tab", "posts") -> /users/7?tab=posts
+
+		// @asset("css/output.css") -> /static/css/output.css, fingerprinted once
+		// a static.Server's URL method is added in its place
+		"asset": func(name string) string { return "/static/" + strings.TrimPrefix(name, "/") },
+	}
+}
+
+// truncate shortens s to n characters, ending it with suffix ("…" by default)
+// when it was cut
+func truncate(s string, n int, suffix ...string) string {
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	end := "…"
+	if len(suffix) > 0 {
+		end = suffix[0]
+	}
+	r := []rune(s)
+	return strings.TrimRight(string(r[:n]), " ") + end
+}
+
+// pluralize returns singular when n is 1 and plural otherwise (singular + "s"
+// if not given)
+func pluralize(n any, singular string, plural ...string) (string, error) {
+	f, _, err := toNumber(n)
+	if err != nil {
+		return "", fmt.Errorf("pluralize: %w", err)
+	}
+	if f == 1 {
+		return singular, nil
+	}
+	if len(plural) > 0 {
+		return plural[0], nil
+	}
+	return singular + "s", nil
+}
+
+// orDefault returns fallback when value is empty: nil, zero, "" or an empty
+// slice or map
+func orDefault(value, fallback any) any {
+	if empty(value) {
+		return fallback
+	}
+	return value
+}
+
+func empty(value any) bool {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// dict builds a map from key/value pairs, to pass several values to a partial
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is a %T, not a string", pairs[i], pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// list builds a slice from its arguments
+func list(items ...any) []any {
+	return items
+}
+
+// toJSON encodes v as JSON that is safe inside a <script> element: <, > and &
+// are escaped, so the data can't close the element
+func toJSON(v any) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("json: %w", err)
+	}
+	return template.JS(b), nil
+}
+
+// buildURL fills the {name} parameters of a path (router syntax) from
+// key/value pairs and adds the other pairs as the query string
+func buildURL(path string, pairs ...any) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", errors.New("url: odd number of arguments")
+	}
+	query := url.Values{}
+	for i := 0; i < len(pairs); i += 2 {
+		key := fmt.Sprint(pairs[i])
+		value := fmt.Sprint(pairs[i+1])
+		if param := "{" + key + "}"; strings.Contains(path, param) {
+			path = strings.ReplaceAll(path, param, url.PathEscape(value))
+		} else {
+			query.Add(key, value)
+		}
+	}
+	if start := strings.Index(path, "{"); start >= 0 && strings.Contains(path[start:], "}") {
+		return "", fmt.Errorf("url: no value for %s", path[start:start+strings.Index(path[start:], "}")+1])
+	}
+	if len(query) == 0 {
+		return path, nil
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + query.Encode(), nil
+}
@@ -0,0 +1,149 @@
+package view
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStdFuncs(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "page.html", `@truncate(.Bio, 10)|@truncate(.Bio, 10, "...")|@truncate("short", 10)
+@.Count @pluralize(.Count, "user")|@pluralize(1, "person", "people")|@pluralize(2, "person", "people")
+@default(.Name, "Anonymous")|@default(.Bio, "none")|@default(.Items, "no items")
+go:: with dict("Title", .Bio, "N", 2)
+@.Title @.N
+::end
+go:: range list("a", "b")
+[@.]
+::end
+<script>const data = @json(.Data);</script>
+<a href="@url("/users/{id}", "id", 7, "tab", "a b")">x</a>`)
+
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := engine.RenderString("page", map[string]any{
+		"Bio":   "Gopher since 2009",
+		"Count": 3,
+		"Name":  "",
+		"Items": []string{},
+		"Data":  map[string]string{"x": "</script><b>"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Gopher sin…|Gopher sin...|short",
+		"3 users|person|people",
+		"Anonymous|Gopher since 2009|no items",
+		"Gopher since 2009 2",
+		"[a]", "[b]",
+		`const data = {"x":"\u003c/script\u003e\u003cb\u003e"};`,
+		`<a href="/users/7?tab=a&#43;b">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestStdFuncErrors(t *testing.T) {
+	if _, err := dict("a"); err == nil {
+		t.Error("dict with an odd number of arguments")
+	}
+	if _, err := dict(1, 2); err == nil {
+		t.Error("dict with a non-string key")
+	}
+	if _, err := buildURL("/users/{id}"); err == nil || !strings.Contains(err.Error(), "{id}") {
+		t.Errorf("url without a parameter value error = %v", err)
+	}
+	if got, _ := buildURL("/search?q=go", "page", 2); got != "/search?q=go&page=2" {
+		t.Errorf("url with a query = %q", got)
+	}
+	if _, err := pluralize("x", "a"); err == nil {
+		t.Error("pluralize with a string count")
+	}
+}
+
+func TestLocaleFuncs(t *testing.T) {
+	now = func() time.Time { return time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	created := time.Date(2025, 3, 3, 9, 5, 0, 0, time.UTC) // A Monday
+
+	tests := []struct {
+		locale string
+		tmpl   string
+		want   string
+	}{
+		{"en", `@date(.T)`, "Mar 3, 2025"},
+		{"en", `@date(.T, "full")`, "Monday, March 3, 2025"},
+		{"en", `@date(.T, "time")`, "9:05 AM"},
+		{"en", `@date(.T, "2006-01-02")`, "2025-03-03"},
+		{"es", `@date(.T, "full")`, "lunes, 3 de marzo de 2025"},
+		{"es", `@date(.T)`, "3 mar 2025"},
+		{"de", `@date(.T, "long")`, "3. März 2025"},
+		{"pt-BR", `@date(.T, "Mon 02/01")`, "seg 03/03"},
+		{"en", `@timeAgo(.T)`, "7 days ago"},
+		{"es", `@timeAgo(.T)`, "hace 7 días"},
+		{"de", `@timeAgo(.T)`, "vor 7 Tagen"},
+		{"fr", `@timeAgo(.Future)`, "dans 2 heures"},
+		{"en", `@timeAgo(.Now)`, "just now"},
+		{"en", `@number(1234567)|@number(1234.5)|@number(-0.001)|@number(2.5, 0)`, "1,234,567|1,234.50|0.00|2"},
+		{"es", `@number(1234567.891)`, "1.234.567,89"},
+		{"fr", `@number(1234567)`, "1\u202f234\u202f567"},
+		{"en", `@currency(1234.5, "USD")|@currency(-5, "EUR")|@currency(1500, "JPY")|@currency(1, "CHF")`, "$1,234.50|-€5.00|¥1,500|CHF\u00a01.00"},
+		{"es", `@currency(1234.5, "EUR")`, "1.234,50\u00a0€"},
+		{"xx", `@number(1234)`, "1,234"}, // Unknown locales use English
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeTemplate(t, dir, "t.html", tt.tmpl)
+		engine, err := NewEngine(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine.SetLocale(tt.locale)
+		got, err := engine.RenderString("t", map[string]any{
+			"T":      created,
+			"Future": now().Add(2*time.Hour + 10*time.Minute),
+			"Now":    now().Add(-10 * time.Second),
+		})
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.locale, tt.tmpl, err)
+		} else if got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.locale, tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+// The response writer's locale wins over the engine's
+func TestRequestLocale(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "t.html", `@number(1234.5)`)
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if err := engine.Render(localeWriter{w, "es-AR"}, "t", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != "1.234,50" {
+		t.Errorf("Render() = %q, want Spanish separators", got)
+	}
+	if got, _ := engine.RenderString("t", nil); got != "1,234.50" {
+		t.Errorf("RenderString() = %q, want English separators", got)
+	}
+}
+
+type localeWriter struct {
+	*httptest.ResponseRecorder
+	locale string
+}
+
+func (w localeWriter) Locale() string { return w.locale }
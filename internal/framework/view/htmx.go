@@ -0,0 +1,77 @@
+package view
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/AlejandroMBJS/goBastion/internal/framework/htmx"
+)
+
+// RenderBlock renders one block of a page (go:: block "messages") as an HTML
+// fragment, with the page's data. Partials the page includes can be rendered
+// the same way, by their name.
+func (e *Engine) RenderBlock(w http.ResponseWriter, name, block string, data any) error {
+	tmpl, p, err := e.blockInstance(w, name, block)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, block, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", p.locate(err))
+	}
+	return nil
+}
+
+// blockInstance is instance for one block of a page
+func (e *Engine) blockInstance(w http.ResponseWriter, name, block string) (*template.Template, *page, error) {
+	tmpl, p, err := e.instance(name, requestFuncs(w))
+	if err != nil {
+		return nil, nil, err
+	}
+	if tmpl.Lookup(block) == nil {
+		return nil, nil, fmt.Errorf("template %s has no block %q", name, block)
+	}
+	return tmpl, p, nil
+}
+
+// RenderHTMX renders only the block of the page for HTMX requests, and the
+// whole page otherwise, so one handler serves both the first visit and later
+// hx-get swaps:
+//
+//	views.RenderHTMX(w, r, "admin/users_list", "rows", data)
+//
+// Boosted and history-restore requests get the whole page, as HTMX swaps it
+// all in. Serve such routes behind middleware.VaryHTMX so caches don't mix
+// the two up.
+func (e *Engine) RenderHTMX(w http.ResponseWriter, r *http.Request, name, block string, data any) error {
+	if htmx.WantsFragment(r) {
+		return e.RenderBlock(w, name, block, data)
+	}
+	return e.Render(w, name, data)
+}
+
+// RenderOOB renders a block of a page as an out-of-band swap, to update
+// another part of the page along with the main response. swap is an
+// hx-swap-oob strategy and selector, e.g. "innerHTML:#unread-count" or
+// "beforeend:#messages":
+//
+//	views.RenderBlock(w, "chat/room", "message", msg)
+//	views.RenderOOB(w, "chat/room", "stats", "innerHTML:#chat-stats", stats)
+func (e *Engine) RenderOOB(w http.ResponseWriter, name, block, swap string, data any) error {
+	tmpl, p, err := e.blockInstance(w, name, block)
+	if err != nil {
+		return err
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	fmt.Fprintf(w, `<div hx-swap-oob="%s">`, template.HTMLEscapeString(swap))
+	if err := tmpl.ExecuteTemplate(w, block, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", p.locate(err))
+	}
+	_, err = fmt.Fprint(w, "</div>\n")
+	return err
+}
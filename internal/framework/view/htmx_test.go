@@ -0,0 +1,101 @@
+package view
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func htmxEngine(t *testing.T) *Engine {
+	t.Helper()
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layouts/base.gb.html", `<html><body>
+go:: block "content"
+::end
+</body></html>`)
+	writeTemplate(t, dir, "users.gb.html", `go:: extends "layouts/base"
+go:: block "content"
+<h1>Users</h1>
+<ul id="rows">
+go:: block "rows"
+go:: range .Users
+<li>@.</li>
+::end
+::end
+</ul>
+<span id="count">
+go:: block "count"
+@len(.Users)
+::end
+</span>
+::end`)
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestRenderHTMX(t *testing.T) {
+	engine := htmxEngine(t)
+	data := map[string]any{"Users": []string{"ana", "<bob>"}}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		full    bool
+	}{
+		{"browser", nil, true},
+		{"htmx", map[string]string{"HX-Request": "true"}, false},
+		{"boosted", map[string]string{"HX-Request": "true", "HX-Boosted": "true"}, true},
+		{"history restore", map[string]string{"HX-Request": "true", "HX-History-Restore-Request": "true"}, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/users", nil)
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		if err := engine.RenderHTMX(w, r, "users", "rows", data); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		body := w.Body.String()
+		if full := strings.Contains(body, "<html>"); full != tt.full {
+			t.Errorf("%s: full page = %v, want %v:\n%s", tt.name, full, tt.full, body)
+		}
+		if !strings.Contains(body, "<li>ana</li>") || !strings.Contains(body, "<li>&lt;bob&gt;</li>") {
+			t.Errorf("%s: missing escaped rows:\n%s", tt.name, body)
+		}
+		if !tt.full && strings.Contains(body, "<h1>") {
+			t.Errorf("%s: fragment has the rest of the page:\n%s", tt.name, body)
+		}
+	}
+}
+
+func TestRenderOOB(t *testing.T) {
+	engine := htmxEngine(t)
+	data := map[string]any{"Users": []string{"ana", "bob"}}
+
+	w := httptest.NewRecorder()
+	if err := engine.RenderBlock(w, "users", "rows", data); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RenderOOB(w, "users", "count", `innerHTML:#count"`, data); err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	if !strings.HasSuffix(body, "<div hx-swap-oob=\"innerHTML:#count&#34;\">\n2\n</div>\n") {
+		t.Errorf("Unexpected out-of-band swap:\n%s", body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	if err := engine.RenderBlock(httptest.NewRecorder(), "users", "missing", data); err == nil || !strings.Contains(err.Error(), `no block "missing"`) {
+		t.Errorf("RenderBlock(missing) error = %v", err)
+	}
+	w = httptest.NewRecorder()
+	if err := engine.RenderOOB(w, "users", "missing", "innerHTML:#x", data); err == nil || w.Body.Len() > 0 {
+		t.Errorf("RenderOOB(missing) = %v, wrote %q", err, w.Body.String())
+	}
+}
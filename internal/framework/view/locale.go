@@ -0,0 +1,360 @@
+package view
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocaleWriter is implemented by response writers that carry the request's
+// locale, e.g. "es" or "pt-BR". Render formats dates and numbers in it; other
+// renders use the engine's locale (see SetLocale).
+type LocaleWriter interface {
+	Locale() string
+}
+
+// Translator is implemented by response writers that carry the request's
+// message catalog (see middleware.Locale and i18n.Localizer). Render exposes
+// it to templates as @t("key", "name", value...); other renders use the
+// engine's (see SetTranslator).
+type Translator interface {
+	T(key string, args ...any) string
+}
+
+// locale holds what the formatting helpers need to know about a language
+type locale struct {
+	decimal, group string
+	currencyLast   bool // "1.234,50 €" rather than "€1,234.50"
+	months, days   []string
+	layouts        map[string]string // Named date layouts: short, medium, long, full, time, datetime
+
+	// Relative time
+	now, past, future string
+	units             map[string][2]string // Singular and plural
+}
+
+var locales = map[string]*locale{
+	"en": {
+		decimal: ".", group: ",",
+		layouts: map[string]string{
+			"short": "01/02/2006", "medium": "Jan 2, 2006", "long": "January 2, 2006",
+			"full": "Monday, January 2, 2006", "time": "3:04 PM", "datetime": "Jan 2, 2006 3:04 PM",
+		},
+		now: "just now", past: "%s ago", future: "in %s",
+		units: map[string][2]string{
+			"second": {"second", "seconds"}, "minute": {"minute", "minutes"}, "hour": {"hour", "hours"},
+			"day": {"day", "days"}, "month": {"month", "months"}, "year": {"year", "years"},
+		},
+	},
+	"es": {
+		decimal: ",", group: ".", currencyLast: true,
+		months: []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		days:   []string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		layouts: map[string]string{
+			"short": "02/01/2006", "medium": "2 Jan 2006", "long": "2 de January de 2006",
+			"full": "Monday, 2 de January de 2006", "time": "15:04", "datetime": "2 Jan 2006 15:04",
+		},
+		now: "ahora mismo", past: "hace %s", future: "dentro de %s",
+		units: map[string][2]string{
+			"second": {"segundo", "segundos"}, "minute": {"minuto", "minutos"}, "hour": {"hora", "horas"},
+			"day": {"día", "días"}, "month": {"mes", "meses"}, "year": {"año", "años"},
+		},
+	},
+	"pt": {
+		decimal: ",", group: ".", currencyLast: true,
+		months: []string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		days:   []string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		layouts: map[string]string{
+			"short": "02/01/2006", "medium": "2 Jan 2006", "long": "2 de January de 2006",
+			"full": "Monday, 2 de January de 2006", "time": "15:04", "datetime": "2 Jan 2006 15:04",
+		},
+		now: "agora mesmo", past: "há %s", future: "em %s",
+		units: map[string][2]string{
+			"second": {"segundo", "segundos"}, "minute": {"minuto", "minutos"}, "hour": {"hora", "horas"},
+			"day": {"dia", "dias"}, "month": {"mês", "meses"}, "year": {"ano", "anos"},
+		},
+	},
+	"fr": {
+		decimal: ",", group: "\u202f", currencyLast: true,
+		months: []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		days:   []string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		layouts: map[string]string{
+			"short": "02/01/2006", "medium": "2 Jan 2006", "long": "2 January 2006",
+			"full": "Monday 2 January 2006", "time": "15:04", "datetime": "2 Jan 2006 15:04",
+		},
+		now: "à l’instant", past: "il y a %s", future: "dans %s",
+		units: map[string][2]string{
+			"second": {"seconde", "secondes"}, "minute": {"minute", "minutes"}, "hour": {"heure", "heures"},
+			"day": {"jour", "jours"}, "month": {"mois", "mois"}, "year": {"an", "ans"},
+		},
+	},
+	"de": {
+		decimal: ",", group: ".", currencyLast: true,
+		months: []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		days:   []string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		layouts: map[string]string{
+			"short": "02.01.2006", "medium": "2. Jan 2006", "long": "2. January 2006",
+			"full": "Monday, 2. January 2006", "time": "15:04", "datetime": "2. Jan 2006, 15:04",
+		},
+		now: "gerade eben", past: "vor %s", future: "in %s",
+		// Dative, as both "vor" and "in" take it
+		units: map[string][2]string{
+			"second": {"Sekunde", "Sekunden"}, "minute": {"Minute", "Minuten"}, "hour": {"Stunde", "Stunden"},
+			"day": {"Tag", "Tagen"}, "month": {"Monat", "Monaten"}, "year": {"Jahr", "Jahren"},
+		},
+	},
+}
+
+// currencies maps ISO 4217 codes to their symbols; others are written with
+// the code. Decimals are 2 unless listed in zeroDecimalCurrencies.
+var currencies = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥", "MXN": "$", "BRL": "R$", "ARS": "$", "CLP": "$", "COP": "$", "INR": "₹",
+}
+
+var zeroDecimalCurrencies = map[string]bool{"JPY": true, "KRW": true, "CLP": true, "VND": true}
+
+// now is replaced in tests
+var now = time.Now
+
+// findLocale returns the locale for a language tag such as "es-MX", falling
+// back to its language and then to English
+func findLocale(tag string) *locale {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	if l, ok := locales[tag]; ok {
+		return l
+	}
+	lang, _, _ := strings.Cut(tag, "-")
+	if l, ok := locales[lang]; ok {
+		return l
+	}
+	return locales["en"]
+}
+
+// SetLocale sets the locale dates and numbers are formatted in when the
+// response writer doesn't carry one (see LocaleWriter). The default is "en";
+// "es", "pt", "fr" and "de" are built in, and regional tags such as "es-MX"
+// use their language.
+func (e *Engine) SetLocale(tag string) {
+	for name, fn := range localeFuncs(tag) {
+		e.funcs[name] = fn
+	}
+	e.Reset()
+}
+
+// SetTranslator sets the catalog @t translates with when the response writer
+// doesn't carry one (see Translator). Without one, @t returns the key.
+func (e *Engine) SetTranslator(t Translator) {
+	e.funcs["t"] = t.T
+	e.Reset()
+}
+
+// localeFuncs returns the formatting helpers bound to a locale tag, and
+// @locale, the tag itself (for <html lang="@locale">)
+func localeFuncs(tag string) template.FuncMap {
+	l := findLocale(tag)
+	return template.FuncMap{
+		"locale":   func() string { return tag },
+		"date":     l.date,
+		"timeAgo":  l.timeAgo,
+		"number":   l.number,
+		"currency": l.currency,
+	}
+}
+
+// date formats a time.Time (or *time.Time; nil is "") with a named layout
+// (short, medium, long, full, time, datetime; medium by default) or a Go
+// layout. Month and day names are translated.
+func (l *locale) date(t any, layout ...string) (string, error) {
+	var tm time.Time
+	switch v := t.(type) {
+	case time.Time:
+		tm = v
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		tm = *v
+	default:
+		return "", fmt.Errorf("date: expected a time.Time, got %T", t)
+	}
+
+	name := "medium"
+	if len(layout) > 0 {
+		name = layout[0]
+	}
+	goLayout, ok := l.layouts[name]
+	if !ok {
+		goLayout = name
+	}
+	out := tm.Format(goLayout)
+	if l.months == nil {
+		return out, nil
+	}
+
+	// Go writes English names; replace the long forms before the short ones
+	month, day := tm.Month().String(), tm.Weekday().String()
+	if strings.Contains(goLayout, "January") {
+		out = strings.Replace(out, month, l.months[tm.Month()-1], 1)
+	} else if strings.Contains(goLayout, "Jan") {
+		out = strings.Replace(out, month[:3], abbreviate(l.months[tm.Month()-1]), 1)
+	}
+	if strings.Contains(goLayout, "Monday") {
+		out = strings.Replace(out, day, l.days[tm.Weekday()], 1)
+	} else if strings.Contains(goLayout, "Mon") {
+		out = strings.Replace(out, day[:3], abbreviate(l.days[tm.Weekday()]), 1)
+	}
+	return out, nil
+}
+
+// abbreviate shortens a month or day name to its first three letters
+func abbreviate(name string) string {
+	r := []rune(name)
+	if len(r) <= 3 {
+		return name
+	}
+	return string(r[:3])
+}
+
+// timeAgo describes a time relative to now: "5 minutes ago", "in 2 days"
+func (l *locale) timeAgo(t any) (string, error) {
+	var tm time.Time
+	switch v := t.(type) {
+	case time.Time:
+		tm = v
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		tm = *v
+	default:
+		return "", fmt.Errorf("timeAgo: expected a time.Time, got %T", t)
+	}
+
+	d := now().Sub(tm)
+	format := l.past
+	if d < 0 {
+		d, format = -d, l.future
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < 45*time.Second:
+		return l.now, nil
+	case d < time.Hour:
+		n, unit = int(math.Round(d.Minutes())), "minute"
+		if n == 60 {
+			n, unit = 1, "hour"
+		}
+	case d < 24*time.Hour:
+		n, unit = int(math.Round(d.Hours())), "hour"
+		if n == 24 {
+			n, unit = 1, "day"
+		}
+	case d < 30*24*time.Hour:
+		n, unit = int(math.Round(d.Hours()/24)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = max(1, int(d.Hours()/24/30)), "month"
+	default:
+		n, unit = int(d.Hours()/24/365), "year"
+	}
+
+	words := l.units[unit][1]
+	if n == 1 {
+		words = l.units[unit][0]
+	}
+	return fmt.Sprintf(format, fmt.Sprintf("%d %s", n, words)), nil
+}
+
+// number formats a number with the locale's separators. Integers get no
+// decimals and floats 2, unless decimals says otherwise.
+func (l *locale) number(v any, decimals ...int) (string, error) {
+	f, isInt, err := toNumber(v)
+	if err != nil {
+		return "", fmt.Errorf("number: %w", err)
+	}
+	d := 2
+	if isInt {
+		d = 0
+	}
+	if len(decimals) > 0 {
+		d = decimals[0]
+	}
+	return l.format(f, d), nil
+}
+
+// currency formats an amount in the currency with the ISO 4217 code, e.g.
+// "$1,234.50" in English or "1.234,50 €" in Spanish
+func (l *locale) currency(v any, code string) (string, error) {
+	f, _, err := toNumber(v)
+	if err != nil {
+		return "", fmt.Errorf("currency: %w", err)
+	}
+	code = strings.ToUpper(code)
+	d := 2
+	if zeroDecimalCurrencies[code] {
+		d = 0
+	}
+	symbol, ok := currencies[code]
+	if !ok {
+		symbol = code
+	}
+
+	amount := l.format(math.Abs(f), d)
+	sign := ""
+	if f < 0 && amount != l.format(0, d) {
+		sign = "-"
+	}
+	if l.currencyLast {
+		return sign + amount + "\u00a0" + symbol, nil // No line break before the symbol
+	}
+	if !ok {
+		symbol += "\u00a0"
+	}
+	return sign + symbol + amount, nil
+}
+
+// format writes f with d decimals and the locale's separators
+func (l *locale) format(f float64, d int) string {
+	s := strconv.FormatFloat(f, 'f', max(d, 0), 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteRune(digit)
+	}
+	if frac != "" {
+		b.WriteString(l.decimal)
+		b.WriteString(frac)
+	}
+	if strings.Trim(b.String(), "0"+l.group+l.decimal) == "" {
+		sign = "" // No "-0"
+	}
+	return sign + b.String()
+}
+
+// toNumber converts any Go number to a float64, reporting whether it is an
+// integer type
+func toNumber(v any) (float64, bool, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true, nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), false, nil
+	}
+	return 0, false, fmt.Errorf("expected a number, got %T", v)
+}
@@ -0,0 +1,185 @@
+package view
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// markdown renders a safe subset of Markdown, meant for user content:
+// paragraphs, headings, emphasis, inline and fenced code, lists, block quotes,
+// rules and links. Raw HTML is escaped, not rendered, and links only keep
+// http, https, mailto and relative URLs.
+func markdown(src string) template.HTML {
+	// NUL marks the spans inline sets aside, so it can't come from the source
+	src = strings.ReplaceAll(src, "\x00", "")
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	renderBlocks(&b, lines)
+	return template.HTML(b.String())
+}
+
+var (
+	headingRegex = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	ruleRegex    = regexp.MustCompile(`^[ \t]*(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	bulletRegex  = regexp.MustCompile(`^[ \t]*[-*+][ \t]+(.*)$`)
+	orderedRegex = regexp.MustCompile(`^[ \t]*\d{1,9}[.)][ \t]+(.*)$`)
+	fenceRegex   = regexp.MustCompile("^[ \t]*(```|~~~)[ \t]*([A-Za-z0-9_+-]*)")
+
+	codeSpanRegex = regexp.MustCompile("`([^`]+)`")
+	linkRegex     = regexp.MustCompile(`\[([^\]]+)\]\(([^()\s]+)\)`)
+	autolinkRegex = regexp.MustCompile(`&lt;((?:https?://|mailto:)[^\s&]+)&gt;`)
+	strongRegex   = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	emRegex       = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*|\b_(\S(?:[^_]*?\S)?)_\b`)
+)
+
+func renderBlocks(b *strings.Builder, lines []string) {
+	var para, quote []string
+	var items []string
+	listTag := ""
+
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + inline(strings.Join(para, "\n")) + "</p>\n")
+			para = nil
+		}
+		if len(quote) > 0 {
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quote)
+			b.WriteString("</blockquote>\n")
+			quote = nil
+		}
+		if len(items) > 0 {
+			b.WriteString("<" + listTag + ">\n")
+			for _, item := range items {
+				b.WriteString("<li>" + inline(item) + "</li>\n")
+			}
+			b.WriteString("</" + listTag + ">\n")
+			items, listTag = nil, ""
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := fenceRegex.FindStringSubmatch(line); m != nil {
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if m[2] != "" {
+				class = fmt.Sprintf(` class="language-%s"`, m[2])
+			}
+			b.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(strings.TrimLeft(line, " \t"), ">"); ok {
+			if len(quote) == 0 {
+				flush()
+			}
+			quote = append(quote, strings.TrimPrefix(rest, " "))
+			continue
+		}
+		if len(quote) > 0 {
+			flush()
+		}
+
+		if m := headingRegex.FindStringSubmatch(line); m != nil {
+			flush()
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", len(m[1]), inline(m[2]), len(m[1]))
+			continue
+		}
+		if ruleRegex.MatchString(line) {
+			flush()
+			b.WriteString("<hr>\n")
+			continue
+		}
+
+		tag, item := "", ""
+		if m := bulletRegex.FindStringSubmatch(line); m != nil {
+			tag, item = "ul", m[1]
+		} else if m := orderedRegex.FindStringSubmatch(line); m != nil {
+			tag, item = "ol", m[1]
+		}
+		if tag != "" {
+			if tag != listTag {
+				flush()
+				listTag = tag
+			}
+			items = append(items, item)
+			continue
+		}
+
+		// An indented line continues the list item above it
+		if len(items) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			items[len(items)-1] += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		if len(items) > 0 {
+			flush()
+		}
+		para = append(para, line)
+	}
+	flush()
+}
+
+// inline escapes text and renders its inline markup. Code spans and links are
+// set aside first, so emphasis markers inside them are left alone.
+func inline(text string) string {
+	var held []string
+	hold := func(s string) string {
+		held = append(held, s)
+		return fmt.Sprintf("\x00%d\x00", len(held)-1)
+	}
+
+	text = codeSpanRegex.ReplaceAllStringFunc(text, func(m string) string {
+		return hold("<code>" + html.EscapeString(m[1:len(m)-1]) + "</code>")
+	})
+	text = html.EscapeString(text)
+	text = linkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := linkRegex.FindStringSubmatch(m)
+		if !safeURL(html.UnescapeString(parts[2])) {
+			return parts[1]
+		}
+		return hold(`<a href="`+parts[2]+`" rel="nofollow">`) + parts[1] + hold("</a>")
+	})
+	text = autolinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		link := autolinkRegex.FindStringSubmatch(m)[1]
+		return hold(`<a href="` + link + `" rel="nofollow">` + link + `</a>`)
+	})
+
+	text = strongRegex.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = emRegex.ReplaceAllString(text, "<em>$1$2</em>")
+
+	// Two trailing spaces break the line
+	text = strings.ReplaceAll(text, "  \n", "<br>\n")
+
+	for i := len(held) - 1; i >= 0; i-- {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), held[i], 1)
+	}
+	return text
+}
+
+// safeURL reports whether a link target can't run script: relative URLs and
+// the http, https and mailto schemes
+func safeURL(u string) bool {
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true
+	}
+	switch strings.ToLower(u[:i]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
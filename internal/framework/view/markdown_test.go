@@ -0,0 +1,44 @@
+package view
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Hello **bold** and *em* and _em_", "<p>Hello <strong>bold</strong> and <em>em</em> and <em>em</em></p>"},
+		{"# Title\n## Sub ##", "<h1>Title</h1>\n<h2>Sub</h2>"},
+		{"one\ntwo\n\nthree", "<p>one\ntwo</p>\n<p>three</p>"},
+		{"line  \nbreak", "<p>line<br>\nbreak</p>"},
+		{"- a\n- b\n  more\n\n1. x\n2) y", "<ul>\n<li>a</li>\n<li>b\nmore</li>\n</ul>\n<ol>\n<li>x</li>\n<li>y</li>\n</ol>"},
+		{"> quoted\n> **text**\n\nafter", "<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>\n<p>after</p>"},
+		{"---", "<hr>"},
+		{"```go\nif a < b {\n```", "<pre><code class=\"language-go\">if a &lt; b {</code></pre>"},
+		{"Use `a*b*c` and `<b>`", "<p>Use <code>a*b*c</code> and <code>&lt;b&gt;</code></p>"},
+		{"snake_case_name", "<p>snake_case_name</p>"},
+
+		// Links keep safe targets only
+		{"[docs](https://go.dev/doc?a=1&b=2)", `<p><a href="https://go.dev/doc?a=1&amp;b=2" rel="nofollow">docs</a></p>`},
+		{"[home](/about_us_page)", `<p><a href="/about_us_page" rel="nofollow">home</a></p>`},
+		{"[*x*](JavaScript:alert(1))", "<p>[<em>x</em>](JavaScript:alert(1))</p>"},
+		{"[x](JavaScript:void)", "<p>x</p>"},
+		{"[x](javascript:alert)", "<p>x</p>"},
+		{"<https://go.dev>", `<p><a href="https://go.dev" rel="nofollow">https://go.dev</a></p>`},
+
+		// Raw HTML is text
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{`<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>"},
+		{"a\x000\x00b", "<p>a0b</p>"},
+	}
+
+	for _, tt := range tests {
+		got := strings.TrimSpace(string(markdown(tt.input)))
+		if got != tt.want {
+			t.Errorf("markdown(%q) =\n%s\nwant\n%s", tt.input, got, tt.want)
+		}
+	}
+}
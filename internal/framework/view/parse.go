@@ -0,0 +1,717 @@
+package view
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The goBastion syntax is translated to Go template syntax line by line: line
+// N of the result is line N of the source, so errors reported by html/template
+// point at the right line, and columns are mapped back through the spans
+// recorded for each line.
+//
+// Echo expressions:
+//
+//	@.User.Name            {{ .User.Name }}
+//	@upper(.Name)          {{ upper .Name }}
+//	@.Time.Format("15:04") {{ .Time.Format "15:04" }}
+//	@.Items[0].Name        {{ (index .Items 0).Name }}
+//	@.Name[:1]             {{ slice .Name 0 1 }}
+//	@(.Price | printf "%.2f")  {{ .Price | printf "%.2f" }}
+//	@@ and admin@example.com   are literal text
+//
+// go:: statements and legacy <? ?> tags are Go template actions whose operands
+// may use the same call, index and slice forms.
+//
+// The content of a go:: component block is moved out of its template into
+// templates of its own, one per slot (see component.go), padded so their
+// lines keep the source's numbers.
+
+var (
+	// go:: <statement> and ::end lines
+	goBlockRegex  = regexp.MustCompile(`^([ \t]*)go::\s*(.+?)[ \t]*$`)
+	endBlockRegex = regexp.MustCompile(`^[ \t]*::end[ \t]*$`)
+
+	// directiveRegex matches the statements that name another template:
+	// go:: extends "layouts/base", go:: include "partials/nav" [pipeline],
+	// go:: block "content" [pipeline], go:: component "Card" [pipeline] and
+	// go:: slot "footer"
+	directiveRegex = regexp.MustCompile(`^(extends|include|block|component|slot)\s+("(?:[^"\\]|\\.)*")\s*(.*)$`)
+
+	// goErrorRegex matches the location in html/template errors:
+	// "template: NAME:LINE: ..." or "html/template:NAME:LINE:COL: ..."
+	goErrorRegex = regexp.MustCompile(`^(?:html/)?template: ?([^:\s]+):(\d+)(?::(\d+))?: ((?s).*)$`)
+)
+
+// TemplateError is a syntax or execution error located in a template's source
+type TemplateError struct {
+	File string
+	Line int
+	Col  int // 0 when unknown
+	Msg  string
+	Err  error // The html/template error it was translated from, if any
+}
+
+func (e *TemplateError) Error() string {
+	if e.Col > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// translation is a template translated to Go template syntax
+type translation struct {
+	text       string
+	spans      [][]span // Per line, where each output segment came from
+	extends    string
+	includes   []string
+	components []string   // Names of the components used in go:: component blocks
+	slots      []*slotDef // Contents of the go:: component blocks
+}
+
+// span maps output from column out of a line to column in of the source.
+// Literal text maps one to one; an action maps to where its source starts.
+type span struct {
+	out, in int
+	literal bool
+}
+
+// col maps a 1-based column of line in the translated text to the source
+func (t *translation) col(line, col int) int {
+	if line < 1 || line > len(t.spans) {
+		return col
+	}
+	in := col
+	for _, s := range t.spans[line-1] {
+		if s.out > col-1 {
+			break
+		}
+		in = s.in + 1
+		if s.literal {
+			in += col - 1 - s.out
+		}
+	}
+	return in
+}
+
+// block is a go:: statement waiting for its ::end
+type block struct {
+	keyword   string
+	line, col int
+	slot      *slotDef // Where the lines of a component or slot block go
+}
+
+// slotDef is the content of a go:: component block (its default slot) or of
+// a go:: slot inside one. Each is parsed as a template of its own, named
+// after the template and the block's line: "admin/dashboard#12" and
+// "admin/dashboard#12.footer".
+type slotDef struct {
+	name  string
+	first int            // Line of the first content
+	lines map[int]string // Translated lines, by line number
+}
+
+// text returns the slot's template text. It starts with a comment spanning
+// the lines before the content, so html/template reports the source's lines.
+func (s *slotDef) text() string {
+	last := s.first - 1
+	for n := range s.lines {
+		last = max(last, n)
+	}
+	var b strings.Builder
+	b.WriteString("{{/*" + strings.Repeat("\n", s.first-2) + "*/}}")
+	for n := s.first; n <= last; n++ {
+		b.WriteString("\n")
+		b.WriteString(s.lines[n])
+	}
+	return b.String()
+}
+
+// blank reports whether the slot has no content, so it needn't be parsed
+func (s *slotDef) blank() bool {
+	for _, line := range s.lines {
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// translator converts a template source, one line at a time
+type translator struct {
+	name   string // Template name, to name the slots of go:: component blocks
+	file   string
+	result translation
+	blocks []block
+
+	line int             // Current line, 1-based
+	out  strings.Builder // Current line's output
+	sp   []span
+}
+
+// translate converts the goBastion syntax in content, the template name, to
+// Go template syntax. file is only used in error messages.
+func translate(name, file, content string) (*translation, error) {
+	t := &translator{name: name, file: file}
+	lines := strings.Split(content, "\n")
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		t.line = i + 1
+		t.out.Reset()
+		t.sp = nil
+		slot := t.slot() // Before the line, as it may open or close one
+		if err := t.translateLine(line); err != nil {
+			return nil, err
+		}
+		if slot != nil {
+			slot.lines[t.line] = t.out.String()
+		} else {
+			out[i] = t.out.String()
+		}
+		t.result.spans = append(t.result.spans, t.sp)
+	}
+	if len(t.blocks) > 0 {
+		b := t.blocks[len(t.blocks)-1]
+		return nil, t.errorAt(b.line, b.col, fmt.Sprintf("go:: %s is never closed (missing ::end)", b.keyword))
+	}
+	t.result.text = strings.Join(out, "\n")
+	return &t.result, nil
+}
+
+func (t *translator) errorAt(line, col int, msg string) error {
+	return &TemplateError{File: t.file, Line: line, Col: col, Msg: msg}
+}
+
+// emit writes an action translated from source column in (0-based)
+func (t *translator) emit(action string, in int) {
+	t.sp = append(t.sp, span{out: t.out.Len(), in: in})
+	t.out.WriteString(action)
+}
+
+// text writes literal source text starting at column in (0-based)
+func (t *translator) text(s string, in int) {
+	if s == "" {
+		return
+	}
+	if n := len(t.sp); n == 0 || !t.sp[n-1].literal || t.sp[n-1].in+(t.out.Len()-t.sp[n-1].out) != in {
+		t.sp = append(t.sp, span{out: t.out.Len(), in: in, literal: true})
+	}
+	t.out.WriteString(s)
+}
+
+func (t *translator) translateLine(line string) error {
+	body := strings.TrimSuffix(line, "\r")
+	cr := line[len(body):]
+
+	if m := goBlockRegex.FindStringSubmatchIndex(body); m != nil {
+		stmt := body[m[4]:m[5]]
+		action, err := t.statement(stmt, m[4], m[3])
+		if err != nil {
+			return err
+		}
+		t.emit(action, m[3])
+		t.text(cr, len(body))
+		return nil
+	}
+
+	if endBlockRegex.MatchString(body) {
+		col := strings.Index(body, "::end")
+		b, err := t.close("::end", col)
+		if err != nil {
+			return err
+		}
+		if b.slot == nil {
+			t.emit("{{ end }}", col)
+		}
+		t.text(cr, len(body))
+		return nil
+	}
+
+	return t.translateText(line)
+}
+
+// statement translates the go:: statement stmt, found at column col of a line
+// whose go:: is at column at
+func (t *translator) statement(stmt string, col, at int) (string, error) {
+	if d := directiveRegex.FindStringSubmatchIndex(stmt); d != nil {
+		keyword, quoted := stmt[d[2]:d[3]], stmt[d[4]:d[5]]
+		name, err := strconv.Unquote(quoted)
+		if err != nil {
+			return "", t.errorAt(t.line, col+d[4]+1, "invalid template name "+quoted)
+		}
+		if keyword == "slot" {
+			return "", t.openNamedSlot(name, stmt[d[6]:d[7]], at)
+		}
+		pipeline, err := t.pipeline(stmt[d[6]:d[7]], col+d[6])
+		if err != nil {
+			return "", err
+		}
+		if pipeline == "" {
+			pipeline = "."
+		}
+		switch keyword {
+		case "extends":
+			if t.result.extends != "" {
+				return "", t.errorAt(t.line, at+1, "go:: extends used more than once")
+			}
+			t.result.extends = name
+			return "", nil // Resolved by compile
+		case "include":
+			t.result.includes = append(t.result.includes, name)
+			return "{{ template " + quoted + " " + pipeline + " }}", nil
+		case "component":
+			// The body is the default slot, rendered with the dot it's at
+			id := fmt.Sprintf("%s#%d", t.name, t.line)
+			t.result.components = append(t.result.components, name)
+			t.openSlot("component", at, id)
+			if strings.ContainsAny(pipeline, " |") {
+				pipeline = "(" + pipeline + ")" // More arguments follow
+			}
+			return "{{ component " + quoted + " " + pipeline + " (slots " + strconv.Quote(id) + " .) }}", nil
+		default:
+			t.open("block", at)
+			return "{{ block " + quoted + " " + pipeline + " }}", nil
+		}
+	}
+
+	translated, err := t.pipeline(stmt, col)
+	if err != nil {
+		return "", err
+	}
+	if err := t.track(stmt, at); err != nil {
+		return "", err
+	}
+	return "{{ " + translated + " }}", nil
+}
+
+// track follows the blocks opened and closed by a statement
+func (t *translator) track(stmt string, col int) error {
+	keyword, _, _ := strings.Cut(stmt, " ")
+	switch keyword {
+	case "if", "range", "with", "block", "define":
+		t.open(keyword, col)
+	case "end":
+		b, err := t.close("end", col)
+		if err == nil && b.slot != nil {
+			err = t.errorAt(t.line, col+1, "go:: "+b.keyword+" must be closed with ::end")
+		}
+		return err
+	}
+	return nil
+}
+
+func (t *translator) open(keyword string, col int) {
+	t.blocks = append(t.blocks, block{keyword: keyword, line: t.line, col: col + 1})
+}
+
+// openSlot opens a component or slot block, whose lines go to a new slot
+func (t *translator) openSlot(keyword string, col int, name string) {
+	s := &slotDef{name: name, first: t.line + 1, lines: make(map[int]string)}
+	t.result.slots = append(t.result.slots, s)
+	t.blocks = append(t.blocks, block{keyword: keyword, line: t.line, col: col + 1, slot: s})
+}
+
+// openNamedSlot opens go:: slot "name", which must be directly inside a
+// go:: component block
+func (t *translator) openNamedSlot(name, rest string, col int) error {
+	n := len(t.blocks)
+	if n == 0 || t.blocks[n-1].keyword != "component" {
+		return t.errorAt(t.line, col+1, "go:: slot must be directly inside a go:: component block")
+	}
+	if strings.TrimSpace(rest) != "" {
+		return t.errorAt(t.line, col+1, "go:: slot takes only a name")
+	}
+	id := t.blocks[n-1].slot.name + "." + name
+	for _, s := range t.result.slots {
+		if s.name == id {
+			return t.errorAt(t.line, col+1, fmt.Sprintf("slot %q is already defined", name))
+		}
+	}
+	t.openSlot("slot", col, id)
+	return nil
+}
+
+// slot returns the innermost open slot, or nil outside component blocks
+func (t *translator) slot() *slotDef {
+	for i := len(t.blocks) - 1; i >= 0; i-- {
+		if t.blocks[i].slot != nil {
+			return t.blocks[i].slot
+		}
+	}
+	return nil
+}
+
+func (t *translator) close(what string, col int) (block, error) {
+	if len(t.blocks) == 0 {
+		return block{}, t.errorAt(t.line, col+1, what+" without a matching go:: block")
+	}
+	b := t.blocks[len(t.blocks)-1]
+	t.blocks = t.blocks[:len(t.blocks)-1]
+	return b, nil
+}
+
+// translateText translates the @ expressions and legacy tags in a line of text
+func (t *translator) translateText(line string) error {
+	start := 0 // Start of the pending literal text
+	flush := func(end int) {
+		t.text(line[start:end], start)
+	}
+
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == '@':
+			action, n, err := t.echo(line, i)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				i++
+				continue
+			}
+			flush(i)
+			if action == "@" {
+				t.text("@", i) // @@ is a literal @
+			} else {
+				t.emit(action, i)
+			}
+			i += n
+			start = i
+
+		case strings.HasPrefix(line[i:], "<?"):
+			// Legacy PHP-style tags are kept for backward compatibility:
+			// <?= expr ?> echoes, <? stmt ?> is a logic tag. A space is
+			// required after "<?" so XML declarations like <?xml ...?> are
+			// left untouched.
+			end := strings.Index(line[i+2:], "?>")
+			if end < 0 {
+				i++
+				continue
+			}
+			inner, offset := line[i+2:i+2+end], i+2
+			isEcho := strings.HasPrefix(inner, "=")
+			if isEcho {
+				inner, offset = inner[1:], offset+1
+			} else if inner == "" || (inner[0] != ' ' && inner[0] != '\t') {
+				i++
+				continue
+			}
+			trimmed := strings.TrimSpace(inner)
+			if trimmed == "" {
+				i++
+				continue
+			}
+			offset += strings.Index(inner, trimmed)
+			translated, err := t.pipeline(trimmed, offset)
+			if err != nil {
+				return err
+			}
+			if !isEcho {
+				if err := t.track(trimmed, i); err != nil {
+					return err
+				}
+			}
+			flush(i)
+			t.emit("{{ "+translated+" }}", i)
+			i += end + 4
+			start = i
+
+		default:
+			i++
+		}
+	}
+	flush(len(line))
+	return nil
+}
+
+// echo translates the @ construct at line[at]. It returns the action (or "@"
+// for an escaped @) and the number of bytes it spans; 0 means the @ is text.
+func (t *translator) echo(line string, at int) (string, int, error) {
+	rest := line[at+1:]
+	switch {
+	case rest == "":
+		return "", 0, nil
+	case rest[0] == '@':
+		return "@", 2, nil
+	case at > 0 && (isIdentChar(line[at-1]) || line[at-1] >= 0x80):
+		return "", 0, nil // user@example.com, htmx.org@1.9.10
+	case rest[0] == '(':
+		x := &exprParser{t: t, src: rest, base: at + 1}
+		x.pos++
+		inner := x.pipe(")")
+		if x.err == nil && !x.eat(')') {
+			x.fail("missing ) after @(")
+		}
+		if x.err != nil {
+			return "", 0, x.err
+		}
+		return "{{ " + strings.TrimSpace(inner) + " }}", x.pos + 1, nil
+	case rest[0] == '.' || rest[0] == '$' || isLetter(rest[0]):
+		x := &exprParser{t: t, src: rest, base: at + 1}
+		op := x.operand()
+		if x.err != nil {
+			return "", 0, x.err
+		}
+		return "{{ " + op.bare() + " }}", x.pos + 1, nil
+	}
+	return "", 0, nil
+}
+
+// pipeline translates the operands of a Go template pipeline found at column col
+func (t *translator) pipeline(src string, col int) (string, error) {
+	x := &exprParser{t: t, src: src, base: col}
+	out := x.pipe("")
+	if x.err == nil && x.pos < len(src) {
+		x.fail(fmt.Sprintf("unexpected %q", src[x.pos]))
+	}
+	return out, x.err
+}
+
+// exprParser translates the call (f(x)), index (a[i]) and slice (a[i:j])
+// forms of an expression into Go template syntax; everything else is copied
+type exprParser struct {
+	t    *translator
+	src  string
+	pos  int
+	base int // Column of src[0] in the line
+	err  error
+}
+
+// operand is a translated operand. A call, index or slice is wrapped in
+// parentheses, which bare removes where they aren't needed.
+type operand struct {
+	text    string
+	wrapped bool
+}
+
+func (o operand) bare() string {
+	if o.wrapped {
+		return o.text[1 : len(o.text)-1]
+	}
+	return o.text
+}
+
+func (x *exprParser) fail(msg string) {
+	if x.err == nil {
+		x.err = x.t.errorAt(x.t.line, x.base+x.pos+1, msg)
+	}
+}
+
+func (x *exprParser) peek() byte {
+	if x.pos < len(x.src) {
+		return x.src[x.pos]
+	}
+	return 0
+}
+
+func (x *exprParser) eat(c byte) bool {
+	if x.peek() == c && c != 0 {
+		x.pos++
+		return true
+	}
+	return false
+}
+
+// seq translates until one of stops (or the end) at nesting depth 0. It
+// returns the translation and the number of top-level items in it, so a
+// caller can tell a single operand from a command.
+func (x *exprParser) seq(stops string) (string, int) {
+	text, items, _ := x.items(stops)
+	return text, items
+}
+
+// pipe is seq for a whole pipeline, where a lone call, index or slice needs
+// no parentheses: @(f(x)) is {{ f x }}
+func (x *exprParser) pipe(stops string) string {
+	text, items, last := x.items(stops)
+	if items == 1 && last.wrapped && strings.TrimSpace(text) == last.text {
+		return last.bare()
+	}
+	return text
+}
+
+func (x *exprParser) items(stops string) (string, int, operand) {
+	var b strings.Builder
+	items := 0
+	var last operand
+	for x.err == nil && x.pos < len(x.src) {
+		c := x.peek()
+		switch {
+		case strings.IndexByte(stops, c) >= 0:
+			return b.String(), items, last
+		case c == ' ' || c == '\t':
+			b.WriteByte(c)
+			x.pos++
+		case c == ')' || c == ']':
+			x.fail(fmt.Sprintf("unexpected %q", c))
+		case isOperandStart(c):
+			last = x.operand()
+			b.WriteString(last.text)
+			items++
+		default:
+			b.WriteByte(c) // Operators and punctuation: | := = , ...
+			x.pos++
+			items++
+		}
+	}
+	return b.String(), items, last
+}
+
+// operand translates one operand and its call, index, slice and field suffixes
+func (x *exprParser) operand() operand {
+	var op operand
+	switch c := x.peek(); {
+	case c == '(':
+		x.pos++
+		inner, _ := x.seq(")")
+		if !x.eat(')') {
+			x.fail("missing )")
+		}
+		op.text = "(" + inner + ")"
+	case c == '"' || c == '`' || c == '\'':
+		op.text = x.quoted()
+	case isDigit(c):
+		op.text = x.scan(func(c byte) bool { return isIdentChar(c) || c == '.' })
+	default:
+		op.text = x.name()
+	}
+
+	for x.err == nil {
+		switch x.peek() {
+		case '(':
+			args := x.list('(', ')', ",")
+			op = operand{text: "(" + strings.Join(append([]string{op.text}, args...), " ") + ")", wrapped: true}
+		case '[':
+			start := x.pos
+			parts := x.list('[', ']', ":")
+			switch {
+			case len(parts) == 1 && parts[0] != "":
+				op = operand{text: "(index " + op.text + " " + parts[0] + ")", wrapped: true}
+			case len(parts) == 2 || (len(parts) == 3 && parts[1] != "" && parts[2] != ""):
+				// a[i:j:k] is slice a i j k, with i defaulting to 0
+				args := []string{"slice", op.text}
+				if parts[0] != "" || parts[1] != "" {
+					args = append(args, cmp.Or(parts[0], "0"))
+				}
+				for _, part := range parts[1:] {
+					if part != "" {
+						args = append(args, part)
+					}
+				}
+				op = operand{text: "(" + strings.Join(args, " ") + ")", wrapped: true}
+			default:
+				x.pos = start
+				x.fail("invalid index or slice expression")
+			}
+		case '.':
+			if x.pos+1 >= len(x.src) || !isLetter(x.src[x.pos+1]) {
+				return op // A dot that ends the sentence
+			}
+			op = operand{text: op.text + x.name()}
+		default:
+			return op
+		}
+	}
+	return op
+}
+
+// list parses a bracketed list like (a, b) or [i:j] into its translated items
+func (x *exprParser) list(open, close byte, sep string) []string {
+	start := x.pos
+	x.pos++ // open
+	var items []string
+	for {
+		item, n := x.seq(sep + string(close))
+		item = strings.TrimSpace(item)
+		if n > 1 {
+			item = "(" + item + ")"
+		}
+		items = append(items, item)
+		if x.err != nil {
+			return nil
+		}
+		if x.eat(close) {
+			break
+		}
+		if !x.eat(sep[0]) {
+			x.pos = start
+			x.fail(fmt.Sprintf("missing %c", close))
+			return nil
+		}
+	}
+	if open == '(' && len(items) == 1 && items[0] == "" {
+		return nil // f()
+	}
+	for _, item := range items {
+		if item == "" && open == '(' {
+			x.fail("empty argument")
+		}
+	}
+	return items
+}
+
+// name scans a field chain (.A.B), a variable ($x.A) or an identifier (fn.A)
+func (x *exprParser) name() string {
+	start := x.pos
+	if c := x.peek(); c == '$' || c == '.' {
+		x.pos++
+	}
+	for {
+		x.scan(isIdentChar)
+		if x.peek() != '.' || x.pos+1 >= len(x.src) || !isLetter(x.src[x.pos+1]) {
+			break
+		}
+		x.pos++
+	}
+	return x.src[start:x.pos]
+}
+
+// quoted scans a string, raw string or character literal
+func (x *exprParser) quoted() string {
+	start := x.pos
+	q := x.src[x.pos]
+	for x.pos++; x.pos < len(x.src); x.pos++ {
+		switch x.src[x.pos] {
+		case '\\':
+			if q != '`' {
+				x.pos++
+			}
+		case q:
+			x.pos++
+			return x.src[start:x.pos]
+		}
+	}
+	x.pos = start
+	x.fail("unterminated " + map[byte]string{'"': "string", '`': "raw string", '\'': "character"}[q])
+	return ""
+}
+
+func (x *exprParser) scan(ok func(byte) bool) string {
+	start := x.pos
+	for x.pos < len(x.src) && ok(x.src[x.pos]) {
+		x.pos++
+	}
+	return x.src[start:x.pos]
+}
+
+func isOperandStart(c byte) bool {
+	return c == '.' || c == '$' || c == '(' || c == '"' || c == '`' || c == '\'' || isLetter(c) || isDigit(c)
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isLetter(c) || isDigit(c)
+}
@@ -0,0 +1,101 @@
+package view
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Escapes and text that only looks like an expression
+		{"Mail admin@example.com", "Mail admin@example.com"},
+		{"htmx.org@1.9.10", "htmx.org@1.9.10"},
+		{"@@keyframes fade", "@keyframes fade"},
+		{"@ home, @1, @-", "@ home, @1, @-"},
+		{"Hello @.Name.", "Hello {{ .Name }}."},
+		{"<li>@.</li>", "<li>{{ . }}</li>"},
+		{"@$user.Name", "{{ $user.Name }}"},
+
+		// Calls, indexes and slices
+		{"@upper(.Name)", "{{ upper .Name }}"},
+		{"@csrfField()", "{{ csrfField }}"},
+		{`@.Timestamp.Format("15:04")`, `{{ .Timestamp.Format "15:04" }}`},
+		{`@printf("%s, %s", .A, upper(.B))`, `{{ printf "%s, %s" .A (upper .B) }}`},
+		{"@.Items[0].Name", "{{ (index .Items 0).Name }}"},
+		{`@.Meta["a:b"]`, `{{ index .Meta "a:b" }}`},
+		{"@.User[:1]", "{{ slice .User 0 1 }}"},
+		{"@.User[1:]", "{{ slice .User 1 }}"},
+		{"@.User[i:j:k]", "{{ slice .User i j k }}"},
+		{"@len(.Items[1:])", "{{ len (slice .Items 1) }}"},
+
+		// Explicit expressions take a whole pipeline
+		{`@(.Price | printf "%.2f") EUR`, `{{ .Price | printf "%.2f" }} EUR`},
+		{`@(index .Map "x)")`, `{{ index .Map "x)" }}`},
+
+		// go:: statements and legacy tags get the same operand forms
+		{"go:: if eq(.Role, \"admin\")\nx\n::end", "{{ if (eq .Role \"admin\") }}\nx\n{{ end }}"},
+		{"go:: range $i, $u := .Users[1:]\n::end", "{{ range $i, $u := (slice .Users 1) }}\n{{ end }}"},
+		{`<?= upper(.Name) ?>`, `{{ upper .Name }}`},
+		{`<?xml version="1.0"?>`, `<?xml version="1.0"?>`},
+	}
+
+	for _, tt := range tests {
+		got, err := translate("t", "t.html", tt.input)
+		if err != nil {
+			t.Errorf("translate(%q) error = %v", tt.input, err)
+		} else if got.text != tt.expected {
+			t.Errorf("translate(%q) = %q, want %q", tt.input, got.text, tt.expected)
+		}
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"<p>\n  @upper(.Name</p>", "t.html:2:9: missing )"},
+		{`x @printf("%s) y`, `t.html:1:11: unterminated string`},
+		{"@.Items[]", "t.html:1:8: invalid index or slice expression"},
+		{"a\n  go:: if .X\n", "t.html:2:3: go:: if is never closed (missing ::end)"},
+		{"::end", "t.html:1:1: ::end without a matching go:: block"},
+		{"go:: extends \"a\"\ngo:: extends \"b\"", "t.html:2:1: go:: extends used more than once"},
+	}
+
+	for _, tt := range tests {
+		_, err := translate("t", "t.html", tt.input)
+		var terr *TemplateError
+		if !errors.As(err, &terr) || err.Error() != tt.want {
+			t.Errorf("translate(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+// Errors from html/template point at the source file, line and column
+func TestTemplateErrorLocation(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "parse.html", "<p>\n@nope(.X)\n</p>")
+	writeTemplate(t, dir, "exec.html", "<p>\n  Hi @.User.Name, @.Count</p>")
+
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = engine.RenderString("parse", nil)
+	var terr *TemplateError
+	if !errors.As(err, &terr) || terr.File != filepath.Join(dir, "parse.html") || terr.Line != 2 || !strings.Contains(terr.Msg, `function "nope" not defined`) {
+		t.Errorf("Parse error = %v", err)
+	}
+
+	// .User is a string, so .Name fails at "@.User.Name" in column 6
+	_, err = engine.RenderString("exec", map[string]any{"User": "ana"})
+	if !errors.As(err, &terr) || terr.Line != 2 || terr.Col != 6 {
+		t.Errorf("Execution error = %v, want line 2, column 6", err)
+	}
+}
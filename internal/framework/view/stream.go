@@ -0,0 +1,76 @@
+package view
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Stream renders like Render, but sends the page in parts: everything before
+// a go:: flush goes to the client as soon as the flush is reached. The base
+// layout flushes after </head>, so the browser loads styles and scripts and
+// draws the layout while the handler's slow queries finish. Start them with
+// Async and let the template Wait where it needs them:
+//
+//	stats := view.Async(func() (Stats, error) { return loadStats(ctx) })
+//	err := views.Stream(w, "admin/dashboard", map[string]any{"Stats": stats})
+//
+//	go:: with .Stats.Wait
+//	  @.Total users
+//	::end
+//
+// Once something is flushed the status is sent, so a later error can't turn
+// into an error page: the response ends where it failed. Log the error
+// instead of writing another response. Writers that can't flush, like
+// httptest.ResponseRecorder, get the whole page at the end.
+func (e *Engine) Stream(w http.ResponseWriter, name string, data any) error {
+	funcs := requestFuncs(w)
+	rc := http.NewResponseController(w)
+	funcs["flush"] = func() (string, error) {
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return "", err
+		}
+		return "", nil
+	}
+
+	tmpl, p, err := e.instance(name, funcs)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", p.locate(err))
+	}
+	return nil
+}
+
+// Future is a value loaded in the background, for pages rendered with Stream
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Async runs fn in a new goroutine and returns its Future. A panic in fn
+// becomes the Future's error.
+func Async[T any](fn func() (T, error)) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = fmt.Errorf("view: Async function panicked: %v", r)
+			}
+		}()
+		f.value, f.err = fn()
+	}()
+	return f
+}
+
+// Wait waits for fn to return and returns its result. A template calling it
+// (.Stats.Wait) stops with the error, if any.
+func (f *Future[T]) Wait() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
@@ -0,0 +1,76 @@
+package view
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// flushRecorder records what had been written at each Flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+	onFlush func()
+}
+
+func (w *flushRecorder) Flush() {
+	w.flushed = append(w.flushed, w.Body.String())
+	if w.onFlush != nil {
+		w.onFlush()
+	}
+}
+
+func TestStream(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "page.gb.html", `<head><title>Stats</title></head>
+go:: flush
+<body>
+go:: with .Stats.Wait
+<p>@.</p>
+::end
+</body>`)
+	engine, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	stats := Async(func() (int, error) {
+		<-release
+		return 42, nil
+	})
+
+	// The head is sent before the stats are ready, which they only are once
+	// it was flushed
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), onFlush: func() { close(release) }}
+	if err := engine.Stream(w, "page", map[string]any{"Stats": stats}); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.flushed) != 1 || !strings.HasSuffix(strings.TrimSpace(w.flushed[0]), "</head>") || strings.Contains(w.flushed[0], "<body>") {
+		t.Errorf("Flushed %q, want the head only", w.flushed)
+	}
+	if got := w.Body.String(); !strings.Contains(got, "<p>42</p>") {
+		t.Errorf("Stream() = %q", got)
+	}
+
+	// Render ignores go:: flush
+	if got, err := engine.RenderString("page", map[string]any{"Stats": Async(func() (int, error) { return 1, nil })}); err != nil || !strings.Contains(got, "<p>1</p>") {
+		t.Errorf("RenderString() = %q, %v", got, err)
+	}
+
+	// A failed value stops the page where it's used
+	failed := Async(func() (int, error) { return 0, errors.New("database is down") })
+	w = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	err = engine.Stream(w, "page", map[string]any{"Stats": failed})
+	if err == nil || !strings.Contains(err.Error(), "database is down") || len(w.flushed) != 1 {
+		t.Errorf("Stream() error = %v, flushed %d times", err, len(w.flushed))
+	}
+}
+
+func TestAsyncPanic(t *testing.T) {
+	f := Async(func() (string, error) { panic("boom") })
+	if _, err := f.Wait(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Wait() error = %v", err)
+	}
+}
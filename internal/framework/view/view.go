@@ -16,16 +16,33 @@
 //   - ⚠️  ONLY modify preprocessor if you're extending framework template syntax itself
 //
 // TEMPLATE SYNTAX SUPPORTED:
-//   1. Echo expressions: @variable, @.Prop, @obj.Field, @func(arg)
+//   1. Echo expressions: @variable, @.Prop, @$var.Field, @func(arg), @.List[0], @(pipeline)
 //      - Automatically converted to {{ ... }} with HTML escaping
-//      - First char must be [a-zA-Z_.], NOT $ or special chars
-//      - Examples: @.Title, @user.Name, @len(items)
+//      - First char must be [a-zA-Z_.$(]; @@ is a literal @, and an @ right after
+//        a letter or digit (admin@example.com) is text
+//      - Examples: @.Title, @user.Name, @len(.Items), @.Name[:1], @(.Price | printf "%.2f")
+//      - @cspNonce is the request's Content-Security-Policy nonce: <script nonce="@cspNonce">
+//      - @csrfField is the hidden CSRF input for forms, @csrfHeaders the hx-headers
+//        value for HTMX: <body hx-headers='@csrfHeaders'>, @csrfToken the bare token
+//      - @t("nav.home") translates a message into the request's locale (see package i18n)
 //
 //   2. Logic blocks: go:: ... ::end
 //      - Go template control flow with clean syntax
 //      - Converted to {{ ... }} {{ end }}
 //      - Examples: go:: if .User, go:: range .Items, go:: else
 //
+//   3. Layouts and partials (see cache.go)
+//      - go:: extends "layouts/base" renders the page inside a layout
+//      - go:: block "content" ... ::end declares a block a page can override
+//      - go:: include "partials/nav" renders another template in place
+//      - RenderBlock and RenderHTMX render a single block, for HTMX (see htmx.go)
+//
+//   4. Components and streaming
+//      - go:: component "Card" .Props ... ::end renders templates/components/Card
+//        with its own data and slots; @component("Badge", .Role) has no slots
+//        (see component.go)
+//      - go:: flush sends the page so far when rendered with Stream (see stream.go)
+//
 // SECURITY NOTES:
 //   - All @expr outputs are HTML-escaped automatically (XSS prevention)
 //   - Never use raw {{ }} in templates - always use @ or go:: syntax
@@ -41,31 +58,40 @@
 package view
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"net/http"
 	"os"
-	"path/filepath"
-	"regexp"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Engine is the template rendering engine that preprocesses goBastion's custom syntax
 // (go:: / @ constructs) into Go's html/template format.
 //
-// The engine maintains a base directory for templates and a function map for custom
-// template helpers. All templates are preprocessed at render time to convert clean
-// syntax into secure, HTML-escaped Go templates.
+// The engine reads templates from a file system (a directory on disk or an embed.FS)
+// and keeps a function map for custom template helpers. Each template is preprocessed and compiled with its layouts and
+// partials on first use, then served from a cache (see SetDevMode).
 type Engine struct {
-	baseDir string
+	fsys    fs.FS
+	baseDir string // Where fsys was read from, to name files in errors ("" for embedded files)
 	funcs   template.FuncMap
-	verbose bool // Enable verbose template debugging (controlled by config.logging.verbose)
+	verbose atomic.Bool // Enable verbose template debugging (controlled by config.logging.verbose, reloadable)
+	dev     atomic.Bool // Recompile templates whose files changed
+
+	mu    sync.RWMutex
+	pages map[string]*page        // Compiled templates by name
+	types map[string]reflect.Type // Declared data types by template name, for Check
 }
 
 // NewEngine creates a new template engine instance.
 //
 // This initializes the template engine with a base directory for template files and
-// a default set of template helper functions (upper, lower, title).
+// the standard template helpers (see funcs.go and TEMPLATE_SYNTAX.md).
 //
 // Parameters:
 //   - baseDir: Absolute path to the directory containing template files (e.g., "/app/templates")
@@ -81,6 +107,8 @@ type Engine struct {
 //	    log.Fatal(err)
 //	}
 //
+// To read the templates from the binary instead, see NewEngineFS.
+//
 // EXTENSION:
 // To add custom template functions, use Engine.AddFunctions() after creation:
 //
@@ -92,20 +120,48 @@ func NewEngine(baseDir string) (*Engine, error) {
 	if _, err := os.Stat(baseDir); os.IsNotExist(err) {
 		return nil, fmt.Errorf("template directory does not exist: %s", baseDir)
 	}
+	e := NewEngineFS(os.DirFS(baseDir))
+	e.baseDir = baseDir
+	return e, nil
+}
 
+// NewEngineFS creates a template engine that reads templates from fsys, whose
+// root holds what the templates directory would, e.g. the templates/ of an
+// embed.FS:
+//
+//	//go:embed templates
+//	var files embed.FS
+//
+//	sub, _ := fs.Sub(files, "templates")
+//	views := view.NewEngineFS(sub)
+//
+// Embedded files never change, so SetDevMode has no effect on them.
+func NewEngineFS(fsys fs.FS) *Engine {
 	// Initialize default template functions
-	// These are available in all templates via @ syntax
-	funcs := template.FuncMap{
-		"upper": strings.ToUpper, // @upper(.Name) -> JOHN
-		"lower": strings.ToLower, // @lower(.Name) -> john
-		"title": strings.Title,   // @title(.Name) -> John
+	// These are available in all templates via @ syntax (see funcs.go)
+	funcs := stdFuncs()
+	for name, fn := range localeFuncs("en") {
+		funcs[name] = fn
 	}
 
+	// Per-request helpers, bound to the request in Render (see requestFuncs)
+	funcs["cspNonce"] = func() string { return "" }
+	funcs["csrfToken"] = func() string { return "" }
+	funcs["csrfField"] = func() template.HTML { return "" }
+	funcs["csrfHeaders"] = func() string { return "{}" }
+	funcs["t"] = func(key string, args ...any) string { return key }
+
+	// Bound to each rendering (see componentFuncs and Stream)
+	funcs["component"] = func(name string, props any, slots ...*slotSet) (template.HTML, error) { return "", nil }
+	funcs["slots"] = func(id string, dot any) *slotSet { return nil }
+	funcs["slot"] = func(name ...string) (template.HTML, error) { return "", nil }
+	funcs["hasSlot"] = func(name ...string) bool { return false }
+	funcs["flush"] = func() (string, error) { return "", nil }
+
 	return &Engine{
-		baseDir: baseDir,
-		funcs:   funcs,
-		verbose: false,
-	}, nil
+		fsys:  fsys,
+		funcs: funcs,
+	}
 }
 
 // SetVerbose enables or disables verbose template debugging output.
@@ -122,18 +178,18 @@ func NewEngine(baseDir string) (*Engine, error) {
 //	views, _ := view.NewEngine("templates")
 //	views.SetVerbose(cfg.Logging.Verbose)
 func (e *Engine) SetVerbose(verbose bool) {
-	e.verbose = verbose
+	e.verbose.Store(verbose)
 }
 
 // Render renders a template with the given data and writes the output to the HTTP response.
 //
 // This is the main entry point for template rendering. It:
-//  1. Locates the template file by name (adds .html extension)
-//  2. Reads the template content
-//  3. Preprocesses custom syntax (go:: / @) into Go template syntax ({{ }})
-//  4. Compiles the preprocessed template
-//  5. Executes the template with the provided data
-//  6. Writes the resulting HTML to the HTTP response
+//  1. Compiles the template on first use: locates the file by name (name.gb.html,
+//     then name.html), preprocesses custom syntax (go:: / @) into Go template
+//     syntax ({{ }}) and parses it with its layouts and partials
+//  2. Binds the per-request helpers (@cspNonce, @csrfField, ...) to a copy of it
+//  3. Executes the template with the provided data
+//  4. Writes the resulting HTML to the HTTP response
 //
 // Parameters:
 //   - w: HTTP response writer where rendered HTML will be written
@@ -162,26 +218,10 @@ func (e *Engine) SetVerbose(verbose bool) {
 //   - Template compilation validates type safety
 //   - Never bypass this method to render raw HTML
 func (e *Engine) Render(w http.ResponseWriter, name string, data any) error {
-	// Construct full path
-	fullPath := filepath.Join(e.baseDir, name+".html")
-
-	// Read template file
-	content, err := os.ReadFile(fullPath)
+	// Bind @cspNonce and the CSRF helpers to this request
+	tmpl, p, err := e.instance(name, requestFuncs(w))
 	if err != nil {
-		return fmt.Errorf("failed to read template: %w", err)
-	}
-
-	// Preprocess PHP-like syntax to Go template syntax
-	processed := e.preprocess(string(content))
-
-	// Parse template
-	tmpl, err := template.New(name).Funcs(e.funcs).Parse(processed)
-	if err != nil {
-		if e.verbose {
-			fmt.Printf("[VERBOSE] Template parse error for %s: %v\n", name, err)
-			fmt.Printf("[VERBOSE] Processed content:\n%s\n", processed)
-		}
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
 	}
 
 	// Set content type
@@ -189,80 +229,127 @@ func (e *Engine) Render(w http.ResponseWriter, name string, data any) error {
 
 	// Execute template
 	if err := tmpl.Execute(w, data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+		return fmt.Errorf("failed to execute template: %w", p.locate(err))
 	}
 
 	return nil
 }
 
-// RenderString renders a template and returns the result as a string
-func (e *Engine) RenderString(name string, data any) (string, error) {
-	// Construct full path
-	fullPath := filepath.Join(e.baseDir, name+".html")
+// NonceWriter is implemented by response writers that carry the request's
+// Content-Security-Policy nonce (see middleware.SecurityHeaders). Render exposes
+// it to templates as @cspNonce.
+type NonceWriter interface {
+	CSPNonce() string
+}
+
+// CSRFWriter is implemented by response writers that carry the request's CSRF
+// token (see middleware.CSRFMiddleware). Render exposes it to templates as
+// @csrfToken, @csrfField and @csrfHeaders.
+type CSRFWriter interface {
+	CSRFToken() string // A freshly masked token
+	CSRFFieldName() string
+	CSRFHeaderName() string
+}
+
+// requestFuncs binds the per-request template helpers to what the middleware
+// attached to w
+func requestFuncs(w http.ResponseWriter) template.FuncMap {
+	funcs := template.FuncMap{}
+
+	if nw, ok := findWriter[NonceWriter](w); ok {
+		nonce := nw.CSPNonce()
+		funcs["cspNonce"] = func() string { return nonce }
+	}
+
+	if cw, ok := findWriter[CSRFWriter](w); ok {
+		// Each helper call masks the token afresh, so no two occurrences match
+		funcs["csrfToken"] = cw.CSRFToken
+		funcs["csrfField"] = func() template.HTML {
+			return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+				template.HTMLEscapeString(cw.CSRFFieldName()), template.HTMLEscapeString(cw.CSRFToken())))
+		}
+		funcs["csrfHeaders"] = func() (string, error) {
+			b, err := json.Marshal(map[string]string{cw.CSRFHeaderName(): cw.CSRFToken()})
+			return string(b), err
+		}
+	}
+
+	if lw, ok := findWriter[LocaleWriter](w); ok {
+		for name, fn := range localeFuncs(lw.Locale()) {
+			funcs[name] = fn
+		}
+	}
+
+	if tw, ok := findWriter[Translator](w); ok {
+		funcs["t"] = tw.T
+	}
+
+	return funcs
+}
 
-	// Read template file
-	content, err := os.ReadFile(fullPath)
+// instance returns a copy of the compiled template for name, with funcs bound.
+// The compiled template itself is never executed, as html/template can't copy
+// a template after that.
+func (e *Engine) instance(name string, funcs template.FuncMap) (*template.Template, *page, error) {
+	p, err := e.lookup(name)
 	if err != nil {
-		return "", fmt.Errorf("failed to read template: %w", err)
+		return nil, nil, err
 	}
+	tmpl, err := p.tmpl.Clone()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to clone template: %w", err)
+	}
+	return tmpl.Funcs(funcs).Funcs(componentFuncs(tmpl, p)), p, nil
+}
 
-	// Preprocess PHP-like syntax to Go template syntax
-	processed := e.preprocess(string(content))
+// findWriter finds a T on w or on a writer it wraps
+func findWriter[T any](w http.ResponseWriter) (T, bool) {
+	for {
+		if t, ok := w.(T); ok {
+			return t, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		w = u.Unwrap()
+	}
+}
 
-	// Parse template
-	tmpl, err := template.New(name).Funcs(e.funcs).Parse(processed)
+// RenderString renders a template and returns the result as a string
+func (e *Engine) RenderString(name string, data any) (string, error) {
+	tmpl, p, err := e.instance(name, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return "", err
 	}
 
 	// Execute template to string
 	var buf strings.Builder
 	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+		return "", fmt.Errorf("failed to execute template: %w", p.locate(err))
 	}
 
 	return buf.String(), nil
 }
 
 // preprocess converts goBastion-specific syntax to Go template syntax
-// Supports two main constructs:
+// Supports two main constructs (see translate for the details):
 // 1. go:: ... ::end - Logic blocks (if, for, range, with, etc.)
 // 2. @expr - Echo expressions (HTML-escaped output)
-func (e *Engine) preprocess(content string) string {
-	// Process @expr echo expressions FIRST
-	// This ensures they work both inside and outside logic blocks
-	// Matches: @identifier or @func(args) or @obj.field
-	// This regex handles:
-	// - @variable
-	// - @.field (dot notation for current context)
-	// - @object.field
-	// - @object.method()
-	// - @func(args)
-	echoRegex := regexp.MustCompile(`@([a-zA-Z_.][a-zA-Z0-9_.]*(?:\([^)]*\))?)`)
-	content = echoRegex.ReplaceAllStringFunc(content, func(match string) string {
-		// Extract the expression (remove @)
-		expr := match[1:]
-		return "{{ " + expr + " }}"
-	})
-
-	// Process go:: logic blocks AFTER echo expressions
-	// Matches: go:: <statement>
-	// Example: go:: if user != nil {
-	goBlockRegex := regexp.MustCompile(`(?m)^[ \t]*go::\s*(.+?)[ \t]*$`)
-	content = goBlockRegex.ReplaceAllString(content, "{{ $1 }}")
-
-	// Process ::end tags
-	// Matches: ::end
-	endBlockRegex := regexp.MustCompile(`(?m)^[ \t]*::end[ \t]*$`)
-	content = endBlockRegex.ReplaceAllString(content, "{{ end }}")
-
-	return content
+func (e *Engine) preprocess(content string) (string, error) {
+	translated, err := translate("", "", content)
+	if err != nil {
+		return "", err
+	}
+	return translated.text, nil
 }
 
-
-// AddFunc adds a custom template function
+// AddFunc adds a custom template function. Templates compiled before are
+// dropped, as functions are bound when a template is parsed.
 func (e *Engine) AddFunc(name string, fn any) {
 	e.funcs[name] = fn
+	e.Reset()
 }
 
 // RenderError renders an error page
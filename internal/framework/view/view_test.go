@@ -26,7 +26,7 @@ func TestPreprocessEchoExpressions(t *testing.T) {
 		{
 			name:     "Function call",
 			input:    "@formatPrice(product.Price)",
-			expected: "{{ formatPrice(product.Price) }}",
+			expected: "{{ formatPrice product.Price }}",
 		},
 		{
 			name:     "Nested property",
@@ -47,8 +47,8 @@ func TestPreprocessEchoExpressions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := engine.preprocess(tt.input)
-			if result != tt.expected {
+			result, err := engine.preprocess(tt.input)
+			if err != nil || result != tt.expected {
 				t.Errorf("preprocess() = %q, want %q", result, tt.expected)
 			}
 		})
@@ -108,8 +108,8 @@ go:: range users
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := engine.preprocess(tt.input)
-			if result != tt.expected {
+			result, err := engine.preprocess(tt.input)
+			if err != nil || result != tt.expected {
 				t.Errorf("preprocess() = %q, want %q", result, tt.expected)
 			}
 		})
@@ -130,8 +130,8 @@ func TestPreprocessMixedSyntax(t *testing.T) {
 <p>Email: {{ user.Email }}</p>
 {{ end }}`
 
-	result := engine.preprocess(input)
-	if result != expected {
+	result, err := engine.preprocess(input)
+	if err != nil || result != expected {
 		t.Errorf("preprocess() = %q, want %q", result, expected)
 	}
 }
@@ -164,8 +164,8 @@ func TestPreprocessBackwardCompatibility(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := engine.preprocess(tt.input)
-			if result != tt.expected {
+			result, err := engine.preprocess(tt.input)
+			if err != nil || result != tt.expected {
 				t.Errorf("preprocess() = %q, want %q", result, tt.expected)
 			}
 		})